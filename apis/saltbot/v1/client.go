@@ -0,0 +1,236 @@
+package v1
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+)
+
+var (
+	scheme         = runtime.NewScheme()
+	codecs         = serializer.NewCodecFactory(scheme)
+	parameterCodec = runtime.NewParameterCodec(scheme)
+)
+
+func init() {
+	utilruntime.Must(AddToScheme(scheme))
+}
+
+// Saltbot's resources, the way a clientset has CoreV1() for configmaps.
+type SaltbotV1Interface interface {
+	Polls(namespace string) PollInterface
+	Reminders(namespace string) ReminderInterface
+}
+
+type PollInterface interface {
+	List(ctx context.Context, opts metav1.ListOptions) (*PollList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*Poll, error)
+	Create(ctx context.Context, poll *Poll, opts metav1.CreateOptions) (*Poll, error)
+	Update(ctx context.Context, poll *Poll, opts metav1.UpdateOptions) (*Poll, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+}
+
+type ReminderInterface interface {
+	List(ctx context.Context, opts metav1.ListOptions) (*ReminderList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*Reminder, error)
+	Create(ctx context.Context, reminder *Reminder, opts metav1.CreateOptions) (*Reminder, error)
+	Update(ctx context.Context, reminder *Reminder, opts metav1.UpdateOptions) (*Reminder, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+}
+
+// +k8s:deepcopy-gen=false
+type SaltbotV1Client struct {
+	client rest.Interface
+}
+
+// Create a client for saltbot's resources from the same config as the
+// kubernetes clientset.
+func NewForConfig(c *rest.Config) (*SaltbotV1Client, error) {
+	config := *c
+	config.GroupVersion = &SchemeGroupVersion
+	config.APIPath = "/apis"
+	config.NegotiatedSerializer = codecs.WithoutConversion()
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+
+	client, err := rest.RESTClientFor(&config)
+	if err != nil {
+		return nil, err
+	}
+	return &SaltbotV1Client{client: client}, nil
+}
+
+func (c *SaltbotV1Client) Polls(namespace string) PollInterface {
+	return &polls{client: c.client, ns: namespace}
+}
+
+func (c *SaltbotV1Client) Reminders(namespace string) ReminderInterface {
+	return &reminders{client: c.client, ns: namespace}
+}
+
+// How long a watch can stay open, from the list options.
+func watchTimeout(opts metav1.ListOptions) time.Duration {
+	if opts.TimeoutSeconds == nil {
+		return 0
+	}
+	return time.Duration(*opts.TimeoutSeconds) * time.Second
+}
+
+// +k8s:deepcopy-gen=false
+type polls struct {
+	client rest.Interface
+	ns     string
+}
+
+func (c *polls) List(ctx context.Context, opts metav1.ListOptions) (*PollList, error) {
+	result := &PollList{}
+	err := c.client.Get().
+		Namespace(c.ns).
+		Resource("polls").
+		VersionedParams(&opts, parameterCodec).
+		Timeout(watchTimeout(opts)).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *polls) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("polls").
+		VersionedParams(&opts, parameterCodec).
+		Timeout(watchTimeout(opts)).
+		Watch(ctx)
+}
+
+func (c *polls) Get(ctx context.Context, name string, opts metav1.GetOptions) (*Poll, error) {
+	result := &Poll{}
+	err := c.client.Get().
+		Namespace(c.ns).
+		Resource("polls").
+		Name(name).
+		VersionedParams(&opts, parameterCodec).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *polls) Create(ctx context.Context, poll *Poll, opts metav1.CreateOptions) (*Poll, error) {
+	result := &Poll{}
+	err := c.client.Post().
+		Namespace(c.ns).
+		Resource("polls").
+		VersionedParams(&opts, parameterCodec).
+		Body(poll).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *polls) Update(ctx context.Context, poll *Poll, opts metav1.UpdateOptions) (*Poll, error) {
+	result := &Poll{}
+	err := c.client.Put().
+		Namespace(c.ns).
+		Resource("polls").
+		Name(poll.Name).
+		VersionedParams(&opts, parameterCodec).
+		Body(poll).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *polls) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("polls").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// +k8s:deepcopy-gen=false
+type reminders struct {
+	client rest.Interface
+	ns     string
+}
+
+func (c *reminders) List(ctx context.Context, opts metav1.ListOptions) (*ReminderList, error) {
+	result := &ReminderList{}
+	err := c.client.Get().
+		Namespace(c.ns).
+		Resource("reminders").
+		VersionedParams(&opts, parameterCodec).
+		Timeout(watchTimeout(opts)).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *reminders) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("reminders").
+		VersionedParams(&opts, parameterCodec).
+		Timeout(watchTimeout(opts)).
+		Watch(ctx)
+}
+
+func (c *reminders) Get(ctx context.Context, name string, opts metav1.GetOptions) (*Reminder, error) {
+	result := &Reminder{}
+	err := c.client.Get().
+		Namespace(c.ns).
+		Resource("reminders").
+		Name(name).
+		VersionedParams(&opts, parameterCodec).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *reminders) Create(ctx context.Context, reminder *Reminder, opts metav1.CreateOptions) (*Reminder, error) {
+	result := &Reminder{}
+	err := c.client.Post().
+		Namespace(c.ns).
+		Resource("reminders").
+		VersionedParams(&opts, parameterCodec).
+		Body(reminder).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *reminders) Update(ctx context.Context, reminder *Reminder, opts metav1.UpdateOptions) (*Reminder, error) {
+	result := &Reminder{}
+	err := c.client.Put().
+		Namespace(c.ns).
+		Resource("reminders").
+		Name(reminder.Name).
+		VersionedParams(&opts, parameterCodec).
+		Body(reminder).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *reminders) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("reminders").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
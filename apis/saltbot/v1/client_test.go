@@ -0,0 +1,132 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+type request struct {
+	method string
+	path   string
+	body   map[string]interface{}
+}
+
+// A fake API server that records requests and answers with the given poll.
+func newServer(t *testing.T, poll *Poll) (*SaltbotV1Client, *[]request) {
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{method: r.Method, path: r.URL.Path}
+		data, _ := io.ReadAll(r.Body)
+		if len(data) > 0 {
+			json.Unmarshal(data, &req.body)
+		}
+		requests = append(requests, req)
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet && r.URL.Path == "/apis/saltbot.highsaltlevels.github.io/v1/namespaces/saltbot/polls" {
+			json.NewEncoder(w).Encode(&PollList{Items: []Poll{*poll}})
+			return
+		}
+		json.NewEncoder(w).Encode(poll)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return client, &requests
+}
+
+func TestPollClient(t *testing.T) {
+	expiry := metav1.NewTime(time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC))
+	poll := &Poll{
+		ObjectMeta: metav1.ObjectMeta{Name: "1234", ResourceVersion: "7"},
+		Spec: PollSpec{
+			Id:      "1234",
+			Prompt:  "prompt",
+			Choices: []string{"a", "b"},
+			Expiry:  expiry,
+			Votes:   map[string][]string{"0": {"chooser"}},
+		},
+	}
+	client, requests := newServer(t, poll)
+	polls := client.Polls("saltbot")
+	ctx := context.Background()
+
+	list, err := polls.List(ctx, metav1.ListOptions{})
+	if err != nil || len(list.Items) != 1 || list.Items[0].Spec.Prompt != "prompt" {
+		t.Fatalf("expected a poll to be listed, but got %+v: %v", list, err)
+	}
+
+	got, err := polls.Get(ctx, "1234", metav1.GetOptions{})
+	if err != nil || !got.Spec.Expiry.Equal(&expiry) || got.Spec.Votes["0"][0] != "chooser" {
+		t.Fatalf("expected the poll, but got %+v: %v", got, err)
+	}
+
+	if _, err := polls.Create(ctx, poll, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	if _, err := polls.Update(ctx, poll, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	if err := polls.Delete(ctx, "1234", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+
+	base := "/apis/saltbot.highsaltlevels.github.io/v1/namespaces/saltbot/polls"
+	expected := []request{
+		{method: http.MethodGet, path: base},
+		{method: http.MethodGet, path: base + "/1234"},
+		{method: http.MethodPost, path: base},
+		{method: http.MethodPut, path: base + "/1234"},
+		{method: http.MethodDelete, path: base + "/1234"},
+	}
+	if len(*requests) != len(expected) {
+		t.Fatalf("expected %d requests, but got %+v", len(expected), *requests)
+	}
+	for i, r := range *requests {
+		if r.method != expected[i].method || r.path != expected[i].path {
+			t.Errorf("expected %s %s, but got %s %s", expected[i].method, expected[i].path, r.method, r.path)
+		}
+	}
+
+	// Created resources are sent with their kind and spec
+	created := (*requests)[2].body
+	spec, _ := created["spec"].(map[string]interface{})
+	if spec["expiry"] != "2023-07-05T12:00:00Z" || spec["prompt"] != "prompt" {
+		t.Errorf("expected the spec to be sent, but got %+v", created)
+	}
+}
+
+func TestDeepCopy(t *testing.T) {
+	poll := &Poll{
+		Spec: PollSpec{
+			Choices: []string{"a", "b"},
+			Votes:   map[string][]string{"0": {"chooser"}},
+		},
+	}
+	copied := poll.DeepCopy()
+	copied.Spec.Choices[0] = "changed"
+	copied.Spec.Votes["0"][0] = "changed"
+	copied.Spec.Votes["1"] = []string{"new"}
+
+	if poll.Spec.Choices[0] != "a" || poll.Spec.Votes["0"][0] != "chooser" || len(poll.Spec.Votes) != 1 {
+		t.Errorf("expected the copy to be independent, but the original is %+v", poll.Spec)
+	}
+
+	list := &ReminderList{Items: []Reminder{{Spec: ReminderSpec{Message: "bloop"}}}}
+	copiedList := list.DeepCopyObject().(*ReminderList)
+	copiedList.Items[0].Spec.Message = "changed"
+	if list.Items[0].Spec.Message != "bloop" {
+		t.Errorf("expected the list copy to be independent")
+	}
+}
@@ -0,0 +1,7 @@
+// +k8s:deepcopy-gen=package
+
+// Polls and reminders as their own kubernetes resources, in the
+// saltbot.highsaltlevels.github.io group. The CRDs are in k8s/crds.yaml.
+package v1
+
+//go:generate ../../../update_codegen.sh
@@ -0,0 +1,66 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// A poll that's still open. It's named after its id, and deleted once its
+// results are posted.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type Poll struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PollSpec `json:"spec"`
+}
+
+type PollSpec struct {
+	Id      string `json:"id"`
+	Author  string `json:"author"`
+	Channel string `json:"channel"`
+	Guild   string `json:"guild,omitempty"`
+	Prompt  string `json:"prompt"`
+
+	Choices []string `json:"choices"`
+
+	// When the results are posted
+	Expiry metav1.Time `json:"expiry"`
+
+	// Who voted for each choice, keyed on the choice's index
+	Votes map[string][]string `json:"votes,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type PollList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Poll `json:"items"`
+}
+
+// A reminder that hasn't been sent yet. It's named after its id.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type Reminder struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ReminderSpec `json:"spec"`
+}
+
+type ReminderSpec struct {
+	Id      string `json:"id"`
+	Author  string `json:"author"`
+	Channel string `json:"channel"`
+	Message string `json:"message"`
+
+	// When the reminder is sent
+	Expiry metav1.Time `json:"expiry"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ReminderList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Reminder `json:"items"`
+}
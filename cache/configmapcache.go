@@ -2,14 +2,19 @@ package cache
 
 import (
 	"context"
-	"fmt"
 	"log"
+	"log/slog"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	infcorev1 "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
@@ -17,30 +22,103 @@ import (
 	k8scache "k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
+	"k8s.io/client-go/util/retry"
+
+	saltbotv1 "github.com/highsaltlevels/saltbot/apis/saltbot/v1"
+	"github.com/highsaltlevels/saltbot/logging"
 )
 
-const namespace = "saltbot"
+// The namespace saltbot's configmaps live in. Set with SALTBOT_NAMESPACE.
+var namespace = "saltbot"
+
+func init() {
+	if ns, ok := os.LookupEnv("SALTBOT_NAMESPACE"); ok && ns != "" {
+		namespace = ns
+	}
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "saltbot_cache_synced",
+		Help: "1 once the informers have synced.",
+	}, func() float64 {
+		if Synced() {
+			return 1
+		}
+		return 0
+	})
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "saltbot_pending_polls",
+		Help: "Polls waiting for their results to be posted.",
+	}, func() float64 {
+		polls, _ := pending()
+		return float64(polls)
+	})
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "saltbot_pending_reminders",
+		Help: "Reminders waiting to be sent.",
+	}, func() float64 {
+		_, reminders := pending()
+		return float64(reminders)
+	})
+}
+
+// Set once the informers have synced, so the cache has everything in the cluster
+var synced atomic.Bool
+
+// Whether the configmap, poll and reminder informers have synced.
+func Synced() bool {
+	return synced.Load()
+}
+
+// How many polls and reminders are in the cache.
+func pending() (polls, reminders int) {
+	lock.Lock()
+	defer lock.Unlock()
+	if Cache == nil {
+		return 0, 0
+	}
+	return len(Cache.polls), len(Cache.reminders)
+}
 
 type ConfigMapCache struct {
-	informer  k8scache.SharedIndexInformer
-	polls     map[string]Poll
-	reminders map[string]Reminder
-	stopCh    <-chan struct{}
+	informer      k8scache.SharedIndexInformer
+	polls         map[string]Poll
+	reminders     map[string]Reminder
+	ratings       map[string]GifRating
+	subscriptions map[string]Subscription
+	quotas        map[string]Quota
+	leaderboards  map[string]Leaderboard
+	finals        map[string]FinalJeopardy
+	configs       map[string]GuildConfig
+	stopCh        <-chan struct{}
 }
 
 // Only create a single instance of config map cache
 var Cache *ConfigMapCache
-var Client kubernetes.Interface
+var Client KubeClient
 var lock sync.Mutex = sync.Mutex{}
 
+// A kubernetes client that also has saltbot's own resources.
+type KubeClient interface {
+	kubernetes.Interface
+	SaltbotV1() saltbotv1.SaltbotV1Interface
+}
+
+type clientset struct {
+	*kubernetes.Clientset
+	saltbot *saltbotv1.SaltbotV1Client
+}
+
+func (c *clientset) SaltbotV1() saltbotv1.SaltbotV1Interface {
+	return c.saltbot
+}
+
 func getClientConfig() (config *rest.Config, err error) {
 	// If we can't get the in-cluster config, try the ~/.kube/config
 	if config, err = rest.InClusterConfig(); err == nil {
 		return config, nil
 	}
 
-	log.Printf("failed to initialize k8s client config: %v", err)
-	log.Println("attempting to use ~/.kube/config")
+	slog.Info("not in a cluster, using ~/.kube/config", "error", err)
 
 	kubeconfig := filepath.Join(homedir.HomeDir(), ".kube", "config")
 	config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
@@ -55,17 +133,38 @@ func NewConfigMapCache() *ConfigMapCache {
 			log.Fatalf("failed to create k8s client config: %v", err)
 		}
 
-		Client, err = kubernetes.NewForConfig(config)
+		kube, err := kubernetes.NewForConfig(config)
 		if err != nil {
 			log.Fatalf("failed to create k8s client: %v", err)
 		}
 
+		saltbot, err := saltbotv1.NewForConfig(config)
+		if err != nil {
+			log.Fatalf("failed to create saltbot resource client: %v", err)
+		}
+		Client = &clientset{Clientset: kube, saltbot: saltbot}
+
 		var informer k8scache.SharedIndexInformer
 		Cache = &ConfigMapCache{
-			informer:  informer,
-			polls:     make(map[string]Poll, 1),
-			reminders: make(map[string]Reminder, 1),
-			stopCh:    make(chan struct{}),
+			informer:      informer,
+			polls:         make(map[string]Poll, 1),
+			reminders:     make(map[string]Reminder, 1),
+			ratings:       make(map[string]GifRating, 1),
+			subscriptions: make(map[string]Subscription, 1),
+			quotas:        make(map[string]Quota, 1),
+			leaderboards:  make(map[string]Leaderboard, 1),
+			finals:        make(map[string]FinalJeopardy, 1),
+			configs:       make(map[string]GuildConfig, 1),
+			stopCh:        make(chan struct{}),
+		}
+
+		// Move polls and reminders out of configmaps before the informers
+		// start, so they're only ever seen as resources
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err = Migrate(ctx)
+		cancel()
+		if err != nil {
+			slog.Warn("failed to migrate polls and reminders from configmaps", "error", err)
 		}
 
 		informer = infcorev1.NewConfigMapInformer(Client, namespace, time.Hour*24, nil)
@@ -80,10 +179,18 @@ func NewConfigMapCache() *ConfigMapCache {
 			log.Fatalf("failed to create informer handler: %v", err)
 		}
 
-		log.Println("starting informer and waiting for it to sync")
+		polls, reminders, err := newResourceInformers()
+		if err != nil {
+			log.Fatalf("failed to create informer handler: %v", err)
+		}
+
+		slog.Info("starting informers and waiting for them to sync")
 		go informer.Run(Cache.stopCh)
-		k8scache.WaitForCacheSync(Cache.stopCh, informer.HasSynced)
-		log.Println("informer cache has synced")
+		go polls.Run(Cache.stopCh)
+		go reminders.Run(Cache.stopCh)
+		k8scache.WaitForCacheSync(Cache.stopCh, informer.HasSynced, polls.HasSynced, reminders.HasSynced)
+		synced.Store(true)
+		slog.Info("informer caches have synced")
 	}
 
 	return Cache
@@ -92,71 +199,119 @@ func NewConfigMapCache() *ConfigMapCache {
 // Create a configmap cache that is in-memory. Cache is lost if application closes.
 func NewInMemConfigMapCache(polls map[string]Poll, reminders map[string]Reminder) *ConfigMapCache {
 	return &ConfigMapCache{
-		polls:     polls,
-		reminders: reminders,
-		stopCh:    make(chan struct{}),
+		polls:         polls,
+		reminders:     reminders,
+		ratings:       map[string]GifRating{},
+		subscriptions: map[string]Subscription{},
+		quotas:        map[string]Quota{},
+		leaderboards:  map[string]Leaderboard{},
+		finals:        map[string]FinalJeopardy{},
+		configs:       map[string]GuildConfig{},
+		stopCh:        make(chan struct{}),
 	}
 }
 
 // Add handler for the configmap informer
 func (c *ConfigMapCache) addConfigMap(obj interface{}) {
-	configMap := obj.(*corev1.ConfigMap)
+	c.storeConfigMap(obj.(*corev1.ConfigMap), "adding")
+}
+
+// Update handler for the configmap informer
+func (c *ConfigMapCache) updateConfigMap(oldObj interface{}, newObj interface{}) {
+	// We don't care about the older resource version
+	c.storeConfigMap(newObj.(*corev1.ConfigMap), "updating")
+}
+
+// Store a configmap directly in the in-mem cache, the same way the informer
+// would. Useful for seeding an in-memory cache.
+func (c *ConfigMapCache) Store(configMap *corev1.ConfigMap) {
+	c.storeConfigMap(configMap, "storing")
+}
+
+// Parse a configmap and store it in the in-mem cache based on its name prefix.
+func (c *ConfigMapCache) storeConfigMap(configMap *corev1.ConfigMap, verb string) {
 	name := configMap.ObjectMeta.Name
 
 	lock.Lock()
 	defer lock.Unlock()
 
-	if strings.Contains(name, "poll") {
-		p := Poll{}
-		err := p.FromConfigMap(configMap)
+	switch {
+	case strings.HasPrefix(name, "rating-"):
+		r := GifRating{}
+		err := r.FromConfigMap(configMap)
 		if err != nil {
-			log.Printf("failed to parse poll: %v", err)
+			slog.Warn("failed to parse rating", "error", err)
 		} else {
-			log.Printf("adding poll with id: %s", p.Id)
-			c.polls[p.Id] = p
+			slog.Debug(verb+" rating", "id", r.Id)
+			if c.ratings == nil {
+				c.ratings = map[string]GifRating{}
+			}
+			c.ratings[r.Id] = r
 		}
-	}
 
-	if strings.Contains(name, "reminder") {
-		r := Reminder{}
-		err := r.FromConfigMap(configMap)
+	case strings.HasPrefix(name, "subscription-"):
+		sub := Subscription{}
+		err := sub.FromConfigMap(configMap)
 		if err != nil {
-			log.Printf("failed to parse reminder: %v", err)
+			slog.Warn("failed to parse subscription", "error", err)
 		} else {
-			log.Printf("adding reminder with id: %s", r.Id)
-			c.reminders[r.Id] = r
+			slog.Debug(verb+" subscription", "id", sub.Id)
+			if c.subscriptions == nil {
+				c.subscriptions = map[string]Subscription{}
+			}
+			c.subscriptions[sub.Id] = sub
 		}
-	}
-}
 
-// Update handler for the configmap informer
-func (c *ConfigMapCache) updateConfigMap(oldObj interface{}, newObj interface{}) {
-	// We don't care about the older resource version
-	configMap := newObj.(*corev1.ConfigMap)
-	name := configMap.ObjectMeta.Name
+	case strings.HasPrefix(name, "quota-"):
+		q := Quota{}
+		err := q.FromConfigMap(configMap)
+		if err != nil {
+			slog.Warn("failed to parse quota", "error", err)
+		} else {
+			slog.Debug(verb+" quota", "id", q.Id)
+			if c.quotas == nil {
+				c.quotas = map[string]Quota{}
+			}
+			c.quotas[q.Id] = q
+		}
 
-	lock.Lock()
-	defer lock.Unlock()
+	case strings.HasPrefix(name, "leaderboard-"):
+		l := Leaderboard{}
+		err := l.FromConfigMap(configMap)
+		if err != nil {
+			slog.Warn("failed to parse leaderboard", "error", err)
+		} else {
+			slog.Debug(verb+" leaderboard", "id", l.Id)
+			if c.leaderboards == nil {
+				c.leaderboards = map[string]Leaderboard{}
+			}
+			c.leaderboards[l.Id] = l
+		}
 
-	if strings.Contains(name, "poll") {
-		p := Poll{}
-		err := p.FromConfigMap(configMap)
+	case strings.HasPrefix(name, "final-"):
+		f := FinalJeopardy{}
+		err := f.FromConfigMap(configMap)
 		if err != nil {
-			log.Printf("failed to parse poll: %v", err)
+			slog.Warn("failed to parse final jeopardy", "error", err)
 		} else {
-			log.Printf("updating poll with id: %s", p.Id)
-			c.polls[p.Id] = p
+			slog.Debug(verb+" final jeopardy", "id", f.Id)
+			if c.finals == nil {
+				c.finals = map[string]FinalJeopardy{}
+			}
+			c.finals[f.Id] = f
 		}
-	}
 
-	if strings.Contains(name, "reminder") {
-		r := Reminder{}
-		err := r.FromConfigMap(configMap)
+	case strings.HasPrefix(name, "config-"):
+		g := GuildConfig{}
+		err := g.FromConfigMap(configMap)
 		if err != nil {
-			log.Printf("failed to parse poll: %v", err)
+			slog.Warn("failed to parse guild config", "error", err)
 		} else {
-			log.Printf("updating reminder with id: %s", r.Id)
-			c.reminders[r.Id] = r
+			slog.Debug(verb+" guild config", "id", g.Id)
+			if c.configs == nil {
+				c.configs = map[string]GuildConfig{}
+			}
+			c.configs[g.Id] = g
 		}
 	}
 }
@@ -166,19 +321,35 @@ func (c *ConfigMapCache) deleteConfigMap(obj interface{}) {
 	configMap := obj.(*corev1.ConfigMap)
 	nameParts := strings.Split(configMap.ObjectMeta.Name, "-")
 	if len(nameParts) < 2 {
-		fmt.Printf("unparseable configmap name %s. Ignoring deletion\n", configMap.ObjectMeta.Name)
+		slog.Warn("ignoring deletion of unparseable configmap", "name", configMap.ObjectMeta.Name)
 		return
 	}
 
 	lock.Lock()
 	defer lock.Unlock()
 
-	if nameParts[0] == "poll" {
-		delete(c.polls, nameParts[1])
+	if nameParts[0] == "rating" {
+		delete(c.ratings, nameParts[1])
+	}
+
+	if nameParts[0] == "subscription" {
+		delete(c.subscriptions, nameParts[1])
+	}
+
+	if nameParts[0] == "quota" {
+		delete(c.quotas, nameParts[1])
+	}
+
+	if nameParts[0] == "leaderboard" {
+		delete(c.leaderboards, nameParts[1])
+	}
+
+	if nameParts[0] == "final" {
+		delete(c.finals, nameParts[1])
 	}
 
-	if nameParts[0] == "reminder" {
-		delete(c.reminders, nameParts[1])
+	if nameParts[0] == "config" {
+		delete(c.configs, nameParts[1])
 	}
 }
 
@@ -201,30 +372,45 @@ func (c *ConfigMapCache) GetPoll(id, author string) *Poll {
 	return &poll
 }
 
-// Add a poll configmap, this in turn triggers the informer handler which
+// Add a poll resource, this in turn triggers the informer handler which
 // adds it to the in-mem cache.
-func (c *ConfigMapCache) AddPoll(p *Poll) error {
-	cmClient := Client.CoreV1().ConfigMaps(namespace)
-
-	configMap, err := p.ToConfigMap()
-	if err != nil {
-		return err
-	}
-
-	_, err = cmClient.Create(context.TODO(), configMap, metav1.CreateOptions{})
+func (c *ConfigMapCache) AddPoll(ctx context.Context, p *Poll) error {
+	logging.From(ctx).Debug("creating poll", "name", p.Id)
+	_, err := Client.SaltbotV1().Polls(namespace).Create(ctx, p.ToResource(), metav1.CreateOptions{})
 	return err
 }
 
-func (c *ConfigMapCache) UpdatePoll(p *Poll) error {
-	cmClient := Client.CoreV1().ConfigMaps(namespace)
+// Change a poll with update. Updating a resource needs its latest version, so
+// update is applied to the poll fetched from k8s, and if someone else changed
+// it in between, it's fetched and applied again so their change isn't lost.
+func (c *ConfigMapCache) UpdatePoll(ctx context.Context, id string, update func(*Poll)) error {
+	logging.From(ctx).Debug("updating poll", "name", id)
+	polls := Client.SaltbotV1().Polls(namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := polls.Get(ctx, id, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
 
-	configMap, err := p.ToConfigMap()
-	if err != nil {
+		var poll Poll
+		poll.FromResource(current)
+		update(&poll)
+
+		updated := current.DeepCopy()
+		updated.Spec = poll.ToResource().Spec
+		_, err = polls.Update(ctx, updated, metav1.UpdateOptions{})
 		return err
-	}
+	})
+}
 
-	_, err = cmClient.Update(context.TODO(), configMap, metav1.UpdateOptions{})
-	return err
+// Delete a poll resource, which in turn triggers the delete handler to remove
+// it from the in-mem cache.
+func (c *ConfigMapCache) DeletePoll(ctx context.Context, id string) {
+	logging.From(ctx).Debug("deleting poll", "name", id)
+	err := Client.SaltbotV1().Polls(namespace).Delete(ctx, id, metav1.DeleteOptions{})
+	if err != nil {
+		logging.From(ctx).Warn("failed to delete poll", "name", id, "error", err)
+	}
 }
 
 // Getter for reminders in the cache
@@ -250,29 +436,264 @@ func (c *ConfigMapCache) GetReminder(id, author string) *Reminder {
 	return &reminder
 }
 
-// Add a reminder configmap, this in turn triggers the informer handler which
+// Add a reminder resource, this in turn triggers the informer handler which
 // adds it to the in-mem cache.
-func (c *ConfigMapCache) AddReminder(r *Reminder, user string) error {
-	cmClient := Client.CoreV1().ConfigMaps(namespace)
+func (c *ConfigMapCache) AddReminder(ctx context.Context, r *Reminder, user string) error {
+	logging.From(ctx).Debug("creating reminder", "name", r.Id)
+	_, err := Client.SaltbotV1().Reminders(namespace).Create(ctx, r.ToResource(), metav1.CreateOptions{})
+	return err
+}
+
+// Delete a reminder resource, which in turn triggers the delete handler to
+// remove it from the in-mem cache.
+func (c *ConfigMapCache) DeleteReminder(ctx context.Context, id string) {
+	logging.From(ctx).Debug("deleting reminder", "name", id)
+	err := Client.SaltbotV1().Reminders(namespace).Delete(ctx, id, metav1.DeleteOptions{})
+	if err != nil {
+		logging.From(ctx).Warn("failed to delete reminder", "name", id, "error", err)
+	}
+}
+
+// Get the giphy rating set for a guild or channel id, or nil if there isn't one.
+func (c *ConfigMapCache) GetRating(id string) *GifRating {
+	lock.Lock()
+	defer lock.Unlock()
+	var rating GifRating
+	var ok bool
+	if rating, ok = c.ratings[id]; !ok {
+		return nil
+	}
+
+	return &rating
+}
+
+// Create or update a rating configmap, this in turn triggers the informer
+// handler which adds it to the in-mem cache.
+func (c *ConfigMapCache) SetRating(ctx context.Context, r *GifRating) error {
 	configMap, err := r.ToConfigMap()
 	if err != nil {
-		return fmt.Errorf("failed to convert reminder to configMap: %v", err)
+		return err
 	}
 
-	_, err = cmClient.Create(context.TODO(), configMap, metav1.CreateOptions{})
+	if c.GetRating(r.Id) == nil {
+		err = create(ctx, configMap)
+	} else {
+		err = update(ctx, configMap)
+	}
 	return err
 }
 
+// Getter for youtube subscriptions in the cache
+func (c *ConfigMapCache) ListSubscriptions() map[string]Subscription {
+	lock.Lock()
+	defer lock.Unlock()
+	subscriptions := make(map[string]Subscription, len(c.subscriptions))
+	for id, sub := range c.subscriptions {
+		subscriptions[id] = sub
+	}
+	return subscriptions
+}
+
+func (c *ConfigMapCache) GetSubscription(id string) *Subscription {
+	lock.Lock()
+	defer lock.Unlock()
+	var sub Subscription
+	var ok bool
+	if sub, ok = c.subscriptions[id]; !ok {
+		return nil
+	}
+
+	return &sub
+}
+
+// Add a subscription configmap, this in turn triggers the informer handler
+// which adds it to the in-mem cache.
+func (c *ConfigMapCache) AddSubscription(ctx context.Context, s *Subscription) error {
+	configMap, err := s.ToConfigMap()
+	if err != nil {
+		return err
+	}
+
+	err = create(ctx, configMap)
+	return err
+}
+
+func (c *ConfigMapCache) UpdateSubscription(ctx context.Context, s *Subscription) error {
+	configMap, err := s.ToConfigMap()
+	if err != nil {
+		return err
+	}
+
+	err = update(ctx, configMap)
+	return err
+}
+
+func (c *ConfigMapCache) GetQuota(id string) *Quota {
+	lock.Lock()
+	defer lock.Unlock()
+	var q Quota
+	var ok bool
+	if q, ok = c.quotas[id]; !ok {
+		return nil
+	}
+
+	return &q
+}
+
+// Create or update a quota configmap, this in turn triggers the informer
+// handler which adds it to the in-mem cache.
+func (c *ConfigMapCache) SetQuota(ctx context.Context, q *Quota) error {
+	configMap, err := q.ToConfigMap()
+	if err != nil {
+		return err
+	}
+
+	if c.GetQuota(q.Id) == nil {
+		err = create(ctx, configMap)
+		// Quotas are written often, so the informer may not have caught up yet
+		if !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+
+	err = update(ctx, configMap)
+	return err
+}
+
+func (c *ConfigMapCache) GetLeaderboard(id string) *Leaderboard {
+	lock.Lock()
+	defer lock.Unlock()
+	var l Leaderboard
+	var ok bool
+	if l, ok = c.leaderboards[id]; !ok {
+		return nil
+	}
+
+	return &l
+}
+
+// Create or update a leaderboard configmap, this in turn triggers the
+// informer handler which adds it to the in-mem cache.
+func (c *ConfigMapCache) SetLeaderboard(ctx context.Context, l *Leaderboard) error {
+	configMap, err := l.ToConfigMap()
+	if err != nil {
+		return err
+	}
+
+	if c.GetLeaderboard(l.Id) == nil {
+		err = create(ctx, configMap)
+		// Games can end back to back before the informer has caught up
+		if !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+
+	err = update(ctx, configMap)
+	return err
+}
+
+// Getter for final jeopardy channels in the cache
+func (c *ConfigMapCache) ListFinals() map[string]FinalJeopardy {
+	lock.Lock()
+	defer lock.Unlock()
+	finals := make(map[string]FinalJeopardy, len(c.finals))
+	for id, f := range c.finals {
+		finals[id] = f
+	}
+	return finals
+}
+
+func (c *ConfigMapCache) GetFinal(id string) *FinalJeopardy {
+	lock.Lock()
+	defer lock.Unlock()
+	var f FinalJeopardy
+	var ok bool
+	if f, ok = c.finals[id]; !ok {
+		return nil
+	}
+
+	return &f
+}
+
+// Create or update a final jeopardy configmap, this in turn triggers the
+// informer handler which adds it to the in-mem cache.
+func (c *ConfigMapCache) SetFinal(ctx context.Context, f *FinalJeopardy) error {
+	configMap, err := f.ToConfigMap()
+	if err != nil {
+		return err
+	}
+
+	if c.GetFinal(f.Id) == nil {
+		err = create(ctx, configMap)
+	} else {
+		err = update(ctx, configMap)
+	}
+	return err
+}
+
+func (c *ConfigMapCache) GetGuildConfig(id string) *GuildConfig {
+	lock.Lock()
+	defer lock.Unlock()
+	var g GuildConfig
+	var ok bool
+	if g, ok = c.configs[id]; !ok {
+		return nil
+	}
+
+	return &g
+}
+
+// Create or update a guild config configmap. It's stored in the in-mem cache
+// right away, since settings are read from the cache on every message and the
+// informer takes a moment to catch up. The informer still picks up changes
+// made anywhere else.
+func (c *ConfigMapCache) SetGuildConfig(ctx context.Context, g *GuildConfig) error {
+	configMap, err := g.ToConfigMap()
+	if err != nil {
+		return err
+	}
+
+	exists := c.GetGuildConfig(g.Id) != nil
+	if !exists {
+		err = create(ctx, configMap)
+		// Another replica might have made it before the informer caught up
+		exists = apierrors.IsAlreadyExists(err)
+	}
+	if exists {
+		err = update(ctx, configMap)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.storeConfigMap(configMap, "saving")
+	return nil
+}
+
 /*
 Delete the configmap from the cluster which in turn triggers
 
-	the delete handler to remove it from the in-mem cache
+	the delete handler to remove it from the in-mem cache. A configmap
+	that's already gone counts as deleted.
 */
-func (c *ConfigMapCache) Delete(name string) {
-	cmClient := Client.CoreV1().ConfigMaps(namespace)
-	err := cmClient.Delete(context.TODO(), name, metav1.DeleteOptions{})
-	if err != nil {
-		log.Printf("warning: failed to delete configmap: %v\n", err)
-		log.Println("deleting from in-mem cache anyway")
+func (c *ConfigMapCache) Delete(ctx context.Context, name string) error {
+	logging.From(ctx).Debug("deleting configmap", "name", name)
+	err := Client.CoreV1().ConfigMaps(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
 	}
+	return err
+}
+
+// Writes are logged with the correlation id of the request that made them.
+func create(ctx context.Context, configMap *corev1.ConfigMap) error {
+	logging.From(ctx).Debug("creating configmap", "name", configMap.Name)
+	_, err := Client.CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{})
+	return err
+}
+
+func update(ctx context.Context, configMap *corev1.ConfigMap) error {
+	logging.From(ctx).Debug("updating configmap", "name", configMap.Name)
+	_, err := Client.CoreV1().ConfigMaps(namespace).Update(ctx, configMap, metav1.UpdateOptions{})
+	return err
 }
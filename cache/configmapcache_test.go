@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"errors"
 	"reflect"
 	"strings"
@@ -8,406 +9,15 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
 
 	"github.com/highsaltlevels/saltbot/testutil"
 )
 
-func TestAddConfigMap(t *testing.T) {
-	tests := []struct {
-		name             string
-		configMap        interface{}
-		cache            *ConfigMapCache
-		expectedPoll     *Poll
-		expectedReminder *Reminder
-	}{
-		{
-			name: "Test successfully adding poll",
-			configMap: &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "poll-foo",
-				},
-				Data: map[string]string{
-					"json": `{"author":"1234","channel":"1234","prompt":"prompt","choices":["choice1","choice2"],"expiry":1234,"id":"1234","votes":{"0":["chooser"],"1":[]}}`,
-				},
-			},
-			cache: &ConfigMapCache{
-				polls:     map[string]Poll{},
-				reminders: map[string]Reminder{},
-			},
-			expectedPoll: &Poll{
-				Author:  "1234",
-				Channel: "1234",
-				Prompt:  "prompt",
-				Choices: []string{"choice1", "choice2"},
-				Expiry:  1234,
-				Id:      "1234",
-				Votes: map[string][]interface{}{
-					"0": []interface{}{"chooser"},
-					"1": []interface{}{},
-				},
-			},
-		},
-		{
-			name: "Test failure adding poll invalid JSON",
-			configMap: &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "poll-foo",
-				},
-				Data: map[string]string{
-					"json": "i am not json :)",
-				},
-			},
-			cache: &ConfigMapCache{
-				polls:     map[string]Poll{},
-				reminders: map[string]Reminder{},
-			},
-		},
-		{
-			name: "Test failure adding poll json missing",
-			configMap: &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "poll-foo",
-				},
-				Data: map[string]string{},
-			},
-			cache: &ConfigMapCache{
-				polls:     map[string]Poll{},
-				reminders: map[string]Reminder{},
-			},
-		},
-		{
-			name: "Test successfully adding reminder",
-			configMap: &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "reminder-foo",
-				},
-				Data: map[string]string{
-					"json": `{"author":"1234","channel":"1234","expiry":1234,"msg":"bloop","id":"1234"}`,
-				},
-			},
-			cache: &ConfigMapCache{
-				polls:     map[string]Poll{},
-				reminders: map[string]Reminder{},
-			},
-			expectedReminder: &Reminder{
-				Author:  "1234",
-				Channel: "1234",
-				Expiry:  1234,
-				Message: "bloop",
-				Id:      "1234",
-			},
-		},
-		{
-			name: "Test failure adding reminder invalid JSON",
-			configMap: &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "reminder-foo",
-				},
-				Data: map[string]string{
-					"json": "i am not json :)",
-				},
-			},
-			cache: &ConfigMapCache{
-				polls:     map[string]Poll{},
-				reminders: map[string]Reminder{},
-			},
-		},
-		{
-			name: "Test failure adding reminder json missing",
-			configMap: &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "reminder-foo",
-				},
-				Data: map[string]string{},
-			},
-			cache: &ConfigMapCache{
-				polls:     map[string]Poll{},
-				reminders: map[string]Reminder{},
-			},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Set the cache before adding
-			Cache = tt.cache
-			Cache.addConfigMap(tt.configMap)
-			if tt.expectedPoll != nil {
-				if len(Cache.polls) != 1 {
-					t.Fatalf("should have 1 poll, but got no polls")
-				}
-				for _, poll := range Cache.polls {
-					validatePoll(t, tt.expectedPoll, &poll)
-				}
-			} else {
-				if len(Cache.polls) != 0 {
-					t.Fatalf("expected no polls but got: %d", len(Cache.polls))
-				}
-			}
-			if tt.expectedReminder != nil {
-				if len(Cache.reminders) != 1 {
-					t.Fatalf("should have 1 reminder, but got no reminders")
-				}
-				for _, reminder := range Cache.reminders {
-					validateReminder(t, tt.expectedReminder, &reminder)
-				}
-			} else {
-				if len(Cache.reminders) != 0 {
-					t.Fatalf("expected no reminders but got: %d", len(Cache.reminders))
-				}
-			}
-		})
-	}
-}
-
-func TestUpdateConfigMap(t *testing.T) {
-	tests := []struct {
-		name             string
-		configMap        interface{}
-		cache            *ConfigMapCache
-		expectedPoll     *Poll
-		expectedReminder *Reminder
-	}{
-		{
-			name: "Test successfully updating poll",
-			configMap: &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "poll-foo",
-				},
-				Data: map[string]string{
-					"json": `{"author":"1234","channel":"1234","prompt":"prompt","choices":["choice1","choice2"],"expiry":1234,"id":"1234","votes":{"0":["chooser"],"1":[]}}`,
-				},
-			},
-			cache: &ConfigMapCache{
-				polls: map[string]Poll{
-					"1234": Poll{
-						Author:  "1234",
-						Channel: "1234",
-						Prompt:  "prompt",
-						Choices: []string{"choice1", "choice2"},
-						Expiry:  1234,
-						Id:      "1234",
-						Votes: map[string][]interface{}{
-							"0": []interface{}{},
-							"1": []interface{}{"chooser"},
-						},
-					},
-				},
-				reminders: map[string]Reminder{},
-			},
-			expectedPoll: &Poll{
-				Author:  "1234",
-				Channel: "1234",
-				Prompt:  "prompt",
-				Choices: []string{"choice1", "choice2"},
-				Expiry:  1234,
-				Id:      "1234",
-				Votes: map[string][]interface{}{
-					"0": []interface{}{"chooser"},
-					"1": []interface{}{},
-				},
-			},
-		},
-		{
-			name: "Test failure updating poll invalid JSON",
-			configMap: &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "poll-foo",
-				},
-				Data: map[string]string{
-					"json": "i am not json :)",
-				},
-			},
-			cache: &ConfigMapCache{
-				polls:     map[string]Poll{},
-				reminders: map[string]Reminder{},
-			},
-		},
-		{
-			name: "Test successfully updating reminder",
-			configMap: &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "reminder-foo",
-				},
-				Data: map[string]string{
-					"json": `{"author":"1234","channel":"1234","expiry":1234,"msg":"bloop","id":"1234"}`,
-				},
-			},
-			cache: &ConfigMapCache{
-				polls: map[string]Poll{},
-				reminders: map[string]Reminder{
-					"1234": Reminder{
-						Author:  "1234",
-						Channel: "1234",
-						Expiry:  1234,
-						Message: "something else",
-						Id:      "1234",
-					},
-				},
-			},
-			expectedReminder: &Reminder{
-				Author:  "1234",
-				Channel: "1234",
-				Expiry:  1234,
-				Message: "bloop",
-				Id:      "1234",
-			},
-		},
-		{
-			name: "Test failure updating reminder invalid JSON",
-			configMap: &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "reminder-foo",
-				},
-				Data: map[string]string{
-					"json": "i am not json :)",
-				},
-			},
-			cache: &ConfigMapCache{
-				polls:     map[string]Poll{},
-				reminders: map[string]Reminder{},
-			},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Set the cache before adding
-			Cache = tt.cache
-			// We ignore the old object, so let's just reuse tt.configMap
-			Cache.updateConfigMap(tt.configMap, tt.configMap)
-			if tt.expectedPoll != nil {
-				if len(Cache.polls) != 1 {
-					t.Fatalf("should have 1 poll, but got no polls")
-				}
-				for _, poll := range Cache.polls {
-					validatePoll(t, tt.expectedPoll, &poll)
-				}
-			} else {
-				if len(Cache.polls) != 0 {
-					t.Fatalf("expected no polls but got: %d", len(Cache.polls))
-				}
-			}
-			if tt.expectedReminder != nil {
-				if len(Cache.reminders) != 1 {
-					t.Fatalf("should have 1 reminder, but got no reminders")
-				}
-				for _, reminder := range Cache.reminders {
-					validateReminder(t, tt.expectedReminder, &reminder)
-				}
-			} else {
-				if len(Cache.reminders) != 0 {
-					t.Fatalf("expected no reminders but got: %d", len(Cache.reminders))
-				}
-			}
-		})
-	}
-}
-
-func TestDeleteConfigMap(t *testing.T) {
-	tests := []struct {
-		name      string
-		configMap interface{}
-		cache     *ConfigMapCache
-	}{
-		{
-			name: "Test successfully deleting poll",
-			configMap: &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "poll-foo",
-				},
-				Data: map[string]string{
-					"json": `{"author":"1234","channel":"1234","prompt":"prompt","choices":["choice1","choice2"],"expiry":1234,"id":"1234","votes":{"0":["chooser"],"1":[]}}`,
-				},
-			},
-			cache: &ConfigMapCache{
-				polls: map[string]Poll{
-					"1234": Poll{
-						Author:  "1234",
-						Channel: "1234",
-						Prompt:  "prompt",
-						Choices: []string{"choice1", "choice2"},
-						Expiry:  1234,
-						Id:      "1234",
-						Votes: map[string][]interface{}{
-							"0": []interface{}{"chooser"},
-							"1": []interface{}{},
-						},
-					},
-				},
-				reminders: map[string]Reminder{},
-			},
-		},
-		{
-			name: "Test failure deleting poll invalid name",
-			configMap: &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "poll",
-				},
-				Data: map[string]string{
-					"json": "",
-				},
-			},
-			cache: &ConfigMapCache{
-				polls:     map[string]Poll{},
-				reminders: map[string]Reminder{},
-			},
-		},
-		{
-			name: "Test successfully deleting reminder",
-			configMap: &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "reminder-foo",
-				},
-				Data: map[string]string{
-					"json": `{"author":"1234","channel":"1234","expiry":1234,"msg":"bloop","id":"1234"}`,
-				},
-			},
-			cache: &ConfigMapCache{
-				polls: map[string]Poll{},
-				reminders: map[string]Reminder{
-					"1234": Reminder{
-						Author:  "1234",
-						Channel: "1234",
-						Expiry:  1234,
-						Message: "something else",
-						Id:      "1234",
-					},
-				},
-			},
-		},
-		{
-			name: "Test failure deleting reminder invalid name",
-			configMap: &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "reminder",
-				},
-				Data: map[string]string{
-					"json": "",
-				},
-			},
-			cache: &ConfigMapCache{
-				polls:     map[string]Poll{},
-				reminders: map[string]Reminder{},
-			},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Set the cache before adding
-			Cache = tt.cache
-			// We ignore the old object, so let's just reuse tt.configMap
-			Cache.deleteConfigMap(tt.configMap)
-		})
-	}
-}
-
 func TestAddPoll(t *testing.T) {
 	tests := []struct {
 		name          string
-		client        kubernetes.Interface
+		client        KubeClient
 		poll          *Poll
 		expectedError error
 	}{
@@ -429,20 +39,6 @@ func TestAddPoll(t *testing.T) {
 			},
 			expectedError: errors.New(testutil.ExpectedError),
 		},
-		{
-			name:   "Test failed adding invalid poll",
-			client: &testutil.MockErrorK8sClient{},
-			poll: &Poll{
-				Id:     "1234",
-				Author: "1234",
-				Votes: map[string][]interface{}{
-					"1234": []interface{}{
-						make(chan bool),
-					},
-				},
-			},
-			expectedError: errors.New("failed to marshal poll"),
-		},
 	}
 
 	for _, tt := range tests {
@@ -450,7 +46,7 @@ func TestAddPoll(t *testing.T) {
 			Client = tt.client
 			c := ConfigMapCache{}
 
-			err := c.AddPoll(tt.poll)
+			err := c.AddPoll(context.Background(), tt.poll)
 			if tt.expectedError == nil {
 				if err != nil {
 					t.Errorf("expected nil error, but got: %v", err)
@@ -470,7 +66,7 @@ func TestAddPoll(t *testing.T) {
 func TestUpdatePoll(t *testing.T) {
 	tests := []struct {
 		name          string
-		client        kubernetes.Interface
+		client        KubeClient
 		poll          *Poll
 		expectedError error
 	}{
@@ -492,20 +88,6 @@ func TestUpdatePoll(t *testing.T) {
 			},
 			expectedError: errors.New(testutil.ExpectedError),
 		},
-		{
-			name:   "Test failed updating invalid poll",
-			client: &testutil.MockErrorK8sClient{},
-			poll: &Poll{
-				Id:     "1234",
-				Author: "1234",
-				Votes: map[string][]interface{}{
-					"1234": []interface{}{
-						make(chan bool),
-					},
-				},
-			},
-			expectedError: errors.New("failed to marshal poll"),
-		},
 	}
 
 	for _, tt := range tests {
@@ -513,7 +95,7 @@ func TestUpdatePoll(t *testing.T) {
 			Client = tt.client
 			c := ConfigMapCache{}
 
-			err := c.UpdatePoll(tt.poll)
+			err := c.UpdatePoll(context.Background(), tt.poll.Id, func(p *Poll) {})
 			if tt.expectedError == nil {
 				if err != nil {
 					t.Errorf("expected nil error, but got: %v", err)
@@ -533,7 +115,7 @@ func TestUpdatePoll(t *testing.T) {
 func TestAddReminder(t *testing.T) {
 	tests := []struct {
 		name          string
-		client        kubernetes.Interface
+		client        KubeClient
 		reminder      *Reminder
 		expectedError error
 	}{
@@ -549,14 +131,6 @@ func TestAddReminder(t *testing.T) {
 			reminder:      &Reminder{},
 			expectedError: errors.New(testutil.ExpectedError),
 		},
-		{
-			name:   "Test failed adding invalid reminder",
-			client: &testutil.MockErrorK8sClient{},
-			reminder: &Reminder{
-				Message: make(chan bool),
-			},
-			expectedError: errors.New("failed to convert reminder to configMap"),
-		},
 	}
 
 	for _, tt := range tests {
@@ -564,7 +138,7 @@ func TestAddReminder(t *testing.T) {
 			Client = tt.client
 			c := ConfigMapCache{}
 
-			err := c.AddReminder(tt.reminder, "user")
+			err := c.AddReminder(context.Background(), tt.reminder, "user")
 			if tt.expectedError == nil {
 				if err != nil {
 					t.Errorf("expected nil error, but got: %v", err)
@@ -742,10 +316,10 @@ func TestGetReminder(t *testing.T) {
 	}
 }
 
-func TestDelete(t *testing.T) {
+func TestDeletePollAndReminder(t *testing.T) {
 	tests := []struct {
 		name   string
-		client kubernetes.Interface
+		client KubeClient
 		cache  *ConfigMapCache
 	}{
 		{
@@ -807,7 +381,8 @@ func TestDelete(t *testing.T) {
 			Client = tt.client
 			Cache = tt.cache
 
-			Cache.Delete("1234")
+			Cache.DeletePoll(context.Background(), "1234")
+			Cache.DeleteReminder(context.Background(), "1234")
 		})
 	}
 }
@@ -853,3 +428,263 @@ func validateReminder(t *testing.T, expected, actual *Reminder) {
 		t.Errorf("incorrect id. Expected: %s, got: %s", expected.Id, actual.Id)
 	}
 }
+
+func TestStoreAndDeleteRating(t *testing.T) {
+	Cache = NewInMemConfigMapCache(map[string]Poll{}, map[string]Reminder{})
+	rating := GifRating{
+		Author:  "1234",
+		Guild:   "guild",
+		Channel: "channel",
+		Rating:  "pg",
+		Id:      "channel",
+	}
+	configMap, err := rating.ToConfigMap()
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if configMap.ObjectMeta.Name != "rating-channel" {
+		t.Errorf("expected configmap name rating-channel, but got %s", configMap.ObjectMeta.Name)
+	}
+
+	Cache.addConfigMap(configMap)
+	actual := Cache.GetRating("channel")
+	if actual == nil {
+		t.Fatalf("expected rating to be cached but got nil")
+	}
+	if !reflect.DeepEqual(*actual, rating) {
+		t.Errorf("expected rating %+v, but got %+v", rating, *actual)
+	}
+
+	Cache.deleteConfigMap(configMap)
+	if Cache.GetRating("channel") != nil {
+		t.Errorf("expected rating to be deleted")
+	}
+}
+
+func TestDeleteConfigMap(t *testing.T) {
+	kube := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "rating-channel", Namespace: namespace},
+	})
+	Client = fakeClient{kube}
+	Cache = NewInMemConfigMapCache(map[string]Poll{}, map[string]Reminder{})
+
+	if err := Cache.Delete(context.Background(), "rating-channel"); err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	// Already gone
+	if err := Cache.Delete(context.Background(), "rating-channel"); err != nil {
+		t.Errorf("expected deleting a missing configmap to succeed but got: %v", err)
+	}
+
+	Client = &testutil.MockErrorK8sClient{}
+	if err := Cache.Delete(context.Background(), "rating-channel"); err == nil {
+		t.Errorf("expected an error but got nil")
+	}
+}
+
+func TestStoreAndDeleteSubscription(t *testing.T) {
+	Cache = NewInMemConfigMapCache(map[string]Poll{}, map[string]Reminder{})
+	sub := Subscription{
+		Author:         "1234",
+		Guild:          "guild",
+		Channel:        "channel",
+		YoutubeChannel: "UCsaltysaltysaltysalty01",
+		Title:          "Salty Channel",
+		LastVideoId:    "video1",
+		Id:             "abcd",
+	}
+	configMap, err := sub.ToConfigMap()
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if configMap.ObjectMeta.Name != "subscription-abcd" {
+		t.Errorf("expected configmap name subscription-abcd, but got %s", configMap.ObjectMeta.Name)
+	}
+
+	Cache.addConfigMap(configMap)
+	actual := Cache.GetSubscription("abcd")
+	if actual == nil {
+		t.Fatalf("expected subscription to be cached but got nil")
+	}
+	if !reflect.DeepEqual(*actual, sub) {
+		t.Errorf("expected subscription %+v, but got %+v", sub, *actual)
+	}
+
+	sub.LastVideoId = "video2"
+	updated, _ := sub.ToConfigMap()
+	Cache.updateConfigMap(configMap, updated)
+	if actual = Cache.GetSubscription("abcd"); actual.LastVideoId != "video2" {
+		t.Errorf("expected last video id video2, but got %s", actual.LastVideoId)
+	}
+	if len(Cache.ListSubscriptions()) != 1 {
+		t.Errorf("expected 1 subscription, but got %d", len(Cache.ListSubscriptions()))
+	}
+
+	Cache.deleteConfigMap(configMap)
+	if Cache.GetSubscription("abcd") != nil {
+		t.Errorf("expected subscription to be deleted")
+	}
+}
+
+func TestStoreAndDeleteQuota(t *testing.T) {
+	Cache = NewInMemConfigMapCache(map[string]Poll{}, map[string]Reminder{})
+	q := Quota{
+		Day:   "2023-07-01",
+		Units: map[string]int{"search.list": 100},
+		Id:    "youtube",
+	}
+	configMap, err := q.ToConfigMap()
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+
+	Cache.addConfigMap(configMap)
+	actual := Cache.GetQuota("youtube")
+	if actual == nil {
+		t.Fatalf("expected quota to be cached but got nil")
+	}
+	if !reflect.DeepEqual(*actual, q) {
+		t.Errorf("expected quota %+v, but got %+v", q, *actual)
+	}
+
+	Cache.deleteConfigMap(configMap)
+	if Cache.GetQuota("youtube") != nil {
+		t.Errorf("expected quota to be deleted")
+	}
+}
+
+func TestStoreAndDeleteLeaderboard(t *testing.T) {
+	Cache = NewInMemConfigMapCache(map[string]Poll{}, map[string]Reminder{})
+	l := Leaderboard{
+		Players: map[string]PlayerStats{
+			"1234": {Name: "salty", Score: 1200, Correct: 3, Attempts: 4, Streak: 2, BestStreak: 3},
+		},
+		Id: "guild",
+	}
+	configMap, err := l.ToConfigMap()
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+
+	Cache.addConfigMap(configMap)
+	actual := Cache.GetLeaderboard("guild")
+	if actual == nil {
+		t.Fatalf("expected leaderboard to be cached but got nil")
+	}
+	if !reflect.DeepEqual(*actual, l) {
+		t.Errorf("expected leaderboard %+v, but got %+v", l, *actual)
+	}
+
+	Cache.deleteConfigMap(configMap)
+	if Cache.GetLeaderboard("guild") != nil {
+		t.Errorf("expected leaderboard to be deleted")
+	}
+}
+
+func TestStoreAndDeleteFinal(t *testing.T) {
+	Cache = NewInMemConfigMapCache(map[string]Poll{}, map[string]Reminder{})
+	f := FinalJeopardy{
+		Author:     "1234",
+		Guild:      "guild",
+		Channel:    "channel",
+		LastPosted: "2023-07-05",
+		Id:         "guild",
+	}
+	configMap, err := f.ToConfigMap()
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+
+	Cache.addConfigMap(configMap)
+	actual := Cache.GetFinal("guild")
+	if actual == nil {
+		t.Fatalf("expected final jeopardy to be cached but got nil")
+	}
+	if !reflect.DeepEqual(*actual, f) || len(Cache.ListFinals()) != 1 {
+		t.Errorf("expected final jeopardy %+v, but got %+v", f, *actual)
+	}
+
+	Cache.deleteConfigMap(configMap)
+	if Cache.GetFinal("guild") != nil {
+		t.Errorf("expected final jeopardy to be deleted")
+	}
+}
+
+func TestStoreAndDeleteGuildConfig(t *testing.T) {
+	Cache = NewInMemConfigMapCache(map[string]Poll{}, map[string]Reminder{})
+	g := GuildConfig{
+		Prefix:         "?",
+		Disabled:       []string{"gif"},
+		Timezone:       "Europe/London",
+		ResultsChannel: "results",
+		Id:             "guild",
+	}
+	configMap, err := g.ToConfigMap()
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+
+	Cache.addConfigMap(configMap)
+	actual := Cache.GetGuildConfig("guild")
+	if actual == nil {
+		t.Fatalf("expected guild config to be cached but got nil")
+	}
+	if !reflect.DeepEqual(*actual, g) {
+		t.Errorf("expected guild config %+v, but got %+v", g, *actual)
+	}
+
+	Cache.deleteConfigMap(configMap)
+	if Cache.GetGuildConfig("guild") != nil {
+		t.Errorf("expected guild config to be deleted")
+	}
+}
+
+func TestSetRating(t *testing.T) {
+	tests := []struct {
+		name          string
+		client        KubeClient
+		existing      bool
+		expectedError error
+	}{
+		{
+			name:   "Test creating a rating successfully",
+			client: &testutil.MockK8sClient{},
+		},
+		{
+			name:     "Test updating a rating successfully",
+			client:   &testutil.MockK8sClient{},
+			existing: true,
+		},
+		{
+			name:          "Test creating a rating k8s error",
+			client:        &testutil.MockErrorK8sClient{},
+			expectedError: errors.New(testutil.ExpectedError),
+		},
+		{
+			name:          "Test updating a rating k8s error",
+			client:        &testutil.MockErrorK8sClient{},
+			existing:      true,
+			expectedError: errors.New(testutil.ExpectedError),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rating := &GifRating{Id: "guild", Guild: "guild", Rating: "g"}
+			Cache = NewInMemConfigMapCache(map[string]Poll{}, map[string]Reminder{})
+			if tt.existing {
+				configMap, _ := rating.ToConfigMap()
+				Cache.Store(configMap)
+			}
+			Client = tt.client
+
+			err := Cache.SetRating(context.Background(), rating)
+			if tt.expectedError == nil && err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+			if tt.expectedError != nil && (err == nil || !strings.Contains(err.Error(), tt.expectedError.Error())) {
+				t.Errorf("expected error '%v', but got '%v'", tt.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Where a guild's daily Final Jeopardy is posted. LastPosted is the day it was
+// last posted, so that restarts don't post it twice. Id is the guild id.
+type FinalJeopardy struct {
+	Author     string `json:"author"`
+	Guild      string `json:"guild"`
+	Channel    string `json:"channel"`
+	LastPosted string `json:"lastPosted"`
+	Id         string `json:"id"`
+}
+
+func (f *FinalJeopardy) FromConfigMap(configMap *corev1.ConfigMap) error {
+	jsonData, ok := configMap.Data["json"]
+	if !ok {
+		return fmt.Errorf("could not find json data in final jeopardy configmap")
+	}
+
+	err := json.Unmarshal([]byte(jsonData), &f)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal configmap to final jeopardy: %v", err)
+	}
+
+	return nil
+}
+
+func (f *FinalJeopardy) ToConfigMap() (*corev1.ConfigMap, error) {
+	bytes, err := json.Marshal(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal final jeopardy: %v", err)
+	}
+
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "final-" + f.Id,
+			Labels: map[string]string{
+				"author": f.Author,
+			},
+		},
+		Data: map[string]string{
+			"json": string(bytes),
+		},
+	}, nil
+}
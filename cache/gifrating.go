@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The giphy content rating for a whole guild, or for a single channel when
+// Channel is set. Id is the guild or channel id the rating applies to.
+type GifRating struct {
+	Author  string `json:"author"`
+	Guild   string `json:"guild"`
+	Channel string `json:"channel"`
+	Rating  string `json:"rating"`
+	Id      string `json:"id"`
+}
+
+func (r *GifRating) FromConfigMap(configMap *corev1.ConfigMap) error {
+	jsonData, ok := configMap.Data["json"]
+	if !ok {
+		return fmt.Errorf("could not find json data in rating configmap")
+	}
+
+	err := json.Unmarshal([]byte(jsonData), &r)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal configmap to rating: %v", err)
+	}
+
+	return nil
+}
+
+func (r *GifRating) ToConfigMap() (*corev1.ConfigMap, error) {
+	bytes, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rating: %v", err)
+	}
+
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "rating-" + r.Id,
+			Labels: map[string]string{
+				"author": r.Author,
+			},
+		},
+		Data: map[string]string{
+			"json": string(bytes),
+		},
+	}, nil
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// A guild's settings. Empty fields use saltbot's defaults. Id is the guild id.
+type GuildConfig struct {
+	Prefix string `json:"prefix,omitempty"`
+
+	// If set, only these commands can be used
+	Enabled  []string `json:"enabled,omitempty"`
+	Disabled []string `json:"disabled,omitempty"`
+
+	Timezone string `json:"timezone,omitempty"`
+
+	// A language tag like "de", for how numbers are written
+	Locale string `json:"locale,omitempty"`
+
+	// Where poll results are posted instead of the poll's channel
+	ResultsChannel string `json:"resultsChannel,omitempty"`
+
+	// Roles whose members are bot admins, along with Manage Server
+	AdminRoles []string `json:"adminRoles,omitempty"`
+
+	// Users who can't use any command
+	DeniedUsers []string `json:"deniedUsers,omitempty"`
+
+	// Who can use a command, keyed on the command's name
+	Policies map[string]Policy `json:"policies,omitempty"`
+
+	Id string `json:"id"`
+}
+
+// Limits on who can use a command and where. Empty fields don't limit it.
+type Policy struct {
+	Roles       []string `json:"roles,omitempty"`
+	Channels    []string `json:"channels,omitempty"`
+	DeniedUsers []string `json:"deniedUsers,omitempty"`
+}
+
+func (g *GuildConfig) FromConfigMap(configMap *corev1.ConfigMap) error {
+	jsonData, ok := configMap.Data["json"]
+	if !ok {
+		return fmt.Errorf("could not find json data in guild config configmap")
+	}
+
+	err := json.Unmarshal([]byte(jsonData), &g)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal configmap to guild config: %v", err)
+	}
+
+	return nil
+}
+
+func (g *GuildConfig) ToConfigMap() (*corev1.ConfigMap, error) {
+	bytes, err := json.Marshal(g)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal guild config: %v", err)
+	}
+
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "config-" + g.Id,
+		},
+		Data: map[string]string{
+			"json": string(bytes),
+		},
+	}, nil
+}
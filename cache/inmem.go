@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	saltbotv1 "github.com/highsaltlevels/saltbot/apis/saltbot/v1"
+)
+
+// A kubernetes client for running without a cluster. Configmap, poll and
+// reminder writes go straight into the in-memory cache instead of through an
+// informer.
+type InMemClient struct {
+	kubernetes.Interface
+}
+
+func (c InMemClient) CoreV1() typedcorev1.CoreV1Interface {
+	return inMemCoreV1{}
+}
+
+func (c InMemClient) SaltbotV1() saltbotv1.SaltbotV1Interface {
+	return inMemSaltbotV1{}
+}
+
+type inMemCoreV1 struct {
+	typedcorev1.CoreV1Interface
+}
+
+func (c inMemCoreV1) ConfigMaps(namespace string) typedcorev1.ConfigMapInterface {
+	return inMemConfigMaps{}
+}
+
+type inMemConfigMaps struct {
+	typedcorev1.ConfigMapInterface
+}
+
+func (c inMemConfigMaps) Create(ctx context.Context, configMap *corev1.ConfigMap, opts metav1.CreateOptions) (*corev1.ConfigMap, error) {
+	Cache.Store(configMap)
+	return configMap, nil
+}
+
+func (c inMemConfigMaps) Update(ctx context.Context, configMap *corev1.ConfigMap, opts metav1.UpdateOptions) (*corev1.ConfigMap, error) {
+	Cache.Store(configMap)
+	return configMap, nil
+}
+
+func (c inMemConfigMaps) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	Cache.deleteConfigMap(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	return nil
+}
+
+type inMemSaltbotV1 struct{}
+
+func (c inMemSaltbotV1) Polls(namespace string) saltbotv1.PollInterface {
+	return inMemPolls{}
+}
+
+func (c inMemSaltbotV1) Reminders(namespace string) saltbotv1.ReminderInterface {
+	return inMemReminders{}
+}
+
+type inMemPolls struct {
+	saltbotv1.PollInterface
+}
+
+func (c inMemPolls) Get(ctx context.Context, name string, opts metav1.GetOptions) (*saltbotv1.Poll, error) {
+	p := Cache.GetPoll(name, "")
+	if p == nil {
+		return nil, apierrors.NewNotFound(saltbotv1.Resource("polls"), name)
+	}
+	return p.ToResource(), nil
+}
+
+func (c inMemPolls) Create(ctx context.Context, poll *saltbotv1.Poll, opts metav1.CreateOptions) (*saltbotv1.Poll, error) {
+	Cache.storePoll(poll, "storing")
+	return poll, nil
+}
+
+func (c inMemPolls) Update(ctx context.Context, poll *saltbotv1.Poll, opts metav1.UpdateOptions) (*saltbotv1.Poll, error) {
+	Cache.storePoll(poll, "storing")
+	return poll, nil
+}
+
+func (c inMemPolls) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	Cache.deletePollResource(&saltbotv1.Poll{Spec: saltbotv1.PollSpec{Id: name}})
+	return nil
+}
+
+type inMemReminders struct {
+	saltbotv1.ReminderInterface
+}
+
+func (c inMemReminders) Create(ctx context.Context, reminder *saltbotv1.Reminder, opts metav1.CreateOptions) (*saltbotv1.Reminder, error) {
+	Cache.storeReminder(reminder, "storing")
+	return reminder, nil
+}
+
+func (c inMemReminders) Update(ctx context.Context, reminder *saltbotv1.Reminder, opts metav1.UpdateOptions) (*saltbotv1.Reminder, error) {
+	Cache.storeReminder(reminder, "storing")
+	return reminder, nil
+}
+
+func (c inMemReminders) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	Cache.deleteReminderResource(&saltbotv1.Reminder{Spec: saltbotv1.ReminderSpec{Id: name}})
+	return nil
+}
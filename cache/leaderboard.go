@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Trivia stats for a single period, like the week "2023-W27" or the month
+// "2023-07". Stats from an older period are stale and start over.
+type PeriodStats struct {
+	Period   string `json:"period"`
+	Score    int    `json:"score"`
+	Correct  int    `json:"correct"`
+	Attempts int    `json:"attempts"`
+}
+
+type PlayerStats struct {
+	Name       string      `json:"name"`
+	Score      int         `json:"score"`
+	Correct    int         `json:"correct"`
+	Attempts   int         `json:"attempts"`
+	Streak     int         `json:"streak"`
+	BestStreak int         `json:"bestStreak"`
+	Weekly     PeriodStats `json:"weekly"`
+	Monthly    PeriodStats `json:"monthly"`
+}
+
+// A guild's trivia stats keyed by user id. Id is the guild id.
+type Leaderboard struct {
+	Players map[string]PlayerStats `json:"players"`
+	Id      string                 `json:"id"`
+}
+
+func (l *Leaderboard) FromConfigMap(configMap *corev1.ConfigMap) error {
+	jsonData, ok := configMap.Data["json"]
+	if !ok {
+		return fmt.Errorf("could not find json data in leaderboard configmap")
+	}
+
+	err := json.Unmarshal([]byte(jsonData), &l)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal configmap to leaderboard: %v", err)
+	}
+
+	return nil
+}
+
+func (l *Leaderboard) ToConfigMap() (*corev1.ConfigMap, error) {
+	bytes, err := json.Marshal(l)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal leaderboard: %v", err)
+	}
+
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "leaderboard-" + l.Id,
+		},
+		Data: map[string]string{
+			"json": string(bytes),
+		},
+	}, nil
+}
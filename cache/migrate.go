@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Move polls and reminders that older saltbots stored as JSON in configmaps
+// to Poll and Reminder resources. A configmap is only deleted once its
+// resource exists, so anything that fails is tried again on the next start.
+func Migrate(ctx context.Context) error {
+	configMaps, err := Client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list configmaps: %w", err)
+	}
+
+	for i := range configMaps.Items {
+		configMap := &configMaps.Items[i]
+
+		var err error
+		switch {
+		case strings.HasPrefix(configMap.Name, "poll-"):
+			err = migratePoll(ctx, configMap)
+		case strings.HasPrefix(configMap.Name, "reminder-"):
+			err = migrateReminder(ctx, configMap)
+		default:
+			continue
+		}
+
+		if err != nil {
+			slog.Warn("failed to migrate configmap, leaving it", "name", configMap.Name, "error", err)
+			continue
+		}
+
+		err = Client.CoreV1().ConfigMaps(namespace).Delete(ctx, configMap.Name, metav1.DeleteOptions{})
+		if err != nil {
+			slog.Warn("migrated configmap but failed to delete it", "name", configMap.Name, "error", err)
+			continue
+		}
+		slog.Info("migrated configmap", "name", configMap.Name)
+	}
+
+	return nil
+}
+
+func migratePoll(ctx context.Context, configMap *corev1.ConfigMap) error {
+	p := Poll{}
+	err := p.FromConfigMap(configMap)
+	if err != nil {
+		return err
+	}
+
+	_, err = Client.SaltbotV1().Polls(namespace).Create(ctx, p.ToResource(), metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+func migrateReminder(ctx context.Context, configMap *corev1.ConfigMap) error {
+	r := Reminder{}
+	err := r.FromConfigMap(configMap)
+	if err != nil {
+		return err
+	}
+
+	_, err = Client.SaltbotV1().Reminders(namespace).Create(ctx, r.ToResource(), metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
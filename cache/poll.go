@@ -3,21 +3,26 @@ package cache
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	saltbotv1 "github.com/highsaltlevels/saltbot/apis/saltbot/v1"
 )
 
 type Poll struct {
-	Author  string                   `json:"author"`
-	Channel string                   `json:"channel"`
-	Prompt  string                   `json:"prompt"`
-	Choices []string                 `json:"choices"`
-	Expiry  int64                    `json:"expiry"`
-	Id      string                   `json:"id"`
-	Votes   map[string][]interface{} `json:"votes"`
+	Author  string              `json:"author"`
+	Channel string              `json:"channel"`
+	Guild   string              `json:"guild,omitempty"`
+	Prompt  string              `json:"prompt"`
+	Choices []string            `json:"choices"`
+	Expiry  int64               `json:"expiry"`
+	Id      string              `json:"id"`
+	Votes   map[string][]string `json:"votes"`
 }
 
+// Polls used to be stored as JSON in configmaps. Only needed for migrating them.
 func (p *Poll) FromConfigMap(configMap *corev1.ConfigMap) error {
 	jsonData, ok := configMap.Data["json"]
 	if !ok {
@@ -32,21 +37,46 @@ func (p *Poll) FromConfigMap(configMap *corev1.ConfigMap) error {
 	return nil
 }
 
-func (p *Poll) ToConfigMap() (*corev1.ConfigMap, error) {
-	bytes, err := json.Marshal(p)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal poll: %v", err)
-	}
-
-	return &corev1.ConfigMap{
+// The poll as a Poll resource, named after its id.
+func (p *Poll) ToResource() *saltbotv1.Poll {
+	return &saltbotv1.Poll{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: "poll-" + p.Id,
+			Name: p.Id,
 			Labels: map[string]string{
 				"author": p.Author,
 			},
 		},
-		Data: map[string]string{
-			"json": string(bytes),
+		Spec: saltbotv1.PollSpec{
+			Id:      p.Id,
+			Author:  p.Author,
+			Channel: p.Channel,
+			Guild:   p.Guild,
+			Prompt:  p.Prompt,
+			Choices: p.Choices,
+			Expiry:  metav1.NewTime(time.Unix(p.Expiry, 0)),
+			Votes:   copyVotes(p.Votes),
 		},
-	}, nil
+	}
+}
+
+func (p *Poll) FromResource(poll *saltbotv1.Poll) {
+	*p = Poll{
+		Author:  poll.Spec.Author,
+		Channel: poll.Spec.Channel,
+		Guild:   poll.Spec.Guild,
+		Prompt:  poll.Spec.Prompt,
+		Choices: poll.Spec.Choices,
+		Expiry:  poll.Spec.Expiry.Unix(),
+		Id:      poll.Spec.Id,
+		Votes:   copyVotes(poll.Spec.Votes),
+	}
+}
+
+// Copy the votes so the poll and its resource don't share them.
+func copyVotes(votes map[string][]string) map[string][]string {
+	copied := make(map[string][]string, len(votes))
+	for choice, voters := range votes {
+		copied[choice] = append([]string{}, voters...)
+	}
+	return copied
 }
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// API quota spent on a single day. Id is the API the quota belongs to, e.g.
+// "youtube", and Units is keyed by the kind of call that spent them.
+type Quota struct {
+	Day       string         `json:"day"`
+	Units     map[string]int `json:"units"`
+	Exhausted bool           `json:"exhausted"`
+	Id        string         `json:"id"`
+}
+
+func (q *Quota) FromConfigMap(configMap *corev1.ConfigMap) error {
+	jsonData, ok := configMap.Data["json"]
+	if !ok {
+		return fmt.Errorf("could not find json data in quota configmap")
+	}
+
+	err := json.Unmarshal([]byte(jsonData), &q)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal configmap to quota: %v", err)
+	}
+
+	return nil
+}
+
+func (q *Quota) ToConfigMap() (*corev1.ConfigMap, error) {
+	bytes, err := json.Marshal(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal quota: %v", err)
+	}
+
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "quota-" + q.Id,
+		},
+		Data: map[string]string{
+			"json": string(bytes),
+		},
+	}, nil
+}
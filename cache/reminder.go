@@ -3,19 +3,24 @@ package cache
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	saltbotv1 "github.com/highsaltlevels/saltbot/apis/saltbot/v1"
 )
 
 type Reminder struct {
-	Author  string      `json:"author"`
-	Channel string      `json:"channel"`
-	Expiry  int64       `json:"expiry"`
-	Message interface{} `json:"msg"`
-	Id      string      `json:"id"`
+	Author  string `json:"author"`
+	Channel string `json:"channel"`
+	Expiry  int64  `json:"expiry"`
+	Message string `json:"msg"`
+	Id      string `json:"id"`
 }
 
+// Reminders used to be stored as JSON in configmaps. Only needed for migrating
+// them.
 func (r *Reminder) FromConfigMap(configMap *corev1.ConfigMap) error {
 	jsonData, ok := configMap.Data["json"]
 	if !ok {
@@ -30,21 +35,31 @@ func (r *Reminder) FromConfigMap(configMap *corev1.ConfigMap) error {
 	return nil
 }
 
-func (r *Reminder) ToConfigMap() (*corev1.ConfigMap, error) {
-	bytes, err := json.Marshal(r)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal reminder: %v", err)
-	}
-
-	return &corev1.ConfigMap{
+// The reminder as a Reminder resource, named after its id.
+func (r *Reminder) ToResource() *saltbotv1.Reminder {
+	return &saltbotv1.Reminder{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: "reminder-" + r.Id,
+			Name: r.Id,
 			Labels: map[string]string{
 				"author": r.Author,
 			},
 		},
-		Data: map[string]string{
-			"json": string(bytes),
+		Spec: saltbotv1.ReminderSpec{
+			Id:      r.Id,
+			Author:  r.Author,
+			Channel: r.Channel,
+			Message: r.Message,
+			Expiry:  metav1.NewTime(time.Unix(r.Expiry, 0)),
 		},
-	}, nil
+	}
+}
+
+func (r *Reminder) FromResource(reminder *saltbotv1.Reminder) {
+	*r = Reminder{
+		Author:  reminder.Spec.Author,
+		Channel: reminder.Spec.Channel,
+		Expiry:  reminder.Spec.Expiry.Unix(),
+		Message: reminder.Spec.Message,
+		Id:      reminder.Spec.Id,
+	}
 }
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	k8scache "k8s.io/client-go/tools/cache"
+
+	saltbotv1 "github.com/highsaltlevels/saltbot/apis/saltbot/v1"
+)
+
+// Informers for the poll and reminder resources, feeding the in-mem cache.
+func newResourceInformers() (polls, reminders k8scache.SharedIndexInformer, err error) {
+	polls = k8scache.NewSharedIndexInformer(
+		&k8scache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				return Client.SaltbotV1().Polls(namespace).List(context.Background(), opts)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				return Client.SaltbotV1().Polls(namespace).Watch(context.Background(), opts)
+			},
+		},
+		&saltbotv1.Poll{}, time.Hour*24, k8scache.Indexers{},
+	)
+	_, err = polls.AddEventHandler(
+		k8scache.ResourceEventHandlerFuncs{
+			AddFunc:    Cache.addPollResource,
+			UpdateFunc: Cache.updatePollResource,
+			DeleteFunc: Cache.deletePollResource,
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reminders = k8scache.NewSharedIndexInformer(
+		&k8scache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				return Client.SaltbotV1().Reminders(namespace).List(context.Background(), opts)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				return Client.SaltbotV1().Reminders(namespace).Watch(context.Background(), opts)
+			},
+		},
+		&saltbotv1.Reminder{}, time.Hour*24, k8scache.Indexers{},
+	)
+	_, err = reminders.AddEventHandler(
+		k8scache.ResourceEventHandlerFuncs{
+			AddFunc:    Cache.addReminderResource,
+			UpdateFunc: Cache.updateReminderResource,
+			DeleteFunc: Cache.deleteReminderResource,
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return polls, reminders, nil
+}
+
+// A deleted object, or the last state the informer saw if it missed the delete.
+func deleted(obj interface{}) interface{} {
+	if tombstone, ok := obj.(k8scache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
+	}
+	return obj
+}
+
+// Add handler for the poll informer
+func (c *ConfigMapCache) addPollResource(obj interface{}) {
+	c.storePoll(obj.(*saltbotv1.Poll), "adding")
+}
+
+// Update handler for the poll informer
+func (c *ConfigMapCache) updatePollResource(oldObj interface{}, newObj interface{}) {
+	c.storePoll(newObj.(*saltbotv1.Poll), "updating")
+}
+
+func (c *ConfigMapCache) storePoll(poll *saltbotv1.Poll, verb string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	p := Poll{}
+	p.FromResource(poll)
+	slog.Debug(verb+" poll", "id", p.Id)
+	c.polls[p.Id] = p
+}
+
+// Delete handler for the poll informer
+func (c *ConfigMapCache) deletePollResource(obj interface{}) {
+	poll, ok := deleted(obj).(*saltbotv1.Poll)
+	if !ok {
+		slog.Warn("ignoring deletion of something that isn't a poll")
+		return
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	delete(c.polls, poll.Spec.Id)
+}
+
+// Add handler for the reminder informer
+func (c *ConfigMapCache) addReminderResource(obj interface{}) {
+	c.storeReminder(obj.(*saltbotv1.Reminder), "adding")
+}
+
+// Update handler for the reminder informer
+func (c *ConfigMapCache) updateReminderResource(oldObj interface{}, newObj interface{}) {
+	c.storeReminder(newObj.(*saltbotv1.Reminder), "updating")
+}
+
+func (c *ConfigMapCache) storeReminder(reminder *saltbotv1.Reminder, verb string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	r := Reminder{}
+	r.FromResource(reminder)
+	slog.Debug(verb+" reminder", "id", r.Id)
+	c.reminders[r.Id] = r
+}
+
+// Delete handler for the reminder informer
+func (c *ConfigMapCache) deleteReminderResource(obj interface{}) {
+	reminder, ok := deleted(obj).(*saltbotv1.Reminder)
+	if !ok {
+		slog.Warn("ignoring deletion of something that isn't a reminder")
+		return
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	delete(c.reminders, reminder.Spec.Id)
+}
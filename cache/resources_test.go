@@ -0,0 +1,282 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8scache "k8s.io/client-go/tools/cache"
+
+	saltbotv1 "github.com/highsaltlevels/saltbot/apis/saltbot/v1"
+)
+
+var expiry = time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC)
+
+func pollResource(votes map[string][]string) *saltbotv1.Poll {
+	return &saltbotv1.Poll{
+		ObjectMeta: metav1.ObjectMeta{Name: "1234"},
+		Spec: saltbotv1.PollSpec{
+			Id:      "1234",
+			Author:  "author",
+			Channel: "channel",
+			Prompt:  "prompt",
+			Choices: []string{"choice1", "choice2"},
+			Expiry:  metav1.NewTime(expiry),
+			Votes:   votes,
+		},
+	}
+}
+
+func reminderResource(message string) *saltbotv1.Reminder {
+	return &saltbotv1.Reminder{
+		ObjectMeta: metav1.ObjectMeta{Name: "1234"},
+		Spec: saltbotv1.ReminderSpec{
+			Id:      "1234",
+			Author:  "author",
+			Channel: "channel",
+			Message: message,
+			Expiry:  metav1.NewTime(expiry),
+		},
+	}
+}
+
+func TestPollResource(t *testing.T) {
+	Cache = NewInMemConfigMapCache(map[string]Poll{}, map[string]Reminder{})
+
+	Cache.addPollResource(pollResource(map[string][]string{"0": {}, "1": {"chooser"}}))
+	Cache.updatePollResource(nil, pollResource(map[string][]string{"0": {"chooser"}, "1": {}}))
+	if len(Cache.polls) != 1 {
+		t.Fatalf("expected 1 poll, but got %d", len(Cache.polls))
+	}
+	poll := Cache.polls["1234"]
+	validatePoll(t, &Poll{
+		Author:  "author",
+		Channel: "channel",
+		Prompt:  "prompt",
+		Choices: []string{"choice1", "choice2"},
+		Expiry:  expiry.Unix(),
+		Id:      "1234",
+		Votes: map[string][]string{
+			"0": {"chooser"},
+			"1": {},
+		},
+	}, &poll)
+
+	Cache.deletePollResource(k8scache.DeletedFinalStateUnknown{Key: "saltbot/1234", Obj: pollResource(nil)})
+	if len(Cache.polls) != 0 {
+		t.Errorf("expected the poll to be deleted, but got %+v", Cache.polls)
+	}
+}
+
+func TestReminderResource(t *testing.T) {
+	Cache = NewInMemConfigMapCache(map[string]Poll{}, map[string]Reminder{})
+
+	Cache.addReminderResource(reminderResource("something else"))
+	Cache.updateReminderResource(nil, reminderResource("bloop"))
+	if len(Cache.reminders) != 1 {
+		t.Fatalf("expected 1 reminder, but got %d", len(Cache.reminders))
+	}
+	reminder := Cache.reminders["1234"]
+	validateReminder(t, &Reminder{
+		Author:  "author",
+		Channel: "channel",
+		Expiry:  expiry.Unix(),
+		Message: "bloop",
+		Id:      "1234",
+	}, &reminder)
+
+	Cache.deleteReminderResource(reminderResource(""))
+	if len(Cache.reminders) != 0 {
+		t.Errorf("expected the reminder to be deleted, but got %+v", Cache.reminders)
+	}
+}
+
+func TestToResource(t *testing.T) {
+	poll := Poll{
+		Author:  "author",
+		Channel: "channel",
+		Guild:   "guild",
+		Prompt:  "prompt",
+		Choices: []string{"choice1", "choice2"},
+		Expiry:  expiry.Unix(),
+		Id:      "1234",
+		Votes: map[string][]string{
+			"0": {"chooser"},
+			"1": {},
+		},
+	}
+	resource := poll.ToResource()
+	if resource.Name != "1234" || resource.Labels["author"] != "author" {
+		t.Errorf("expected the poll to be named after its id with an author label, but got %+v", resource.ObjectMeta)
+	}
+
+	var roundTrip Poll
+	roundTrip.FromResource(resource.DeepCopy())
+	validatePoll(t, &poll, &roundTrip)
+	if roundTrip.Guild != "guild" {
+		t.Errorf("expected guild to be kept, but got %q", roundTrip.Guild)
+	}
+
+	reminder := Reminder{
+		Author:  "author",
+		Channel: "channel",
+		Expiry:  expiry.Unix(),
+		Message: "bloop",
+		Id:      "1234",
+	}
+	var reminderTrip Reminder
+	reminderTrip.FromResource(reminder.ToResource().DeepCopy())
+	validateReminder(t, &reminder, &reminderTrip)
+}
+
+func TestUpdatePollInMem(t *testing.T) {
+	Client = InMemClient{}
+	Cache = NewInMemConfigMapCache(map[string]Poll{}, map[string]Reminder{})
+
+	poll := &Poll{Id: "1234", Author: "author", Choices: []string{"a", "b"}, Votes: map[string][]string{}}
+	if err := Cache.AddPoll(context.Background(), poll); err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+
+	vote := func(p *Poll) { p.Votes["0"] = append(p.Votes["0"], "chooser") }
+	if err := Cache.UpdatePoll(context.Background(), "1234", vote); err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	if votes := Cache.polls["1234"].Votes["0"]; len(votes) != 1 || votes[0] != "chooser" {
+		t.Errorf("expected the vote to be stored, but got %v", Cache.polls["1234"].Votes)
+	}
+
+	Cache.DeletePoll(context.Background(), "1234")
+	if err := Cache.UpdatePoll(context.Background(), "1234", vote); err == nil {
+		t.Errorf("expected an error updating a deleted poll")
+	}
+}
+
+// Polls where someone else votes just before the first update, so it conflicts.
+type conflictingPolls struct {
+	inMemPolls
+	conflicted bool
+}
+
+func (c *conflictingPolls) Update(ctx context.Context, poll *saltbotv1.Poll, opts metav1.UpdateOptions) (*saltbotv1.Poll, error) {
+	if !c.conflicted {
+		c.conflicted = true
+		other := Cache.GetPoll(poll.Name, "")
+		other.Votes["1"] = append(other.Votes["1"], "other")
+		Cache.storePoll(other.ToResource(), "storing")
+		return nil, apierrors.NewConflict(saltbotv1.Resource("polls"), poll.Name, errors.New("the poll changed"))
+	}
+	return c.inMemPolls.Update(ctx, poll, opts)
+}
+
+type conflictingClient struct {
+	InMemClient
+	polls *conflictingPolls
+}
+
+func (c conflictingClient) SaltbotV1() saltbotv1.SaltbotV1Interface {
+	return conflictingSaltbotV1{polls: c.polls}
+}
+
+type conflictingSaltbotV1 struct {
+	inMemSaltbotV1
+	polls *conflictingPolls
+}
+
+func (c conflictingSaltbotV1) Polls(namespace string) saltbotv1.PollInterface {
+	return c.polls
+}
+
+func TestUpdatePollKeepsConcurrentVotes(t *testing.T) {
+	Client = conflictingClient{polls: &conflictingPolls{}}
+	Cache = NewInMemConfigMapCache(map[string]Poll{}, map[string]Reminder{})
+	poll := &Poll{Id: "1234", Author: "author", Choices: []string{"a", "b"}, Votes: map[string][]string{}}
+	if err := Cache.AddPoll(context.Background(), poll); err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+
+	err := Cache.UpdatePoll(context.Background(), "1234", func(p *Poll) {
+		p.Votes["0"] = append(p.Votes["0"], "chooser")
+	})
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+
+	votes := Cache.polls["1234"].Votes
+	if len(votes["0"]) != 1 || votes["0"][0] != "chooser" || len(votes["1"]) != 1 || votes["1"][0] != "other" {
+		t.Errorf("expected both votes to be kept, but got %v", votes)
+	}
+}
+
+// A kubernetes client with real configmap calls, and resources kept in memory.
+type fakeClient struct {
+	*fake.Clientset
+}
+
+func (c fakeClient) SaltbotV1() saltbotv1.SaltbotV1Interface {
+	return inMemSaltbotV1{}
+}
+
+func TestMigrate(t *testing.T) {
+	configMap := func(name, json string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string]string{"json": json},
+		}
+	}
+	kube := fake.NewSimpleClientset(
+		configMap("poll-1234", `{"author":"1234","channel":"1234","prompt":"prompt","choices":["choice1","choice2"],"expiry":1234,"id":"1234","votes":{"0":["chooser"],"1":[]}}`),
+		configMap("reminder-5678", `{"author":"1234","channel":"1234","expiry":1234,"msg":"bloop","id":"5678"}`),
+		configMap("poll-bad", "i am not json :)"),
+		configMap("rating-channel", `{"id":"channel","rating":"pg"}`),
+	)
+	Client = fakeClient{kube}
+	Cache = NewInMemConfigMapCache(map[string]Poll{}, map[string]Reminder{})
+
+	if err := Migrate(context.Background()); err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+
+	poll := Cache.polls["1234"]
+	validatePoll(t, &Poll{
+		Author:  "1234",
+		Channel: "1234",
+		Prompt:  "prompt",
+		Choices: []string{"choice1", "choice2"},
+		Expiry:  1234,
+		Id:      "1234",
+		Votes: map[string][]string{
+			"0": {"chooser"},
+			"1": {},
+		},
+	}, &poll)
+
+	reminder := Cache.reminders["5678"]
+	validateReminder(t, &Reminder{
+		Author:  "1234",
+		Channel: "1234",
+		Expiry:  1234,
+		Message: "bloop",
+		Id:      "5678",
+	}, &reminder)
+
+	// Only the migrated configmaps are deleted
+	left, err := kube.CoreV1().ConfigMaps(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, but got: %v", err)
+	}
+	var names []string
+	for _, c := range left.Items {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "poll-bad" || names[1] != "rating-channel" {
+		t.Errorf("expected poll-bad and rating-channel to be left, but got %v", names)
+	}
+}
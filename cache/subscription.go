@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// A discord channel subscribed to a youtube channel's uploads.
+type Subscription struct {
+	Author         string `json:"author"`
+	Guild          string `json:"guild"`
+	Channel        string `json:"channel"`
+	YoutubeChannel string `json:"youtubeChannel"`
+	Title          string `json:"title"`
+	LastVideoId    string `json:"lastVideoId"`
+	Id             string `json:"id"`
+}
+
+func (s *Subscription) FromConfigMap(configMap *corev1.ConfigMap) error {
+	jsonData, ok := configMap.Data["json"]
+	if !ok {
+		return fmt.Errorf("could not find json data in subscription configmap")
+	}
+
+	err := json.Unmarshal([]byte(jsonData), &s)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal configmap to subscription: %v", err)
+	}
+
+	return nil
+}
+
+func (s *Subscription) ToConfigMap() (*corev1.ConfigMap, error) {
+	bytes, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal subscription: %v", err)
+	}
+
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "subscription-" + s.Id,
+			Labels: map[string]string{
+				"author": s.Author,
+			},
+		},
+		Data: map[string]string{
+			"json": string(bytes),
+		},
+	}, nil
+}
@@ -0,0 +1,417 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/text/language"
+
+	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/logging"
+)
+
+// The command prefix for guilds that haven't set their own
+const DefaultPrefix = "!"
+
+// Longest prefix a guild can pick
+const maxPrefixLength = 3
+
+const helpMessage string = ("```Server settings (only for bot admins):\n\n" +
+	"\"!config list\" shows every setting\n" +
+	"\"!config get <setting>\" shows one setting\n" +
+	"\"!config set <setting> <value>\" changes a setting\n" +
+	"\"!config reset <setting>\" goes back to the default\n\n" +
+	"Settings:\n" +
+	"prefix           what commands start with, like \"?\" for \"?gif dog\"\n" +
+	"enabled          if set, only these commands work, like \"gif poll vote\"\n" +
+	"disabled         commands that don't work, like \"waifu youtube\"\n" +
+	"timezone         the server's time zone for dates and times, like \"America/Chicago\"\n" +
+	"locale           how numbers are written, like \"de\" for $1.200\n" +
+	"results-channel  where poll results are posted, like \"#results\" or \"here\"\n" +
+	"admin-roles      roles that are bot admins, along with Manage Server\n" +
+	"denied-users     members who can't use saltbot\n\n" +
+	"Limit who can use a command, like \"!config set gif.roles @Gifters\":\n" +
+	"<command>.roles         only members with one of these roles\n" +
+	"<command>.channels      only in these channels, like \"#bots here\"\n" +
+	"<command>.denied-users  members who can't use it\n\n" +
+	"Bot admins can use every command anywhere.\n" +
+	"\"!config\" always works, even with a different prefix```")
+
+var channelMention = regexp.MustCompile(`^<#(\d+)>$|^(\d+)$`)
+var roleMention = regexp.MustCompile(`^<@&(\d+)>$|^(\d+)$`)
+var userMention = regexp.MustCompile(`^<@!?(\d+)>$|^(\d+)$`)
+
+// Changes are made one at a time so that two at once don't lose one. Reading
+// settings doesn't need it.
+var changeLock sync.Mutex
+
+// What "!config" uses to look up channels.
+type SessionInterface interface {
+	Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+}
+
+// A copy of the guild's settings, as the informer last saw them. DMs get the
+// defaults.
+func For(guild string) cache.GuildConfig {
+	if guild == "" {
+		return cache.GuildConfig{}
+	}
+
+	if g := cache.Cache.GetGuildConfig(guild); g != nil {
+		return *g
+	}
+	return cache.GuildConfig{Id: guild}
+}
+
+func Prefix(guild string) string {
+	if prefix := For(guild).Prefix; prefix != "" {
+		return prefix
+	}
+	return DefaultPrefix
+}
+
+// The guild's time zone, or the bot's if it hasn't set one.
+func Location(guild string) *time.Location {
+	name := For(guild).Timezone
+	if name == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		slog.Warn("failed to load guild time zone", "guild", guild, "timezone", name, "error", err)
+		return time.Local
+	}
+	return loc
+}
+
+// The guild's language, or English if it hasn't set one.
+func Language(guild string) language.Tag {
+	name := For(guild).Locale
+	if name == "" {
+		return language.English
+	}
+
+	tag, err := language.Parse(name)
+	if err != nil {
+		slog.Warn("failed to parse guild locale", "guild", guild, "locale", name, "error", err)
+		return language.English
+	}
+	return tag
+}
+
+// Where poll results go, or channel if the guild hasn't picked one.
+func ResultsChannel(guild, channel string) string {
+	if results := For(guild).ResultsChannel; results != "" {
+		return results
+	}
+	return channel
+}
+
+// Whether a command (by its full name, like "gif") can be used in the guild.
+// "config" always can, so that a guild can't lock itself out.
+func Enabled(guild, command string) bool {
+	if command == "config" {
+		return true
+	}
+
+	g := For(guild)
+	if len(g.Enabled) > 0 && !contains(g.Enabled, command) {
+		return false
+	}
+	return !contains(g.Disabled, command)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// A setting that "!config" can change.
+type setting struct {
+	get   func(g *cache.GuildConfig) string
+	set   func(g *cache.GuildConfig, value string, s SessionInterface, m *discordgo.MessageCreate, commands []string) error
+	reset func(g *cache.GuildConfig)
+}
+
+var settings = map[string]setting{
+	"prefix": {
+		get: func(g *cache.GuildConfig) string { return g.Prefix },
+		set: func(g *cache.GuildConfig, value string, s SessionInterface, m *discordgo.MessageCreate, commands []string) error {
+			if len([]rune(value)) > maxPrefixLength || strings.ContainsAny(value, " `") {
+				return fmt.Errorf("the prefix must be %d characters or less, without spaces or backticks", maxPrefixLength)
+			}
+			g.Prefix = value
+			return nil
+		},
+		reset: func(g *cache.GuildConfig) { g.Prefix = "" },
+	},
+	"enabled": {
+		get: func(g *cache.GuildConfig) string { return strings.Join(g.Enabled, " ") },
+		set: func(g *cache.GuildConfig, value string, s SessionInterface, m *discordgo.MessageCreate, commands []string) error {
+			names, err := parseCommands(value, commands)
+			g.Enabled = names
+			return err
+		},
+		reset: func(g *cache.GuildConfig) { g.Enabled = nil },
+	},
+	"disabled": {
+		get: func(g *cache.GuildConfig) string { return strings.Join(g.Disabled, " ") },
+		set: func(g *cache.GuildConfig, value string, s SessionInterface, m *discordgo.MessageCreate, commands []string) error {
+			names, err := parseCommands(value, commands)
+			g.Disabled = names
+			return err
+		},
+		reset: func(g *cache.GuildConfig) { g.Disabled = nil },
+	},
+	"timezone": {
+		get: func(g *cache.GuildConfig) string { return g.Timezone },
+		set: func(g *cache.GuildConfig, value string, s SessionInterface, m *discordgo.MessageCreate, commands []string) error {
+			if _, err := time.LoadLocation(value); err != nil {
+				return fmt.Errorf("unknown time zone \"%s\", try one like \"America/Chicago\"", value)
+			}
+			g.Timezone = value
+			return nil
+		},
+		reset: func(g *cache.GuildConfig) { g.Timezone = "" },
+	},
+	"locale": {
+		get: func(g *cache.GuildConfig) string { return g.Locale },
+		set: func(g *cache.GuildConfig, value string, s SessionInterface, m *discordgo.MessageCreate, commands []string) error {
+			tag, err := language.Parse(value)
+			if err != nil {
+				return fmt.Errorf("unknown locale \"%s\", try one like \"en\" or \"de\"", value)
+			}
+			g.Locale = tag.String()
+			return nil
+		},
+		reset: func(g *cache.GuildConfig) { g.Locale = "" },
+	},
+	"results-channel": {
+		get: func(g *cache.GuildConfig) string {
+			if g.ResultsChannel == "" {
+				return ""
+			}
+			return fmt.Sprintf("<#%s>", g.ResultsChannel)
+		},
+		set: func(g *cache.GuildConfig, value string, s SessionInterface, m *discordgo.MessageCreate, commands []string) error {
+			channels, err := parseIds(value, channelMention, "a channel", m)
+			if err != nil || len(channels) != 1 {
+				return fmt.Errorf("pick a channel like \"#results\", or \"here\" for this one")
+			}
+
+			// Results can only be posted to this server's channels
+			channel, err := s.Channel(channels[0])
+			if err != nil || channel.GuildID != m.GuildID {
+				return fmt.Errorf("pick a channel in this server, like \"#results\"")
+			}
+			g.ResultsChannel = channels[0]
+			return nil
+		},
+		reset: func(g *cache.GuildConfig) { g.ResultsChannel = "" },
+	},
+}
+
+func init() {
+	settings["admin-roles"] = setting{
+		get: func(g *cache.GuildConfig) string { return mentions(g.AdminRoles, "<@&%s>") },
+		set: func(g *cache.GuildConfig, value string, s SessionInterface, m *discordgo.MessageCreate, commands []string) error {
+			roles, err := parseIds(value, roleMention, "a role", m)
+			g.AdminRoles = roles
+			return err
+		},
+		reset: func(g *cache.GuildConfig) { g.AdminRoles = nil },
+	}
+	settings["denied-users"] = setting{
+		get: func(g *cache.GuildConfig) string { return mentions(g.DeniedUsers, "<@%s>") },
+		set: func(g *cache.GuildConfig, value string, s SessionInterface, m *discordgo.MessageCreate, commands []string) error {
+			users, err := parseIds(value, userMention, "a user", m)
+			g.DeniedUsers = users
+			return err
+		},
+		reset: func(g *cache.GuildConfig) { g.DeniedUsers = nil },
+	}
+}
+
+// Find a setting, including ones for a command's policy like "gif.roles".
+func lookupSetting(name string, commands []string) (setting, bool) {
+	if s, ok := settings[name]; ok {
+		return s, true
+	}
+
+	command, field, ok := strings.Cut(name, ".")
+	if !ok || !contains(commands, command) {
+		return setting{}, false
+	}
+	return policySetting(command, field)
+}
+
+// Split a list of command names on spaces or commas, checking that saltbot
+// knows them. "config" can't be turned off, so that it can always be undone.
+func parseCommands(value string, commands []string) ([]string, error) {
+	names := []string{}
+	for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' }) {
+		name = strings.TrimPrefix(strings.ToLower(name), DefaultPrefix)
+		if name == "config" {
+			return nil, fmt.Errorf("config can't be turned off")
+		}
+		if !contains(commands, name) {
+			return nil, fmt.Errorf("unknown command \"%s\", expected some of: %s", name, strings.Join(commands, ", "))
+		}
+		if !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// Split a list of mentions or ids on spaces or commas. "here" is the channel
+// the message was sent in.
+func parseIds(value string, pattern *regexp.Regexp, what string, m *discordgo.MessageCreate) ([]string, error) {
+	ids := []string{}
+	for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' }) {
+		id := ""
+		if field == "here" && pattern == channelMention {
+			id = m.ChannelID
+		} else if match := pattern.FindStringSubmatch(field); match != nil {
+			id = match[1] + match[2]
+		} else {
+			return nil, fmt.Errorf("\"%s\" isn't %s, mention one or use its id", field, what)
+		}
+
+		if !contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func mentions(ids []string, format string) string {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = fmt.Sprintf(format, id)
+	}
+	return strings.Join(values, " ")
+}
+
+func settingNames() []string {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func show(g *cache.GuildConfig, name string, s setting) string {
+	value := s.get(g)
+	if value == "" {
+		value = "(default)"
+		if name == "prefix" {
+			value = fmt.Sprintf("(default %s)", DefaultPrefix)
+		}
+	}
+	return fmt.Sprintf("%-16s %s\n", name, value)
+}
+
+// Handle "!config". Commands are every command name saltbot knows, for
+// checking "enabled" and "disabled".
+func Handle(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate, admin bool, commands []string) (*discordgo.MessageSend, error) {
+	args := strings.Fields(m.Content)[1:]
+	if m.GuildID == "" {
+		return &discordgo.MessageSend{
+			Content: "```Settings are only for servers```",
+		}, nil
+	}
+	if !admin {
+		return &discordgo.MessageSend{
+			Content: "```Only bot admins can change saltbot's settings```",
+		}, nil
+	}
+
+	if len(args) == 0 || args[0] == "help" {
+		return &discordgo.MessageSend{
+			Content: helpMessage,
+		}, nil
+	}
+
+	changeLock.Lock()
+	defer changeLock.Unlock()
+	g := For(m.GuildID)
+
+	if args[0] == "list" {
+		msg := "```Settings for this server:\n"
+		for _, name := range settingNames() {
+			msg += show(&g, name, settings[name])
+		}
+		for _, name := range policyNames(&g) {
+			policy, _ := lookupSetting(name, commands)
+			msg += show(&g, name, policy)
+		}
+		return &discordgo.MessageSend{
+			Content: msg + "```",
+		}, nil
+	}
+
+	if len(args) < 2 {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```Pick a setting like: \"!config %s prefix\"```", args[0]),
+		}, nil
+	}
+
+	name := strings.ToLower(args[1])
+	entry, ok := lookupSetting(name, commands)
+	if !ok {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```Unknown setting \"%s\", expected one of: %s, or <command>.%s```",
+				args[1], strings.Join(settingNames(), ", "), strings.Join(policyFields, "/")),
+		}, nil
+	}
+
+	updated := g
+	switch args[0] {
+	case "get":
+		return &discordgo.MessageSend{
+			Content: "```" + show(&g, name, entry) + "```",
+		}, nil
+	case "set":
+		if len(args) < 3 {
+			return &discordgo.MessageSend{
+				Content: fmt.Sprintf("```Give a value like: \"!config set %s <value>\"```", name),
+			}, nil
+		}
+		err := entry.set(&updated, strings.Join(args[2:], " "), s, m, commands)
+		if err != nil {
+			return &discordgo.MessageSend{
+				Content: fmt.Sprintf("```%v```", err),
+			}, nil
+		}
+	case "reset":
+		entry.reset(&updated)
+	default:
+		return &discordgo.MessageSend{
+			Content: helpMessage,
+		}, nil
+	}
+
+	err := cache.Cache.SetGuildConfig(ctx, &updated)
+	if err != nil {
+		return nil, fmt.Errorf("error saving guild config: %w", err)
+	}
+	logging.From(ctx).Info("changed guild config", "setting", name, "value", entry.get(&updated))
+
+	return &discordgo.MessageSend{
+		Content: "```Updated " + show(&updated, name, entry) + "```",
+	}, nil
+}
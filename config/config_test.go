@@ -0,0 +1,369 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/text/language"
+
+	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/testutil"
+)
+
+var commands = []string{"help", "gif", "poll", "waifu", "config"}
+
+func newMessage(content, guild string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			Content:   content,
+			ChannelID: "channel",
+			GuildID:   guild,
+			Author:    &discordgo.User{ID: "1"},
+		},
+	}
+}
+
+// Channels in the guild, and one in another server
+var session = &testutil.RecordingSession{
+	Channels: map[string]*discordgo.Channel{
+		"channel": {ID: "channel", GuildID: "guild"},
+		"1234":    {ID: "1234", GuildID: "guild"},
+		"5678":    {ID: "5678", GuildID: "other"},
+	},
+}
+
+func setup(client cache.KubeClient) {
+	cache.Cache = cache.NewInMemConfigMapCache(map[string]cache.Poll{}, map[string]cache.Reminder{})
+	cache.Client = client
+}
+
+func TestHandle(t *testing.T) {
+	tests := []struct {
+		name            string
+		commandStr      string
+		guild           string
+		notAdmin        bool
+		client          cache.KubeClient
+		expectedMessage string
+		expectedError   string
+		expected        cache.GuildConfig
+	}{
+		{
+			name:            "test help",
+			commandStr:      "!config",
+			expectedMessage: helpMessage,
+		},
+		{
+			name:            "test in a dm",
+			commandStr:      "!config list",
+			guild:           "-",
+			expectedMessage: "only for servers",
+		},
+		{
+			name:            "test not an admin",
+			commandStr:      "!config set prefix ?",
+			notAdmin:        true,
+			expectedMessage: "Only bot admins",
+		},
+		{
+			name:            "test list defaults",
+			commandStr:      "!config list",
+			expectedMessage: "prefix           (default !)\nresults-channel  (default)",
+		},
+		{
+			name:            "test unknown setting",
+			commandStr:      "!config get colour",
+			expectedMessage: "Unknown setting \"colour\"",
+		},
+		{
+			name:            "test set prefix",
+			commandStr:      "!config set prefix ?",
+			expectedMessage: "Updated prefix           ?",
+			expected:        cache.GuildConfig{Prefix: "?"},
+		},
+		{
+			name:            "test set prefix too long",
+			commandStr:      "!config set prefix salt!",
+			expectedMessage: "3 characters or less",
+		},
+		{
+			name:            "test set disabled",
+			commandStr:      "!config set disabled !waifu, gif",
+			expectedMessage: "disabled         waifu gif",
+			expected:        cache.GuildConfig{Disabled: []string{"waifu", "gif"}},
+		},
+		{
+			name:            "test disable unknown command",
+			commandStr:      "!config set disabled salt",
+			expectedMessage: "unknown command \"salt\"",
+		},
+		{
+			name:            "test disable config",
+			commandStr:      "!config set enabled gif config",
+			expectedMessage: "config can't be turned off",
+		},
+		{
+			name:            "test set timezone",
+			commandStr:      "!config set timezone America/Chicago",
+			expectedMessage: "America/Chicago",
+			expected:        cache.GuildConfig{Timezone: "America/Chicago"},
+		},
+		{
+			name:            "test set unknown timezone",
+			commandStr:      "!config set timezone Salt/Flats",
+			expectedMessage: "unknown time zone",
+		},
+		{
+			name:            "test set locale",
+			commandStr:      "!config set locale de_de",
+			expectedMessage: "de-DE",
+			expected:        cache.GuildConfig{Locale: "de-DE"},
+		},
+		{
+			name:            "test set unknown locale",
+			commandStr:      "!config set locale 12",
+			expectedMessage: "unknown locale",
+		},
+		{
+			name:            "test set results channel here",
+			commandStr:      "!config set results-channel here",
+			expectedMessage: "<#channel>",
+			expected:        cache.GuildConfig{ResultsChannel: "channel"},
+		},
+		{
+			name:            "test set results channel mention",
+			commandStr:      "!config set results-channel <#1234>",
+			expectedMessage: "<#1234>",
+			expected:        cache.GuildConfig{ResultsChannel: "1234"},
+		},
+		{
+			name:            "test set results channel in another server",
+			commandStr:      "!config set results-channel 5678",
+			expectedMessage: "pick a channel in this server",
+		},
+		{
+			name:            "test set unknown results channel",
+			commandStr:      "!config set results-channel <#9999>",
+			expectedMessage: "pick a channel in this server",
+		},
+		{
+			name:            "test set admin roles",
+			commandStr:      "!config set admin-roles <@&42> 43",
+			expectedMessage: "admin-roles      <@&42> <@&43>",
+			expected:        cache.GuildConfig{AdminRoles: []string{"42", "43"}},
+		},
+		{
+			name:            "test set denied users",
+			commandStr:      "!config set denied-users <@!7>",
+			expectedMessage: "denied-users     <@7>",
+			expected:        cache.GuildConfig{DeniedUsers: []string{"7"}},
+		},
+		{
+			name:            "test set invalid role",
+			commandStr:      "!config set admin-roles @Mods",
+			expectedMessage: "\"@Mods\" isn't a role",
+		},
+		{
+			name:            "test set command roles",
+			commandStr:      "!config set gif.roles <@&42>",
+			expectedMessage: "gif.roles        <@&42>",
+			expected:        cache.GuildConfig{Policies: map[string]cache.Policy{"gif": {Roles: []string{"42"}}}},
+		},
+		{
+			name:            "test set command channels",
+			commandStr:      "!config set poll.channels <#9> here",
+			expectedMessage: "poll.channels    <#9> <#channel>",
+			expected:        cache.GuildConfig{Policies: map[string]cache.Policy{"poll": {Channels: []string{"9", "channel"}}}},
+		},
+		{
+			name:            "test limit config",
+			commandStr:      "!config set config.roles <@&42>",
+			expectedMessage: "always for bot admins",
+		},
+		{
+			name:            "test policy for unknown command",
+			commandStr:      "!config set salt.roles <@&42>",
+			expectedMessage: "Unknown setting",
+		},
+		{
+			name:          "test saving returns error",
+			commandStr:    "!config set prefix ?",
+			client:        &testutil.MockErrorK8sClient{},
+			expectedError: "error saving guild config",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := tt.client
+			if client == nil {
+				client = &testutil.MockK8sClient{}
+			}
+			setup(client)
+			guild := tt.guild
+			if guild == "" {
+				guild = "guild"
+			} else if guild == "-" {
+				guild = ""
+			}
+
+			msg, err := Handle(context.Background(), session, newMessage(tt.commandStr, guild), !tt.notAdmin, commands)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("expected error '%s', but got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(msg.Content, tt.expectedMessage) {
+				t.Errorf("expected '%s' in '%s'", tt.expectedMessage, msg.Content)
+			}
+
+			tt.expected.Id = "guild"
+			got := For("guild")
+			if got.Prefix != tt.expected.Prefix || got.Timezone != tt.expected.Timezone || got.Locale != tt.expected.Locale ||
+				got.ResultsChannel != tt.expected.ResultsChannel ||
+				strings.Join(got.Disabled, " ") != strings.Join(tt.expected.Disabled, " ") ||
+				strings.Join(got.AdminRoles, " ") != strings.Join(tt.expected.AdminRoles, " ") ||
+				strings.Join(got.DeniedUsers, " ") != strings.Join(tt.expected.DeniedUsers, " ") ||
+				fmt.Sprint(got.Policies) != fmt.Sprint(tt.expected.Policies) {
+				t.Errorf("expected %+v, but got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	setup(&testutil.MockK8sClient{})
+	Handle(context.Background(), session, newMessage("!config set prefix ?", "guild"), true, commands)
+	if Prefix("guild") != "?" {
+		t.Fatalf("expected prefix ?, but got %s", Prefix("guild"))
+	}
+
+	Handle(context.Background(), session, newMessage("!config reset prefix", "guild"), true, commands)
+	if Prefix("guild") != DefaultPrefix {
+		t.Errorf("expected the default prefix, but got %s", Prefix("guild"))
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	setup(&testutil.MockK8sClient{})
+	stored := &cache.GuildConfig{
+		Id:       "stored",
+		Enabled:  []string{"gif", "poll"},
+		Disabled: []string{"poll"},
+		Timezone: "Asia/Tokyo",
+		Locale:   "ja",
+	}
+	configMap, _ := stored.ToConfigMap()
+	cache.Cache.Store(configMap)
+
+	if Location("guild") != time.Local || Location("stored").String() != "Asia/Tokyo" {
+		t.Errorf("unexpected locations %v and %v", Location("guild"), Location("stored"))
+	}
+	if Language("guild") != language.English || Language("stored") != language.Japanese {
+		t.Errorf("unexpected languages %v and %v", Language("guild"), Language("stored"))
+	}
+	if ResultsChannel("guild", "channel") != "channel" {
+		t.Errorf("expected results in the poll's channel, but got %s", ResultsChannel("guild", "channel"))
+	}
+	if !Enabled("guild", "waifu") || !Enabled("stored", "gif") {
+		t.Errorf("expected commands to be enabled")
+	}
+	if Enabled("stored", "waifu") || Enabled("stored", "poll") {
+		t.Errorf("expected commands outside enabled or inside disabled to be off")
+	}
+	if !Enabled("stored", "config") {
+		t.Errorf("expected config to always be enabled")
+	}
+}
+
+func TestPicksUpOutsideChanges(t *testing.T) {
+	setup(&testutil.MockK8sClient{})
+	Handle(context.Background(), session, newMessage("!config set prefix ?", "guild"), true, commands)
+	if Prefix("guild") != "?" {
+		t.Fatalf("expected our own change right away, but got %s", Prefix("guild"))
+	}
+
+	// Like a kubectl edit or another replica, seen through the informer
+	edited := &cache.GuildConfig{Id: "guild", Prefix: "$"}
+	configMap, _ := edited.ToConfigMap()
+	cache.Cache.Store(configMap)
+	if Prefix("guild") != "$" {
+		t.Errorf("expected the informer's change, but got %s", Prefix("guild"))
+	}
+}
+
+func TestResetPolicy(t *testing.T) {
+	setup(&testutil.MockK8sClient{})
+	Handle(context.Background(), session, newMessage("!config set gif.roles <@&42>", "guild"), true, commands)
+	Handle(context.Background(), session, newMessage("!config set poll.roles <@&42>", "guild"), true, commands)
+	msg, _ := Handle(context.Background(), session, newMessage("!config list", "guild"), true, commands)
+	if !strings.Contains(msg.Content, "gif.roles        <@&42>\npoll.roles") {
+		t.Errorf("expected policies to be listed, but got %s", msg.Content)
+	}
+
+	Handle(context.Background(), session, newMessage("!config reset gif.roles", "guild"), true, commands)
+	if _, ok := For("guild").Policies["gif"]; ok || len(For("guild").Policies) != 1 {
+		t.Errorf("expected only the gif policy to be removed, but got %+v", For("guild").Policies)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	setup(&testutil.MockK8sClient{})
+	stored := &cache.GuildConfig{
+		Id:          "guild",
+		Prefix:      "?",
+		AdminRoles:  []string{"mods"},
+		DeniedUsers: []string{"troll"},
+		Policies: map[string]cache.Policy{
+			"gif":  {Roles: []string{"gifters"}, DeniedUsers: []string{"spammer"}},
+			"poll": {Channels: []string{"polls"}},
+		},
+	}
+	configMap, _ := stored.ToConfigMap()
+	cache.Cache.Store(configMap)
+
+	tests := []struct {
+		name     string
+		command  string
+		author   string
+		channel  string
+		roles    []string
+		admin    bool
+		expected string
+	}{
+		{name: "test no policy", command: "waifu", author: "1", channel: "channel"},
+		{name: "test denied user", command: "waifu", author: "troll", channel: "channel", expected: "You can't use saltbot"},
+		{name: "test denied user for command", command: "gif", author: "spammer", roles: []string{"gifters"}, expected: "You can't use ?gif"},
+		{name: "test missing role", command: "gif", author: "1", roles: []string{"other"}, expected: "You don't have a role that can use ?gif"},
+		{name: "test role", command: "gif", author: "1", roles: []string{"other", "gifters"}},
+		{name: "test wrong channel", command: "poll", author: "1", channel: "channel", expected: "?poll can't be used in this channel"},
+		{name: "test channel", command: "poll", author: "1", channel: "polls"},
+		{name: "test admins skip policies", command: "poll", author: "troll", channel: "channel", admin: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMessage("", "guild")
+			m.Author.ID = tt.author
+			m.ChannelID = tt.channel
+			m.Member = &discordgo.Member{Roles: tt.roles}
+
+			denied := Check(m, tt.command, tt.admin)
+			if (tt.expected == "") != (denied == "") || !strings.Contains(denied, tt.expected) {
+				t.Errorf("expected '%s', but got '%s'", tt.expected, denied)
+			}
+		})
+	}
+
+	if !AdminRole("guild", []string{"mods"}) || AdminRole("guild", []string{"gifters"}) {
+		t.Errorf("expected only mods to be an admin role")
+	}
+}
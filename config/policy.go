@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/cache"
+)
+
+// What can be set on a command's policy, like "gif.roles"
+var policyFields = []string{"roles", "channels", "denied-users"}
+
+func policySetting(command, field string) (setting, bool) {
+	var get func(p cache.Policy) string
+	var set func(p *cache.Policy, value string, m *discordgo.MessageCreate) error
+	switch field {
+	case "roles":
+		get = func(p cache.Policy) string { return mentions(p.Roles, "<@&%s>") }
+		set = func(p *cache.Policy, value string, m *discordgo.MessageCreate) (err error) {
+			p.Roles, err = parseIds(value, roleMention, "a role", m)
+			return err
+		}
+	case "channels":
+		get = func(p cache.Policy) string { return mentions(p.Channels, "<#%s>") }
+		set = func(p *cache.Policy, value string, m *discordgo.MessageCreate) (err error) {
+			p.Channels, err = parseIds(value, channelMention, "a channel", m)
+			return err
+		}
+	case "denied-users":
+		get = func(p cache.Policy) string { return mentions(p.DeniedUsers, "<@%s>") }
+		set = func(p *cache.Policy, value string, m *discordgo.MessageCreate) (err error) {
+			p.DeniedUsers, err = parseIds(value, userMention, "a user", m)
+			return err
+		}
+	default:
+		return setting{}, false
+	}
+
+	return setting{
+		get: func(g *cache.GuildConfig) string { return get(g.Policies[command]) },
+		set: func(g *cache.GuildConfig, value string, s SessionInterface, m *discordgo.MessageCreate, commands []string) error {
+			if command == "config" && field != "denied-users" {
+				return fmt.Errorf("config is always for bot admins, anywhere")
+			}
+
+			p := g.Policies[command]
+			if err := set(&p, value, m); err != nil {
+				return err
+			}
+			setPolicy(g, command, p)
+			return nil
+		},
+		reset: func(g *cache.GuildConfig) {
+			p := g.Policies[command]
+			set(&p, "", nil)
+			setPolicy(g, command, p)
+		},
+	}, true
+}
+
+// The guild config is copied before it's changed, so the policies are too.
+func setPolicy(g *cache.GuildConfig, command string, p cache.Policy) {
+	policies := map[string]cache.Policy{}
+	for name, policy := range g.Policies {
+		policies[name] = policy
+	}
+
+	if len(p.Roles) == 0 && len(p.Channels) == 0 && len(p.DeniedUsers) == 0 {
+		delete(policies, command)
+	} else {
+		policies[command] = p
+	}
+	g.Policies = policies
+}
+
+// The policy settings that have a value, for listing.
+func policyNames(g *cache.GuildConfig) []string {
+	names := []string{}
+	for command, p := range g.Policies {
+		if len(p.Roles) > 0 {
+			names = append(names, command+".roles")
+		}
+		if len(p.Channels) > 0 {
+			names = append(names, command+".channels")
+		}
+		if len(p.DeniedUsers) > 0 {
+			names = append(names, command+".denied-users")
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Whether any of the roles make their member a bot admin in the guild.
+func AdminRole(guild string, roles []string) bool {
+	for _, role := range For(guild).AdminRoles {
+		if contains(roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// Why the author of the message can't use a command (by its full name, like
+// "gif"), or "" if they can. Bot admins can use every command anywhere, and
+// there are no policies in DMs.
+func Check(m *discordgo.MessageCreate, command string, admin bool) string {
+	if m.GuildID == "" || admin {
+		return ""
+	}
+
+	g := For(m.GuildID)
+	name := Prefix(m.GuildID) + command
+	if contains(g.DeniedUsers, m.Author.ID) {
+		return "```You can't use saltbot in this server```"
+	}
+
+	p := g.Policies[command]
+	if contains(p.DeniedUsers, m.Author.ID) {
+		return fmt.Sprintf("```You can't use %s in this server```", name)
+	}
+	if len(p.Channels) > 0 && !contains(p.Channels, m.ChannelID) {
+		return fmt.Sprintf("```%s can't be used in this channel```", name)
+	}
+	if len(p.Roles) > 0 && !hasRole(m, p.Roles) {
+		return fmt.Sprintf("```You don't have a role that can use %s```", name)
+	}
+	return ""
+}
+
+func hasRole(m *discordgo.MessageCreate, roles []string) bool {
+	if m.Member == nil {
+		return false
+	}
+	for _, role := range m.Member.Roles {
+		if contains(roles, role) {
+			return true
+		}
+	}
+	return false
+}
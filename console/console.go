@@ -0,0 +1,166 @@
+package console
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/handler"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+const helpMessage string = ("Type saltbot commands like \"!poll\" or \"!gif dog\" as if you were in discord.\n" +
+	"Console commands:\n" +
+	"  /user <name>     talk as someone else\n" +
+	"  /channel <name>  move to another channel\n" +
+	"  /admin on|off    make the current user a server admin or not\n" +
+	"  /nsfw on|off     mark the current channel NSFW or not\n" +
+	"  /press <number>  press a button on the last message with buttons\n" +
+	"  /wait <duration> skip ahead in time, like \"/wait 2h\" to close a poll early\n" +
+	"  /help            show this message\n" +
+	"  /quit            leave the console\n")
+
+// A fake discord user talking in a fake channel.
+type Console struct {
+	session *Session
+	clock   *util.FakeClock
+	user    string
+	channel string
+	lastId  int
+}
+
+// The clock keeps up with real time while the console runs, and "/wait"
+// moves it further ahead.
+func New(session *Session, clock *util.FakeClock) *Console {
+	return &Console{
+		session: session,
+		clock:   clock,
+		user:    "you",
+		channel: "console",
+	}
+}
+
+func (c *Console) nextId() string {
+	c.lastId++
+	return strconv.Itoa(c.lastId)
+}
+
+// Read lines from in until it closes or the user quits. Lines starting with
+// "/" control the console and everything else is sent to saltbot.
+func (c *Console) Run(in io.Reader) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	go func() {
+		for range ticker.C {
+			c.clock.Advance(time.Second)
+		}
+	}()
+
+	c.session.printf("%s\n", helpMessage)
+	c.prompt()
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "/quit" {
+			return
+		}
+
+		if strings.HasPrefix(line, "/") {
+			c.command(strings.Fields(line))
+		} else if line != "" {
+			handler.HandleMessage(c.session, c.message(line))
+		}
+		c.prompt()
+	}
+}
+
+func (c *Console) prompt() {
+	c.session.printf("[#%s] %s> ", c.channel, c.user)
+}
+
+// A message from the current user in the current channel, the way discord
+// would deliver it.
+func (c *Console) message(content string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			ID:        c.nextId(),
+			Content:   content,
+			ChannelID: c.channel,
+			GuildID:   "console",
+			Author: &discordgo.User{
+				ID:       c.user,
+				Username: c.user,
+			},
+		},
+	}
+}
+
+func (c *Console) command(args []string) {
+	arg := ""
+	if len(args) > 1 {
+		arg = args[1]
+	}
+
+	switch args[0] {
+	case "/user":
+		if arg == "" {
+			c.session.printf("usage: /user <name>\n")
+			return
+		}
+		c.user = arg
+	case "/channel":
+		if arg == "" {
+			c.session.printf("usage: /channel <name>\n")
+			return
+		}
+		c.channel = arg
+	case "/admin":
+		c.session.lock.Lock()
+		c.session.admins[c.user] = arg != "off"
+		c.session.lock.Unlock()
+	case "/nsfw":
+		c.session.lock.Lock()
+		c.session.nsfw[c.channel] = arg != "off"
+		c.session.lock.Unlock()
+	case "/press":
+		n, _ := strconv.Atoi(arg)
+		customId, ok := c.session.button(n)
+		if !ok {
+			c.session.printf("there's no button %s\n", arg)
+			return
+		}
+		handler.HandleInteraction(c.session, c.press(customId))
+	case "/wait":
+		d, err := time.ParseDuration(arg)
+		if err != nil || d <= 0 {
+			c.session.printf("usage: /wait <duration>, like \"/wait 90m\"\n")
+			return
+		}
+		c.clock.Advance(d)
+		c.session.printf("it's now %s\n", c.clock.Now().Format(time.RFC1123))
+	case "/help":
+		c.session.printf("%s", helpMessage)
+	default:
+		c.session.printf("unknown console command %s, type /help for the list\n", args[0])
+	}
+}
+
+func (c *Console) press(customId string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        c.nextId(),
+			Type:      discordgo.InteractionMessageComponent,
+			ChannelID: c.channel,
+			GuildID:   "console",
+			Data:      discordgo.MessageComponentInteractionData{CustomID: customId},
+			Member: &discordgo.Member{
+				User: &discordgo.User{ID: c.user, Username: c.user},
+			},
+		},
+	}
+}
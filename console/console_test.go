@@ -0,0 +1,97 @@
+package console
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/trivia"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+func run(t *testing.T, input string) string {
+	cache.Cache = cache.NewInMemConfigMapCache(map[string]cache.Poll{}, map[string]cache.Reminder{})
+	cache.Client = cache.InMemClient{}
+	trivia.AnswerTime = time.Hour
+
+	out := &bytes.Buffer{}
+	New(NewSession(out), util.NewFakeClock(time.Unix(0, 0))).Run(strings.NewReader(input))
+	return out.String()
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		contains []string
+	}{
+		{
+			name:     "Test help",
+			input:    "!help\n/help\n",
+			contains: []string{"[#console] saltbot:\n```Good salty day to you!", "/press <number>"},
+		},
+		{
+			name:  "Test reminders are kept in memory",
+			input: "!remind set stretch in 2 hours\n/user bob\n!remind list\n/user you\n!remind list\n",
+			contains: []string{
+				"Created reminder with id",
+				"[#console] bob> [#console] saltbot:\n```Reminders:\n```",
+				": stretch on ",
+			},
+		},
+		{
+			name:     "Test switching channels and quitting",
+			input:    "/channel general\n!h\n/quit\n!h\n",
+			contains: []string{"[#general] you> [#general] saltbot:"},
+		},
+		{
+			name:     "Test pressing buttons",
+			input:    "!trivia play --count 1\n/press 9\n/press 1\n!trivia stop\n",
+			contains: []string{"[1: ", "there's no button 9", "[only you can see this] saltbot:\nYou picked", "Game over!"},
+		},
+		{
+			name:     "Test waiting",
+			input:    "/wait 2h\n/wait soon\n",
+			contains: []string{"it's now " + time.Unix(7200, 0).Format(time.RFC1123), "usage: /wait <duration>"},
+		},
+		{
+			name:     "Test unknown console commands",
+			input:    "/bogus\n/user\n",
+			contains: []string{"unknown console command /bogus", "usage: /user <name>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := run(t, tt.input)
+			for _, expected := range tt.contains {
+				if !strings.Contains(out, expected) {
+					t.Errorf("expected '%s' in output:\n%s", expected, out)
+				}
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	actual := render(&discordgo.MessageSend{
+		Content: "Here you go",
+		Embeds: []*discordgo.MessageEmbed{{
+			Title:       "Salty video",
+			URL:         "https://youtube.com/watch?v=salty",
+			Description: "line 1\nline 2",
+			Fields:      []*discordgo.MessageEmbedField{{Name: "Views", Value: "12"}},
+			Image:       &discordgo.MessageEmbedImage{URL: "https://img"},
+		}},
+		Files: []*discordgo.File{{Name: "waifu.png"}},
+	})
+
+	expected := "Here you go\n| Salty video\n| https://youtube.com/watch?v=salty\n| line 1\n| line 2\n| Views: 12\n| image: https://img\n(attached waifu.png)"
+	if actual != expected {
+		t.Errorf("expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+}
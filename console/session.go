@@ -0,0 +1,168 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Stands in for discord, printing everything saltbot sends.
+type Session struct {
+	out  io.Writer
+	lock sync.Mutex
+
+	// Users whose messages count as a server admin's
+	admins map[string]bool
+
+	// Channels marked NSFW
+	nsfw map[string]bool
+
+	// Buttons on the last message with any, for "/press"
+	buttons []discordgo.Button
+}
+
+func NewSession(out io.Writer) *Session {
+	return &Session{
+		out:    out,
+		admins: map[string]bool{},
+		nsfw:   map[string]bool{},
+	}
+}
+
+func (s *Session) ChannelMessageSend(channelID string, content string, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{Content: content})
+}
+
+func (s *Session) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	fmt.Fprintf(s.out, "[#%s] saltbot:\n%s\n", channelID, render(data))
+	if buttons := buttonsOf(data.Components); len(buttons) > 0 {
+		s.buttons = buttons
+	}
+	return &discordgo.Message{ChannelID: channelID, Content: data.Content}, nil
+}
+
+func (s *Session) UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	return &discordgo.Channel{ID: "dm-" + recipientID, Type: discordgo.ChannelTypeDM}, nil
+}
+
+func (s *Session) UserChannelPermissions(userID, channelID string, fetchOptions ...discordgo.RequestOption) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.admins[userID] {
+		return discordgo.PermissionManageServer, nil
+	}
+	return 0, nil
+}
+
+func (s *Session) Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return &discordgo.Channel{ID: channelID, NSFW: s.nsfw[channelID]}, nil
+}
+
+func (s *Session) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if resp.Data == nil {
+		return errors.New("interaction response has no data")
+	}
+	fmt.Fprintf(s.out, "[only you can see this] saltbot:\n%s\n", resp.Data.Content)
+	return nil
+}
+
+func (s *Session) StateUser() *discordgo.User {
+	return &discordgo.User{ID: "saltbot", Username: "saltbot"}
+}
+
+// The custom id of the nth button (starting at 1) on the last message with
+// buttons.
+func (s *Session) button(n int) (string, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if n < 1 || n > len(s.buttons) {
+		return "", false
+	}
+	return s.buttons[n-1].CustomID, true
+}
+
+func (s *Session) printf(format string, args ...interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	fmt.Fprintf(s.out, format, args...)
+}
+
+func buttonsOf(components []discordgo.MessageComponent) []discordgo.Button {
+	buttons := []discordgo.Button{}
+	for _, component := range components {
+		switch c := component.(type) {
+		case discordgo.ActionsRow:
+			buttons = append(buttons, buttonsOf(c.Components)...)
+		case *discordgo.ActionsRow:
+			buttons = append(buttons, buttonsOf(c.Components)...)
+		case discordgo.Button:
+			buttons = append(buttons, c)
+		case *discordgo.Button:
+			buttons = append(buttons, *c)
+		}
+	}
+	return buttons
+}
+
+// Render a message as plain text, with embeds, attachments and buttons below
+// the content.
+func render(data *discordgo.MessageSend) string {
+	lines := []string{}
+	if data.Content != "" {
+		lines = append(lines, data.Content)
+	}
+
+	for _, embed := range data.Embeds {
+		if embed.Title != "" {
+			lines = append(lines, "| "+embed.Title)
+		}
+		if embed.URL != "" {
+			lines = append(lines, "| "+embed.URL)
+		}
+		if embed.Description != "" {
+			for _, line := range strings.Split(embed.Description, "\n") {
+				lines = append(lines, "| "+line)
+			}
+		}
+		for _, field := range embed.Fields {
+			lines = append(lines, fmt.Sprintf("| %s: %s", field.Name, field.Value))
+		}
+		if embed.Image != nil {
+			lines = append(lines, "| image: "+embed.Image.URL)
+		}
+		if embed.Thumbnail != nil {
+			lines = append(lines, "| thumbnail: "+embed.Thumbnail.URL)
+		}
+		if embed.Footer != nil {
+			lines = append(lines, "| "+embed.Footer.Text)
+		}
+	}
+
+	for _, file := range data.Files {
+		lines = append(lines, "(attached "+file.Name+")")
+	}
+
+	labels := []string{}
+	for i, button := range buttonsOf(data.Components) {
+		labels = append(labels, fmt.Sprintf("[%d: %s]", i+1, button.Label))
+	}
+	if len(labels) > 0 {
+		lines = append(lines, strings.Join(labels, " ")+"  (type \"/press <number>\")")
+	}
+
+	return strings.Join(lines, "\n")
+}
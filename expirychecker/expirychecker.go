@@ -3,12 +3,32 @@ package expirychecker
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strconv"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	c "github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/config"
+	"github.com/highsaltlevels/saltbot/logging"
+	"github.com/highsaltlevels/saltbot/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// How late results and reminders go out. The poller checks every second,
+	// so anything much past that means sending is failing or falling behind.
+	lag = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "saltbot_expiry_lag_seconds",
+		Help:    "How long after expiring polls and reminders were sent.",
+		Buckets: []float64{1, 2, 5, 10, 30, 60, 300, 900},
+	}, []string{"kind"})
+
+	lastCheck = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "saltbot_expiry_last_check_timestamp_seconds",
+		Help: "When the poller last checked for expired polls and reminders.",
+	})
 )
 
 type SessionInterface interface {
@@ -18,12 +38,14 @@ type SessionInterface interface {
 type Poller struct {
 	session SessionInterface
 	ctx     context.Context
+	clock   util.Clock
 }
 
-func NewPoller(s SessionInterface, ctx context.Context) *Poller {
+func NewPoller(s SessionInterface, ctx context.Context, clock util.Clock) *Poller {
 	return &Poller{
 		session: s,
 		ctx:     ctx,
+		clock:   clock,
 	}
 }
 
@@ -31,30 +53,33 @@ func (p *Poller) Loop() {
 	for {
 		select {
 		case <-p.ctx.Done():
-			fmt.Println("poller stopped")
+			slog.Info("poller stopped")
 			return
 
-		case <-time.After(1 * time.Second):
+		case <-p.clock.After(1 * time.Second):
 			polls, reminders := p.getExpired()
+			lastCheck.Set(float64(p.clock.Now().Unix()))
 			for _, poll := range polls {
-				log.Printf("sending poll %s to %s\n", poll.Id, poll.Channel)
+				ctx := logging.Start(p.ctx, "poll", poll.Id, "guild", poll.Guild, "channel", poll.Channel)
+				logging.From(ctx).Info("sending poll results")
 				err := p.sendPoll(&poll)
 				if err != nil {
-					log.Printf("error sending poll: %v\n", err)
-					log.Printf("will retry again on the next iteration")
+					logging.From(ctx).Error("failed to send poll results, retrying next time", "error", err)
 				} else {
-					c.Cache.Delete(fmt.Sprintf("poll-%s", poll.Id))
+					lag.WithLabelValues("poll").Observe(p.clock.Now().Sub(time.Unix(poll.Expiry, 0)).Seconds())
+					c.Cache.DeletePoll(ctx, poll.Id)
 				}
 			}
 
 			for _, reminder := range reminders {
-				log.Printf("sending reminder %s to %s\n", reminder.Id, reminder.Channel)
+				ctx := logging.Start(p.ctx, "reminder", reminder.Id, "channel", reminder.Channel)
+				logging.From(ctx).Info("sending reminder")
 				err := p.sendReminder(&reminder)
 				if err != nil {
-					log.Printf("error sending reminder: %v\n", err)
-					log.Printf("will retry again on the next iteration")
+					logging.From(ctx).Error("failed to send reminder, retrying next time", "error", err)
 				} else {
-					c.Cache.Delete(fmt.Sprintf("reminder-%s", reminder.Id))
+					lag.WithLabelValues("reminder").Observe(p.clock.Now().Sub(time.Unix(reminder.Expiry, 0)).Seconds())
+					c.Cache.DeleteReminder(ctx, reminder.Id)
 				}
 			}
 		}
@@ -64,14 +89,14 @@ func (p *Poller) Loop() {
 func (p *Poller) getExpired() (polls []c.Poll, reminders []c.Reminder) {
 	for _, poll := range c.Cache.ListPolls() {
 		expiry := time.Unix(poll.Expiry, 0)
-		if time.Now().Sub(expiry) >= 0.0 {
+		if p.clock.Now().Sub(expiry) >= 0.0 {
 			polls = append(polls, poll)
 		}
 	}
 
 	for _, reminder := range c.Cache.ListReminders() {
 		expiry := time.Unix(reminder.Expiry, 0)
-		if time.Now().Sub(expiry) >= 0.0 {
+		if p.clock.Now().Sub(expiry) >= 0.0 {
 			reminders = append(reminders, reminder)
 		}
 	}
@@ -102,7 +127,9 @@ func (p *Poller) sendPoll(poll *c.Poll) error {
 		}
 	}
 
-	return p.sendMessage(poll.Channel, fmt.Sprintf("%s```", msg))
+	// Guilds can pick a channel for results instead of the poll's
+	channel := config.ResultsChannel(poll.Guild, poll.Channel)
+	return p.sendMessage(channel, fmt.Sprintf("%s```", msg))
 }
 
 func (p *Poller) sendReminder(r *c.Reminder) error {
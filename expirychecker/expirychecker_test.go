@@ -8,18 +8,19 @@ import (
 	"time"
 
 	"github.com/bwmarrin/discordgo"
-	"k8s.io/client-go/kubernetes"
 
 	"github.com/highsaltlevels/saltbot/cache"
 	"github.com/highsaltlevels/saltbot/testutil"
+	"github.com/highsaltlevels/saltbot/util"
 )
 
 type MockDiscordSession struct {
 	// error to return. Leave this as nil to return nil as error
 	err error
 
-	// used to save what would have been sent as a message
+	// used to save what would have been sent as a message, and where
 	SentMessage string
+	SentChannel string
 }
 
 func (m *MockDiscordSession) ChannelMessageSend(channelID string, content string, options ...discordgo.RequestOption) (*discordgo.Message, error) {
@@ -28,14 +29,23 @@ func (m *MockDiscordSession) ChannelMessageSend(channelID string, content string
 	}
 
 	m.SentMessage = content
+	m.SentChannel = channelID
 	return nil, m.err
 }
 
+// Move the clock forward and wait for the poller to get back to waiting on
+// it, so that whatever was due has been sent.
+func tick(clock *util.FakeClock, d time.Duration) {
+	clock.BlockUntil(1)
+	clock.Advance(d)
+	clock.BlockUntil(1)
+}
+
 func TestPollerLoop(t *testing.T) {
 	tests := []struct {
 		name                 string
 		session              MockDiscordSession
-		client               kubernetes.Interface
+		client               cache.KubeClient
 		cache                *cache.ConfigMapCache
 		expectedMessageParts []string
 	}{
@@ -47,9 +57,9 @@ func TestPollerLoop(t *testing.T) {
 				map[string]cache.Poll{
 					"1234": cache.Poll{
 						Prompt: "prompt",
-						Votes: map[string][]interface{}{
-							"0": []interface{}{"person1", "persion2"},
-							"1": []interface{}{"person3"},
+						Votes: map[string][]string{
+							"0": []string{"person1", "persion2"},
+							"1": []string{"person3"},
 						},
 						Choices: []string{
 							"choice1",
@@ -76,9 +86,9 @@ func TestPollerLoop(t *testing.T) {
 				map[string]cache.Poll{
 					"1234": cache.Poll{
 						Prompt: "prompt",
-						Votes: map[string][]interface{}{
-							"0": []interface{}{},
-							"1": []interface{}{},
+						Votes: map[string][]string{
+							"0": []string{},
+							"1": []string{},
 						},
 						Choices: []string{
 							"choice1",
@@ -114,9 +124,9 @@ func TestPollerLoop(t *testing.T) {
 				map[string]cache.Poll{
 					"1234": cache.Poll{
 						Prompt: "prompt",
-						Votes: map[string][]interface{}{
-							"0": []interface{}{},
-							"1": []interface{}{},
+						Votes: map[string][]string{
+							"0": []string{},
+							"1": []string{},
 						},
 						Choices: []string{
 							"choice1",
@@ -151,10 +161,10 @@ func TestPollerLoop(t *testing.T) {
 			cache.Client = tt.client
 			ctx, cancel := context.WithCancel(context.Background())
 
-			poller := NewPoller(&tt.session, ctx)
+			clock := util.NewFakeClock(time.Unix(0, 0))
+			poller := NewPoller(&tt.session, ctx, clock)
 			go poller.Loop()
-			// Give 1.1 seconds to make sure the poll/reminder gets picked up.
-			time.Sleep(1100 * time.Millisecond)
+			tick(clock, time.Second)
 			cancel()
 
 			if len(tt.expectedMessageParts) == 0 && tt.session.SentMessage != "" {
@@ -169,3 +179,65 @@ func TestPollerLoop(t *testing.T) {
 		})
 	}
 }
+
+func TestPollerWaitsForExpiry(t *testing.T) {
+	start := time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC)
+	cache.Client = &testutil.MockK8sClient{}
+	cache.Cache = cache.NewInMemConfigMapCache(
+		map[string]cache.Poll{},
+		map[string]cache.Reminder{
+			"1234": cache.Reminder{
+				Message: "an hour later",
+				Expiry:  start.Add(time.Hour).Unix(),
+			},
+		},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	session := &MockDiscordSession{}
+	clock := util.NewFakeClock(start)
+	go NewPoller(session, ctx, clock).Loop()
+
+	tick(clock, 59*time.Minute)
+	if session.SentMessage != "" {
+		t.Fatalf("expected nothing to be sent before the reminder is due, but got: %s", session.SentMessage)
+	}
+
+	tick(clock, time.Minute)
+	if session.SentMessage != "```an hour later```" {
+		t.Errorf("expected the reminder once it was due, but got: %s", session.SentMessage)
+	}
+}
+
+func TestPollerPostsToResultsChannel(t *testing.T) {
+	start := time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC)
+	cache.Client = &testutil.MockK8sClient{}
+	cache.Cache = cache.NewInMemConfigMapCache(
+		map[string]cache.Poll{
+			"1234": cache.Poll{
+				Prompt:  "prompt",
+				Channel: "poll-channel",
+				Guild:   "results-guild",
+				Expiry:  start.Unix(),
+			},
+		},
+		map[string]cache.Reminder{},
+	)
+	guild := &cache.GuildConfig{Id: "results-guild", ResultsChannel: "results"}
+	configMap, _ := guild.ToConfigMap()
+	cache.Cache.Store(configMap)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	session := &MockDiscordSession{}
+	clock := util.NewFakeClock(start)
+	go NewPoller(session, ctx, clock).Loop()
+
+	tick(clock, time.Second)
+	if session.SentChannel != "results" {
+		t.Errorf("expected the results in the guild's results channel, but got: %s", session.SentChannel)
+	}
+}
@@ -1,47 +1,127 @@
 package giphy
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 
+	"github.com/highsaltlevels/saltbot/lrucache"
 	"github.com/highsaltlevels/saltbot/util"
 )
 
+const (
+	searchEndpoint    = "https://api.giphy.com/v1/gifs/search"
+	randomEndpoint    = "https://api.giphy.com/v1/gifs/random"
+	trendingEndpoint  = "https://api.giphy.com/v1/gifs/trending"
+	translateEndpoint = "https://api.giphy.com/v1/gifs/translate"
+)
+
+// Number of gifs per "-a" page, keeping messages under discord's 2000 characters.
+const allGifsPageSize = 10
+
 var token string
 var client util.HttpClientInterface
 
+// Make giphy and tenor requests with c instead, like a stand in for the apis.
+// Must be called before any gifs are fetched.
+func SetClient(c util.HttpClientInterface) {
+	client = c
+	tenorClient = c
+}
+
+// The flags understood by "!gif"
+var flagSpec = util.FlagSpec{"-i": true, "-a": false, "-p": true}
+
+// Responses keyed on the endpoint and normalized query. TTL is set with GIPHY_CACHE_TTL.
+var gifCache *lrucache.Cache
+
 type GiphyData struct {
-	// We only care about the bitly_gif_url
-	Url string `json:"bitly_gif_url"`
+	Url string `json:"url"`
+
+	// Deprecated by giphy, only used if url is missing
+	BitlyUrl string `json:"bitly_gif_url,omitempty"`
 }
 
 type GiphyResponse struct {
 	Data []GiphyData `json:"data"`
 }
 
+// The random endpoint returns a single object for data rather than a list.
+func (r *GiphyResponse) UnmarshalJSON(body []byte) error {
+	var raw struct {
+		Data json.RawMessage `json:"data"`
+	}
+	err := json.Unmarshal(body, &raw)
+	if err != nil {
+		return err
+	}
+
+	data := bytes.TrimSpace(raw.Data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		r.Data = []GiphyData{}
+		return nil
+	}
+
+	if data[0] == '{' {
+		var single GiphyData
+		err = json.Unmarshal(data, &single)
+		r.Data = []GiphyData{single}
+		return err
+	}
+
+	return json.Unmarshal(data, &r.Data)
+}
+
 func init() {
 	var ok bool
 	if token, ok = os.LookupEnv("GIPHY_AUTH"); !ok {
-		log.Println("failed to get giphy auth from env var")
-		log.Println("continuing saltbot startup with partial functionality")
+		slog.Warn("GIPHY_AUTH isn't set, continuing without gifs")
 	}
+	tenorToken = os.Getenv("TENOR_AUTH")
 
 	if client == nil {
-		client = &http.Client{}
+		client = util.NewResilientClient("giphy", util.HttpTimeout, util.SystemClock)
+	}
+	if tenorClient == nil {
+		tenorClient = util.NewResilientClient("tenor", util.HttpTimeout, util.SystemClock)
 	}
+
+	gifCache = lrucache.New("giphy", lrucache.DefaultSize, lrucache.TTLFromEnv("GIPHY_CACHE_TTL", time.Hour), util.SystemClock)
+	tenorCache = lrucache.New("tenor", lrucache.DefaultSize, lrucache.TTLFromEnv("TENOR_CACHE_TTL", time.Hour), util.SystemClock)
+	providers = selectProviders(os.Getenv("GIF_PROVIDER"))
 }
 
-func fetchGif(query string) (*GiphyResponse, error) {
-	url := fmt.Sprintf("http://api.giphy.com/v1/gifs/search?q=%s&api_key=%s", query, token)
-	resp, err := client.Get(url)
+// Call a giphy endpoint. Responses from the random endpoint are never cached.
+func fetchGif(ctx context.Context, endpoint string, params url.Values) (*GiphyResponse, error) {
+	key := cacheKey(endpoint, params)
+	if endpoint != randomEndpoint {
+		if cached, ok := gifCache.Get(key); ok {
+			return cached.(*GiphyResponse), nil
+		}
+	}
+
+	query := url.Values{}
+	for k, v := range params {
+		query[k] = v
+	}
+	query.Set("api_key", token)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get giphy gif: %w", err)
 	}
@@ -62,66 +142,125 @@ func fetchGif(query string) (*GiphyResponse, error) {
 		return nil, fmt.Errorf("failed to unmarshal giphy response: %w", err)
 	}
 
+	if endpoint != randomEndpoint {
+		gifCache.Add(key, &gif)
+	}
 	return &gif, nil
 }
 
-func getAllGifs(query string) (string, error) {
-	gif, err := fetchGif(query)
-	if err != nil {
-		return "", err
+// Build a cache key from an endpoint and its params, normalizing each value.
+func cacheKey(endpoint string, params url.Values) string {
+	normalized := url.Values{}
+	for k, values := range params {
+		for _, v := range values {
+			normalized.Add(k, lrucache.NormalizeKey(v))
+		}
+	}
+	return endpoint + "?" + normalized.Encode()
+}
+
+// List a page of gifs. Pages start at 1.
+func getAllGifs(gifs []Gif, page int) string {
+	pages := (len(gifs) + allGifsPageSize - 1) / allGifsPageSize
+	if page > pages {
+		return fmt.Sprintf("```There are only %d pages of gifs for that query```", pages)
 	}
 
 	msg := "Here's all the gifs for that query:\n"
-	for _, data := range gif.Data {
-		msg += data.Url + "\n"
+	if pages > 1 {
+		msg = fmt.Sprintf("Here's all the gifs for that query (page %d of %d):\n", page, pages)
 	}
 
-	return msg, nil
+	start := (page - 1) * allGifsPageSize
+	end := start + allGifsPageSize
+	if end > len(gifs) {
+		end = len(gifs)
+	}
+	for _, gif := range gifs[start:end] {
+		msg += gif.Url + "\n"
+	}
+
+	if page < pages {
+		msg += fmt.Sprintf("Add \"-p %d\" to see the next page\n", page+1)
+	}
+
+	return msg
 }
 
-func getGif(query string, idx int) (string, error) {
-	gif, err := fetchGif(query)
+func Get(ctx context.Context, m *discordgo.MessageCreate, origin Origin) (*discordgo.MessageSend, error) {
+	flags, err := util.ParseFlags(m.Content, flagSpec)
 	if err != nil {
-		return "", err
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```%v```", err),
+		}, nil
 	}
 
-	return gif.Data[idx].Url, nil
-}
+	if len(flags.Terms) > 0 && flags.Terms[0] == "rating" {
+		return handleRating(ctx, flags.Terms[1:], origin, m.Author.ID)
+	}
 
-func Get(content string) (*discordgo.MessageSend, error) {
-	args := strings.Split(content, " ")[1:]
-	if len(args) == 0 {
+	idx, err := flags.Int("-i", 0)
+	if err != nil || idx < 0 || idx > 24 {
 		return &discordgo.MessageSend{
-			Content: "```Must specify giphy query like: \"!giphy dog\"```",
+			Content: "```Must use a valid number between 0 and 24```",
 		}, nil
 	}
 
-	for i, arg := range args {
-		if arg == "-i" {
-			idx, err := strconv.Atoi(args[i+1])
-			if err != nil || idx < 0 || idx > 24 {
-				return &discordgo.MessageSend{
-					Content: "```Must use a valid number between 0 and 24```",
-				}, nil
-			}
+	page, err := flags.Int("-p", 1)
+	if err != nil || page < 1 {
+		return &discordgo.MessageSend{
+			Content: "```Must use a page number of 1 or more```",
+		}, nil
+	}
 
-			gif, err := getGif(util.ParseArgsToQuery(args), idx)
-			return &discordgo.MessageSend{
-				Content: gif,
-			}, err
-		}
+	if len(flags.Terms) == 0 {
+		return &discordgo.MessageSend{
+			Content: "```Must specify giphy query like: \"!giphy dog\"```",
+		}, nil
+	}
 
-		if arg == "-a" {
-			gif, err := getAllGifs(util.ParseArgsToQuery(args))
+	var gifs []Gif
+	rating := ratingFor(origin)
+	args := flags.Terms[1:]
+	switch flags.Terms[0] {
+	case "random":
+		gifs, err = random(ctx, strings.Join(args, " "), rating)
+	case "trending":
+		gifs, err = trending(ctx, rating)
+	case "say":
+		if len(args) == 0 {
 			return &discordgo.MessageSend{
-				Content: gif,
-			}, err
+				Content: "```Must specify something to say like: \"!gif say good morning\"```",
+			}, nil
 		}
+		gifs, err = translate(ctx, strings.Join(args, " "), rating)
+	default:
+		gifs, err = search(ctx, flags.Query(), rating)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(gifs) == 0 {
+		return &discordgo.MessageSend{
+			Content: "```No gifs for that query :(```",
+		}, nil
+	}
+
+	if flags.Has("-a") || flags.Has("-p") {
+		return &discordgo.MessageSend{
+			Content: getAllGifs(gifs, page),
+		}, nil
+	}
+
+	// Assume the first query result if -i wasn't used
+	if idx >= len(gifs) {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```There are only %d gifs for that query```", len(gifs)),
+		}, nil
 	}
 
-	// If we get here, then -a and -i were not used. Assume first query result
-	gif, err := getGif(util.ParseArgsToQuery(args), 0)
 	return &discordgo.MessageSend{
-		Content: gif,
-	}, err
+		Content: gifs[idx].Url,
+	}, nil
 }
@@ -1,14 +1,19 @@
 package giphy
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"testing"
 
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/cache"
 	"github.com/highsaltlevels/saltbot/util"
 )
 
@@ -41,9 +46,21 @@ type MockHttpClient struct {
 
 	// The response code to be used in the http response object
 	responseCode int
+
+	// The last url requested through the client
+	lastUrl string
+
+	// Number of requests made through the client
+	calls int
 }
 
-func (c *MockHttpClient) Get(url string) (*http.Response, error) {
+func (c *MockHttpClient) Do(req *http.Request) (*http.Response, error) {
+	return c.Get(req.Context(), req.URL.String())
+}
+
+func (c *MockHttpClient) Get(ctx context.Context, url string) (*http.Response, error) {
+	c.lastUrl = url
+	c.calls++
 	if c.expectError {
 		return nil, errors.New(expectedError)
 	}
@@ -63,6 +80,19 @@ func (c *MockHttpClient) Get(url string) (*http.Response, error) {
 	}, nil
 }
 
+func newMessage(content string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			Content:   content,
+			ChannelID: "channel",
+			GuildID:   "guild",
+			Author: &discordgo.User{
+				ID: "1234",
+			},
+		},
+	}
+}
+
 func TestGet(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -207,6 +237,44 @@ func TestGet(t *testing.T) {
 			expectedResponse: "```Must use a valid number between 0 and 24```",
 			expectedError:    nil,
 		},
+		{
+			name:             "Test no results",
+			commandStr:       "!giphy query",
+			giphyResponse:    GiphyResponse{Data: []GiphyData{}},
+			getResponseCode:  http.StatusOK,
+			expectedResponse: "```No gifs for that query :(```",
+			expectedError:    nil,
+		},
+		{
+			name:       "Test index past the number of results",
+			commandStr: "!giphy query -i 3",
+			giphyResponse: GiphyResponse{
+				Data: []GiphyData{
+					GiphyData{
+						Url: "foo",
+					},
+				},
+			},
+			getResponseCode:  http.StatusOK,
+			expectedResponse: "```There are only 1 gifs for that query```",
+			expectedError:    nil,
+		},
+		{
+			name:             "Test trailing index flag",
+			commandStr:       "!giphy query -i",
+			giphyResponse:    GiphyResponse{},
+			getResponseCode:  http.StatusOK,
+			expectedResponse: "```flag -i needs a value after it```",
+			expectedError:    nil,
+		},
+		{
+			name:             "Test unknown flag",
+			commandStr:       "!giphy query -x",
+			giphyResponse:    GiphyResponse{},
+			getResponseCode:  http.StatusOK,
+			expectedResponse: "```unknown flag -x, expected one of: -a, -i, -p```",
+			expectedError:    nil,
+		},
 		{
 			name:            "Test unmarshalable giphy response",
 			commandStr:      "!giphy query",
@@ -226,6 +294,8 @@ func TestGet(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			gifCache.Purge()
+			cache.Cache = cache.NewInMemConfigMapCache(map[string]cache.Poll{}, map[string]cache.Reminder{})
 			client = &MockHttpClient{
 				expectError:   tt.shouldClientError,
 				expectIOError: tt.shouldReadCloserError,
@@ -233,7 +303,7 @@ func TestGet(t *testing.T) {
 				giphyResponse: tt.giphyResponse,
 			}
 
-			msg, err := Get(tt.commandStr)
+			msg, err := Get(context.Background(), newMessage(tt.commandStr), Origin{})
 			if tt.expectedError != nil {
 				if err == nil {
 					t.Errorf("expected error '%v' to be returned but was nil", tt.expectedError)
@@ -252,3 +322,181 @@ func TestGet(t *testing.T) {
 		})
 	}
 }
+
+func TestFetchGifCached(t *testing.T) {
+	gifCache.Purge()
+	cache.Cache = cache.NewInMemConfigMapCache(map[string]cache.Poll{}, map[string]cache.Reminder{})
+	mock := &MockHttpClient{
+		responseCode: http.StatusOK,
+		giphyResponse: GiphyResponse{
+			Data: []GiphyData{
+				GiphyData{
+					Url: "foo",
+				},
+			},
+		},
+	}
+	client = mock
+
+	for _, query := range []string{"dog", "Dog", " DOG "} {
+		gif, err := fetchGif(context.Background(), searchEndpoint, url.Values{"q": {query}, "rating": {"g"}})
+		if err != nil {
+			t.Fatalf("expected no error but got error: '%v'", err)
+		}
+		if gif.Data[0].Url != "foo" {
+			t.Errorf("expected url 'foo', but got '%s'", gif.Data[0].Url)
+		}
+	}
+
+	if mock.calls != 1 {
+		t.Errorf("expected 1 request to giphy, but got %d", mock.calls)
+	}
+
+	stats := gifCache.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("expected 2 hits and 1 miss, but got %d hits and %d misses", stats.Hits, stats.Misses)
+	}
+}
+
+func TestFetchGifEncodesQuery(t *testing.T) {
+	gifCache.Purge()
+	cache.Cache = cache.NewInMemConfigMapCache(map[string]cache.Poll{}, map[string]cache.Reminder{})
+	token = "secret&key"
+	defer func() { token = "" }()
+	mock := &MockHttpClient{
+		responseCode:  http.StatusOK,
+		giphyResponse: GiphyResponse{Data: []GiphyData{GiphyData{Url: "foo"}}},
+	}
+	client = mock
+
+	_, err := Get(context.Background(), newMessage("!gif cats & dogs #1?"), Origin{})
+	if err != nil {
+		t.Fatalf("expected no error but got error: '%v'", err)
+	}
+
+	expectedUrl := "https://api.giphy.com/v1/gifs/search?api_key=secret%26key&q=cats+%26+dogs+%231%3F&rating=g"
+	if mock.lastUrl != expectedUrl {
+		t.Errorf("expected url '%s', but got '%s'", expectedUrl, mock.lastUrl)
+	}
+}
+
+func TestGetSubcommands(t *testing.T) {
+	tests := []struct {
+		name             string
+		commandStr       string
+		giphyResponse    interface{}
+		expectedResponse string
+		expectedUrl      string
+	}{
+		{
+			name:             "Test random gif",
+			commandStr:       "!gif random dog",
+			giphyResponse:    map[string]interface{}{"data": map[string]string{"url": "foo"}},
+			expectedResponse: "foo",
+			expectedUrl:      "https://api.giphy.com/v1/gifs/random?api_key=&rating=g&tag=dog",
+		},
+		{
+			name:             "Test random gif without a tag",
+			commandStr:       "!gif random",
+			giphyResponse:    map[string]interface{}{"data": map[string]string{"url": "foo"}},
+			expectedResponse: "foo",
+			expectedUrl:      "https://api.giphy.com/v1/gifs/random?api_key=&rating=g",
+		},
+		{
+			name:       "Test trending gif with index",
+			commandStr: "!gif trending -i 1",
+			giphyResponse: GiphyResponse{
+				Data: []GiphyData{{Url: "foo"}, {Url: "bar"}},
+			},
+			expectedResponse: "bar",
+			expectedUrl:      "https://api.giphy.com/v1/gifs/trending?api_key=&rating=g",
+		},
+		{
+			name:             "Test translate phrase",
+			commandStr:       "!gif say good morning",
+			giphyResponse:    map[string]interface{}{"data": map[string]string{"url": "foo"}},
+			expectedResponse: "foo",
+			expectedUrl:      "https://api.giphy.com/v1/gifs/translate?api_key=&rating=g&s=good+morning",
+		},
+		{
+			name:             "Test translate without a phrase",
+			commandStr:       "!gif say",
+			giphyResponse:    GiphyResponse{},
+			expectedResponse: "```Must specify something to say like: \"!gif say good morning\"```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gifCache.Purge()
+			cache.Cache = cache.NewInMemConfigMapCache(map[string]cache.Poll{}, map[string]cache.Reminder{})
+			mock := &MockHttpClient{
+				responseCode:  http.StatusOK,
+				giphyResponse: tt.giphyResponse,
+			}
+			client = mock
+
+			msg, err := Get(context.Background(), newMessage(tt.commandStr), Origin{})
+			if err != nil {
+				t.Fatalf("expected no error but got error: '%v'", err)
+			}
+			if msg.Content != tt.expectedResponse {
+				t.Errorf("expected message: '%s', but got '%s'", tt.expectedResponse, msg.Content)
+			}
+			if mock.lastUrl != tt.expectedUrl {
+				t.Errorf("expected url '%s', but got '%s'", tt.expectedUrl, mock.lastUrl)
+			}
+		})
+	}
+}
+
+func TestGetAllGifsPaginated(t *testing.T) {
+	gifs := []Gif{}
+	for i := 0; i < 25; i++ {
+		gifs = append(gifs, Gif{Url: fmt.Sprintf("https://giphy.com/gifs/%d", i)})
+	}
+
+	tests := []struct {
+		name          string
+		page          int
+		expectedParts []string
+		missingParts  []string
+	}{
+		{
+			name:          "Test first page",
+			page:          1,
+			expectedParts: []string{"(page 1 of 3)", "gifs/0\n", "gifs/9\n", "\"-p 2\""},
+			missingParts:  []string{"gifs/10\n"},
+		},
+		{
+			name:          "Test last page",
+			page:          3,
+			expectedParts: []string{"(page 3 of 3)", "gifs/20\n", "gifs/24\n"},
+			missingParts:  []string{"gifs/19\n", "-p"},
+		},
+		{
+			name:          "Test page past the end",
+			page:          4,
+			expectedParts: []string{"There are only 3 pages"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := getAllGifs(gifs, tt.page)
+			if len(msg) > 2000 {
+				t.Errorf("expected message under 2000 characters, but got %d", len(msg))
+			}
+			for _, part := range tt.expectedParts {
+				if !strings.Contains(msg, part) {
+					t.Errorf("expected '%s' to be in '%s'", part, msg)
+				}
+			}
+			for _, part := range tt.missingParts {
+				if strings.Contains(msg, part) {
+					t.Errorf("expected '%s' not to be in '%s'", part, msg)
+				}
+			}
+		})
+	}
+}
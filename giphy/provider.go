@@ -0,0 +1,164 @@
+package giphy
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/highsaltlevels/saltbot/logging"
+)
+
+// A single gif result
+type Gif struct {
+	Url string
+}
+
+// A source of gifs. Every call takes a giphy style content rating (g, pg,
+// pg-13 or r) which providers translate to their own content filter.
+type GifProvider interface {
+	Name() string
+	Search(ctx context.Context, query, rating string) ([]Gif, error)
+	// Random returns a single gif related to the tag
+	Random(ctx context.Context, tag, rating string) ([]Gif, error)
+	Trending(ctx context.Context, rating string) ([]Gif, error)
+}
+
+// Providers in order of preference. Later providers are used as a fallback
+// when earlier ones error or come back empty.
+var providers []GifProvider
+
+// Put the provider named by GIF_PROVIDER first, falling back to the other one
+// if it has credentials.
+func selectProviders(primary string) []GifProvider {
+	available := map[string]GifProvider{
+		"giphy": &giphyProvider{},
+	}
+	order := []string{"giphy", "tenor"}
+	if tenorToken != "" {
+		available["tenor"] = &tenorProvider{}
+	}
+
+	primary = strings.ToLower(primary)
+	if primary == "tenor" {
+		if _, ok := available["tenor"]; !ok {
+			slog.Warn("GIF_PROVIDER is tenor but TENOR_AUTH isn't set, using giphy")
+		}
+		order = []string{"tenor", "giphy"}
+	} else if primary != "" && primary != "giphy" {
+		slog.Warn("unknown GIF_PROVIDER, using giphy", "provider", primary)
+	}
+
+	selected := []GifProvider{}
+	for _, name := range order {
+		if provider, ok := available[name]; ok {
+			selected = append(selected, provider)
+		}
+	}
+
+	return selected
+}
+
+// Try each provider in turn until one returns gifs. If none do, the first
+// error (if any) is returned, so an outage isn't reported as "no gifs found"
+// just because a fallback came back empty.
+func withFallback(ctx context.Context, call func(GifProvider) ([]Gif, error)) ([]Gif, error) {
+	var firstErr error
+	for _, provider := range providers {
+		gifs, err := call(provider)
+		if err == nil && len(gifs) > 0 {
+			return gifs, nil
+		}
+
+		if err != nil {
+			logging.From(ctx).Warn("gif provider failed", "provider", provider.Name(), "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return []Gif{}, firstErr
+}
+
+func search(ctx context.Context, query, rating string) ([]Gif, error) {
+	return withFallback(ctx, func(p GifProvider) ([]Gif, error) {
+		return p.Search(ctx, query, rating)
+	})
+}
+
+func random(ctx context.Context, tag, rating string) ([]Gif, error) {
+	return withFallback(ctx, func(p GifProvider) ([]Gif, error) {
+		return p.Random(ctx, tag, rating)
+	})
+}
+
+func trending(ctx context.Context, rating string) ([]Gif, error) {
+	return withFallback(ctx, func(p GifProvider) ([]Gif, error) {
+		return p.Trending(ctx, rating)
+	})
+}
+
+// Only giphy can translate a phrase into a gif. Other providers (or giphy
+// itself when translate comes back empty) fall back to a plain search.
+func translate(ctx context.Context, phrase, rating string) ([]Gif, error) {
+	return withFallback(ctx, func(p GifProvider) ([]Gif, error) {
+		if g, ok := p.(*giphyProvider); ok {
+			gifs, err := g.Translate(ctx, phrase, rating)
+			if err != nil || len(gifs) > 0 {
+				return gifs, err
+			}
+		}
+		return p.Search(ctx, phrase, rating)
+	})
+}
+
+type giphyProvider struct{}
+
+func (g *giphyProvider) Name() string {
+	return "giphy"
+}
+
+func (g *giphyProvider) Search(ctx context.Context, query, rating string) ([]Gif, error) {
+	return g.fetch(ctx, searchEndpoint, url.Values{"q": {query}, "rating": {rating}})
+}
+
+func (g *giphyProvider) Random(ctx context.Context, tag, rating string) ([]Gif, error) {
+	params := url.Values{"rating": {rating}}
+	if tag != "" {
+		params.Set("tag", tag)
+	}
+	return g.fetch(ctx, randomEndpoint, params)
+}
+
+func (g *giphyProvider) Trending(ctx context.Context, rating string) ([]Gif, error) {
+	return g.fetch(ctx, trendingEndpoint, url.Values{"rating": {rating}})
+}
+
+// Giphy's translate endpoint picks a single gif to match a word or phrase
+func (g *giphyProvider) Translate(ctx context.Context, phrase, rating string) ([]Gif, error) {
+	return g.fetch(ctx, translateEndpoint, url.Values{"s": {phrase}, "rating": {rating}})
+}
+
+func (g *giphyProvider) fetch(ctx context.Context, endpoint string, params url.Values) ([]Gif, error) {
+	resp, err := fetchGif(ctx, endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.gifs(), nil
+}
+
+func (r *GiphyResponse) gifs() []Gif {
+	gifs := make([]Gif, 0, len(r.Data))
+	for _, data := range r.Data {
+		gifUrl := data.Url
+		if gifUrl == "" {
+			gifUrl = data.BitlyUrl
+		}
+		if gifUrl != "" {
+			gifs = append(gifs, Gif{Url: gifUrl})
+		}
+	}
+	return gifs
+}
@@ -0,0 +1,319 @@
+package giphy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/highsaltlevels/saltbot/cache"
+)
+
+type MockProvider struct {
+	name  string
+	gifs  []Gif
+	err   error
+	calls int
+}
+
+func (p *MockProvider) Name() string {
+	return p.name
+}
+
+func (p *MockProvider) Search(ctx context.Context, query, rating string) ([]Gif, error) {
+	p.calls++
+	return p.gifs, p.err
+}
+
+func (p *MockProvider) Random(ctx context.Context, tag, rating string) ([]Gif, error) {
+	p.calls++
+	return p.gifs, p.err
+}
+
+func (p *MockProvider) Trending(ctx context.Context, rating string) ([]Gif, error) {
+	p.calls++
+	return p.gifs, p.err
+}
+
+func TestSelectProviders(t *testing.T) {
+	tests := []struct {
+		name          string
+		primary       string
+		tenorToken    string
+		expectedOrder []string
+	}{
+		{
+			name:          "Test giphy only without tenor credentials",
+			primary:       "",
+			expectedOrder: []string{"giphy"},
+		},
+		{
+			name:          "Test tenor as a fallback",
+			primary:       "giphy",
+			tenorToken:    "token",
+			expectedOrder: []string{"giphy", "tenor"},
+		},
+		{
+			name:          "Test tenor as the primary",
+			primary:       "Tenor",
+			tenorToken:    "token",
+			expectedOrder: []string{"tenor", "giphy"},
+		},
+		{
+			name:          "Test tenor as the primary without credentials",
+			primary:       "tenor",
+			expectedOrder: []string{"giphy"},
+		},
+		{
+			name:          "Test unknown provider",
+			primary:       "imgur",
+			expectedOrder: []string{"giphy"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenorToken = tt.tenorToken
+			defer func() { tenorToken = "" }()
+
+			order := []string{}
+			for _, provider := range selectProviders(tt.primary) {
+				order = append(order, provider.Name())
+			}
+			if !reflect.DeepEqual(order, tt.expectedOrder) {
+				t.Errorf("expected providers %v, but got %v", tt.expectedOrder, order)
+			}
+		})
+	}
+}
+
+func TestWithFallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		primary       *MockProvider
+		fallback      *MockProvider
+		expectedGifs  []Gif
+		expectedError error
+		fallbackCalls int
+	}{
+		{
+			name:          "Test primary succeeds",
+			primary:       &MockProvider{name: "primary", gifs: []Gif{{Url: "foo"}}},
+			fallback:      &MockProvider{name: "fallback", gifs: []Gif{{Url: "bar"}}},
+			expectedGifs:  []Gif{{Url: "foo"}},
+			fallbackCalls: 0,
+		},
+		{
+			name:          "Test primary errors",
+			primary:       &MockProvider{name: "primary", err: errors.New(expectedError)},
+			fallback:      &MockProvider{name: "fallback", gifs: []Gif{{Url: "bar"}}},
+			expectedGifs:  []Gif{{Url: "bar"}},
+			fallbackCalls: 1,
+		},
+		{
+			name:          "Test primary has no results",
+			primary:       &MockProvider{name: "primary", gifs: []Gif{}},
+			fallback:      &MockProvider{name: "fallback", gifs: []Gif{{Url: "bar"}}},
+			expectedGifs:  []Gif{{Url: "bar"}},
+			fallbackCalls: 1,
+		},
+		{
+			name:          "Test every provider has no results",
+			primary:       &MockProvider{name: "primary", gifs: []Gif{}},
+			fallback:      &MockProvider{name: "fallback", gifs: []Gif{}},
+			expectedGifs:  []Gif{},
+			fallbackCalls: 1,
+		},
+		{
+			name:          "Test primary errors and fallback has no results",
+			primary:       &MockProvider{name: "primary", err: errors.New(expectedError)},
+			fallback:      &MockProvider{name: "fallback", gifs: []Gif{}},
+			expectedGifs:  []Gif{},
+			expectedError: errors.New(expectedError),
+			fallbackCalls: 1,
+		},
+		{
+			name:          "Test every provider errors",
+			primary:       &MockProvider{name: "primary", err: errors.New(expectedError)},
+			fallback:      &MockProvider{name: "fallback", err: errors.New("second")},
+			expectedGifs:  []Gif{},
+			expectedError: errors.New(expectedError),
+			fallbackCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers = []GifProvider{tt.primary, tt.fallback}
+			defer func() { providers = selectProviders("") }()
+
+			gifs, err := search(context.Background(), "query", "g")
+			if tt.expectedError == nil && err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+			if tt.expectedError != nil && (err == nil || err.Error() != tt.expectedError.Error()) {
+				t.Errorf("expected error '%v', but got '%v'", tt.expectedError, err)
+			}
+			if !reflect.DeepEqual(gifs, tt.expectedGifs) {
+				t.Errorf("expected gifs %v, but got %v", tt.expectedGifs, gifs)
+			}
+			if tt.fallback.calls != tt.fallbackCalls {
+				t.Errorf("expected %d calls to the fallback, but got %d", tt.fallbackCalls, tt.fallback.calls)
+			}
+		})
+	}
+}
+
+func TestGetFallsBack(t *testing.T) {
+	cache.Cache = cache.NewInMemConfigMapCache(map[string]cache.Poll{}, map[string]cache.Reminder{})
+	providers = []GifProvider{
+		&MockProvider{name: "primary", err: errors.New(expectedError)},
+		&MockProvider{name: "fallback", gifs: []Gif{{Url: "bar"}}},
+	}
+	defer func() { providers = selectProviders("") }()
+
+	msg, err := Get(context.Background(), newMessage("!gif dog"), Origin{})
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if msg.Content != "bar" {
+		t.Errorf("expected the fallback gif 'bar', but got '%s'", msg.Content)
+	}
+}
+
+func TestGiphyResponseUnmarshal(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedGifs []Gif
+	}{
+		{
+			name:         "Test list of gifs",
+			body:         `{"data":[{"url":"foo"},{"url":"bar"}]}`,
+			expectedGifs: []Gif{{Url: "foo"}, {Url: "bar"}},
+		},
+		{
+			name:         "Test single gif from the random endpoint",
+			body:         `{"data":{"url":"foo"}}`,
+			expectedGifs: []Gif{{Url: "foo"}},
+		},
+		{
+			name:         "Test falling back to the bitly url",
+			body:         `{"data":[{"bitly_gif_url":"foo"}]}`,
+			expectedGifs: []Gif{{Url: "foo"}},
+		},
+		{
+			name:         "Test empty random result",
+			body:         `{"data":[]}`,
+			expectedGifs: []Gif{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp GiphyResponse
+			err := json.Unmarshal([]byte(tt.body), &resp)
+			if err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+			if !reflect.DeepEqual(resp.gifs(), tt.expectedGifs) {
+				t.Errorf("expected gifs %v, but got %v", tt.expectedGifs, resp.gifs())
+			}
+		})
+	}
+}
+
+func TestTenorProvider(t *testing.T) {
+	tests := []struct {
+		name            string
+		tenorResponse   interface{}
+		getResponseCode int
+		call            func(GifProvider) ([]Gif, error)
+		expectedGifs    []Gif
+		expectedUrl     string
+		expectedError   error
+	}{
+		{
+			name: "Test tenor search",
+			tenorResponse: TenorResponse{
+				Results: []TenorResult{{Url: "foo"}, {ItemUrl: "bar"}},
+			},
+			getResponseCode: http.StatusOK,
+			call: func(p GifProvider) ([]Gif, error) {
+				return p.Search(context.Background(), "cats & dogs", "pg")
+			},
+			expectedGifs: []Gif{{Url: "foo"}, {Url: "bar"}},
+			expectedUrl:  "https://tenor.googleapis.com/v2/search?contentfilter=medium&key=&media_filter=gif&q=cats+%26+dogs",
+		},
+		{
+			name:            "Test tenor trending",
+			tenorResponse:   TenorResponse{Results: []TenorResult{{Url: "foo"}}},
+			getResponseCode: http.StatusOK,
+			call: func(p GifProvider) ([]Gif, error) {
+				return p.Trending(context.Background(), "r")
+			},
+			expectedGifs: []Gif{{Url: "foo"}},
+			expectedUrl:  "https://tenor.googleapis.com/v2/featured?contentfilter=off&key=&media_filter=gif",
+		},
+		{
+			name:            "Test tenor random",
+			tenorResponse:   TenorResponse{Results: []TenorResult{{Url: "foo"}}},
+			getResponseCode: http.StatusOK,
+			call: func(p GifProvider) ([]Gif, error) {
+				return p.Random(context.Background(), "dog", "g")
+			},
+			expectedGifs: []Gif{{Url: "foo"}},
+			expectedUrl:  "https://tenor.googleapis.com/v2/search?contentfilter=high&key=&limit=1&media_filter=gif&q=dog&random=true",
+		},
+		{
+			name:            "Test tenor non-200 status code",
+			tenorResponse:   TenorResponse{},
+			getResponseCode: http.StatusInternalServerError,
+			call: func(p GifProvider) ([]Gif, error) {
+				return p.Search(context.Background(), "dog", "g")
+			},
+			expectedError: errors.New("received status code 500 from tenor"),
+		},
+		{
+			name:            "Test tenor unmarshalable response",
+			tenorResponse:   []byte("this can't be marshaled"),
+			getResponseCode: http.StatusOK,
+			call: func(p GifProvider) ([]Gif, error) {
+				return p.Search(context.Background(), "dog", "g")
+			},
+			expectedError: errors.New("failed to unmarshal tenor response"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenorCache.Purge()
+			mock := &MockHttpClient{
+				responseCode:  tt.getResponseCode,
+				giphyResponse: tt.tenorResponse,
+			}
+			tenorClient = mock
+
+			gifs, err := tt.call(&tenorProvider{})
+			if tt.expectedError != nil {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError.Error()) {
+					t.Errorf("expected error '%v', but got '%v'", tt.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+			if !reflect.DeepEqual(gifs, tt.expectedGifs) {
+				t.Errorf("expected gifs %v, but got %v", tt.expectedGifs, gifs)
+			}
+			if mock.lastUrl != tt.expectedUrl {
+				t.Errorf("expected url '%s', but got '%s'", tt.expectedUrl, mock.lastUrl)
+			}
+		})
+	}
+}
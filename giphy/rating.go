@@ -0,0 +1,147 @@
+package giphy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/cache"
+)
+
+// Giphy content ratings from most to least family friendly.
+var ratings = []string{"g", "pg", "pg-13", "r"}
+
+// Used when neither the channel nor the guild has a rating set.
+const defaultRating = "g"
+
+// The highest rating allowed in channels that aren't marked NSFW.
+const maxSafeRating = "pg-13"
+
+const ratingHelpMessage string = ("```Set the content rating for gifs. Ratings from most to least\n" +
+	"family friendly are: g, pg, pg-13 and r. Only channels marked NSFW\n" +
+	"can go above pg-13.\n\n" +
+	"To see the rating for this channel:\n\"!gif rating\"\n\n" +
+	"To set it for the whole server or just this channel (needs Manage Server):\n" +
+	"\"!gif rating pg server\"\n\"!gif rating pg-13 channel\"\n\n" +
+	"To go back to the server rating in this channel:\n\"!gif rating reset channel\"```")
+
+// Where a gif request came from, used to decide which content rating applies.
+type Origin struct {
+	Guild   string
+	Channel string
+	NSFW    bool
+
+	// Whether the requester can manage the server, and so change ratings
+	Admin bool
+}
+
+func ratingRank(rating string) int {
+	for rank, r := range ratings {
+		if r == rating {
+			return rank
+		}
+	}
+	return -1
+}
+
+// The rating to use for a request. A channel's rating overrides its guild's,
+// and anything above pg-13 is capped unless the channel is NSFW.
+func ratingFor(origin Origin) string {
+	rating := defaultRating
+	if r := cache.Cache.GetRating(origin.Channel); r != nil {
+		rating = r.Rating
+	} else if r := cache.Cache.GetRating(origin.Guild); origin.Guild != "" && r != nil {
+		rating = r.Rating
+	}
+
+	if !origin.NSFW && ratingRank(rating) > ratingRank(maxSafeRating) {
+		return maxSafeRating
+	}
+
+	return rating
+}
+
+func describeRating(id string) string {
+	if r := cache.Cache.GetRating(id); id != "" && r != nil {
+		return r.Rating
+	}
+	return "not set"
+}
+
+func handleRating(ctx context.Context, args []string, origin Origin, author string) (*discordgo.MessageSend, error) {
+	if len(args) == 0 {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```Gifs in this channel are rated %s (server: %s, channel: %s)```",
+				ratingFor(origin), describeRating(origin.Guild), describeRating(origin.Channel)),
+		}, nil
+	}
+
+	if len(args) != 2 || args[0] == "help" {
+		return &discordgo.MessageSend{
+			Content: ratingHelpMessage,
+		}, nil
+	}
+
+	if !origin.Admin {
+		return &discordgo.MessageSend{
+			Content: "```You need the Manage Server permission to change gif ratings```",
+		}, nil
+	}
+
+	rating := strings.ToLower(args[0])
+	if rating != "reset" && ratingRank(rating) < 0 {
+		return &discordgo.MessageSend{
+			Content: ratingHelpMessage,
+		}, nil
+	}
+
+	gifRating := cache.GifRating{
+		Author: author,
+		Guild:  origin.Guild,
+		Rating: rating,
+	}
+	switch args[1] {
+	case "server":
+		if origin.Guild == "" {
+			return &discordgo.MessageSend{
+				Content: "```Server ratings can only be set from a server channel```",
+			}, nil
+		}
+		gifRating.Id = origin.Guild
+	case "channel":
+		gifRating.Id = origin.Channel
+		gifRating.Channel = origin.Channel
+	default:
+		return &discordgo.MessageSend{
+			Content: ratingHelpMessage,
+		}, nil
+	}
+
+	if rating == "reset" {
+		if cache.Cache.GetRating(gifRating.Id) != nil {
+			err := cache.Cache.Delete(ctx, "rating-"+gifRating.Id)
+			if err != nil {
+				return nil, fmt.Errorf("error deleting gif rating from k8s: %w", err)
+			}
+		}
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```Cleared the %s gif rating```", args[1]),
+		}, nil
+	}
+
+	err := cache.Cache.SetRating(ctx, &gifRating)
+	if err != nil {
+		return nil, fmt.Errorf("error setting gif rating in k8s: %w", err)
+	}
+
+	msg := fmt.Sprintf("```Set the %s gif rating to %s", args[1], rating)
+	if ratingRank(rating) > ratingRank(maxSafeRating) {
+		msg += fmt.Sprintf(". Channels not marked NSFW will still be capped at %s", maxSafeRating)
+	}
+
+	return &discordgo.MessageSend{
+		Content: msg + "```",
+	}, nil
+}
@@ -0,0 +1,181 @@
+package giphy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/testutil"
+)
+
+func newRatingCache(ratings ...cache.GifRating) *cache.ConfigMapCache {
+	c := cache.NewInMemConfigMapCache(map[string]cache.Poll{}, map[string]cache.Reminder{})
+	for _, rating := range ratings {
+		configMap, _ := rating.ToConfigMap()
+		c.Store(configMap)
+	}
+	return c
+}
+
+func TestRatingFor(t *testing.T) {
+	tests := []struct {
+		name           string
+		cache          *cache.ConfigMapCache
+		origin         Origin
+		expectedRating string
+	}{
+		{
+			name:           "Test default rating",
+			cache:          newRatingCache(),
+			origin:         Origin{Guild: "guild", Channel: "channel"},
+			expectedRating: defaultRating,
+		},
+		{
+			name:           "Test guild rating",
+			cache:          newRatingCache(cache.GifRating{Id: "guild", Rating: "pg"}),
+			origin:         Origin{Guild: "guild", Channel: "channel"},
+			expectedRating: "pg",
+		},
+		{
+			name: "Test channel rating overrides guild rating",
+			cache: newRatingCache(
+				cache.GifRating{Id: "guild", Rating: "pg"},
+				cache.GifRating{Id: "channel", Channel: "channel", Rating: "g"},
+			),
+			origin:         Origin{Guild: "guild", Channel: "channel"},
+			expectedRating: "g",
+		},
+		{
+			name:           "Test r rating is capped outside of NSFW channels",
+			cache:          newRatingCache(cache.GifRating{Id: "channel", Channel: "channel", Rating: "r"}),
+			origin:         Origin{Guild: "guild", Channel: "channel"},
+			expectedRating: maxSafeRating,
+		},
+		{
+			name:           "Test r rating is allowed in NSFW channels",
+			cache:          newRatingCache(cache.GifRating{Id: "channel", Channel: "channel", Rating: "r"}),
+			origin:         Origin{Guild: "guild", Channel: "channel", NSFW: true},
+			expectedRating: "r",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache.Cache = tt.cache
+			if rating := ratingFor(tt.origin); rating != tt.expectedRating {
+				t.Errorf("expected rating '%s', but got '%s'", tt.expectedRating, rating)
+			}
+		})
+	}
+}
+
+func TestHandleRating(t *testing.T) {
+	tests := []struct {
+		name            string
+		commandStr      string
+		origin          Origin
+		ratings         []cache.GifRating
+		client          cache.KubeClient
+		expectedMessage string
+		expectedError   string
+	}{
+		{
+			name:            "Test show rating",
+			commandStr:      "!gif rating",
+			origin:          Origin{Guild: "guild", Channel: "channel"},
+			client:          &testutil.MockK8sClient{},
+			expectedMessage: "Gifs in this channel are rated g (server: not set, channel: not set)",
+		},
+		{
+			name:            "Test rating help",
+			commandStr:      "!gif rating help",
+			origin:          Origin{Guild: "guild", Channel: "channel"},
+			client:          &testutil.MockK8sClient{},
+			expectedMessage: ratingHelpMessage,
+		},
+		{
+			name:            "Test set rating without permission",
+			commandStr:      "!gif rating pg server",
+			origin:          Origin{Guild: "guild", Channel: "channel"},
+			client:          &testutil.MockK8sClient{},
+			expectedMessage: "You need the Manage Server permission",
+		},
+		{
+			name:            "Test set server rating",
+			commandStr:      "!gif rating PG server",
+			origin:          Origin{Guild: "guild", Channel: "channel", Admin: true},
+			client:          &testutil.MockK8sClient{},
+			expectedMessage: "Set the server gif rating to pg",
+		},
+		{
+			name:            "Test set channel rating above the safe cap",
+			commandStr:      "!gif rating r channel",
+			origin:          Origin{Guild: "guild", Channel: "channel", Admin: true},
+			client:          &testutil.MockK8sClient{},
+			expectedMessage: "will still be capped at pg-13",
+		},
+		{
+			name:            "Test set server rating from a DM",
+			commandStr:      "!gif rating pg server",
+			origin:          Origin{Channel: "channel", Admin: true},
+			client:          &testutil.MockK8sClient{},
+			expectedMessage: "Server ratings can only be set from a server channel",
+		},
+		{
+			name:            "Test invalid rating",
+			commandStr:      "!gif rating nc-17 server",
+			origin:          Origin{Guild: "guild", Channel: "channel", Admin: true},
+			client:          &testutil.MockK8sClient{},
+			expectedMessage: ratingHelpMessage,
+		},
+		{
+			name:            "Test reset channel rating",
+			commandStr:      "!gif rating reset channel",
+			origin:          Origin{Guild: "guild", Channel: "channel", Admin: true},
+			ratings:         []cache.GifRating{{Id: "channel", Channel: "channel", Rating: "pg"}},
+			client:          &testutil.MockK8sClient{},
+			expectedMessage: "Cleared the channel gif rating",
+		},
+		{
+			name:          "Test k8s error resetting rating",
+			commandStr:    "!gif rating reset channel",
+			origin:        Origin{Guild: "guild", Channel: "channel", Admin: true},
+			ratings:       []cache.GifRating{{Id: "channel", Channel: "channel", Rating: "pg"}},
+			client:        &testutil.MockErrorK8sClient{},
+			expectedError: testutil.ExpectedError,
+		},
+		{
+			name:          "Test k8s error setting rating",
+			commandStr:    "!gif rating pg server",
+			origin:        Origin{Guild: "guild", Channel: "channel", Admin: true},
+			client:        &testutil.MockErrorK8sClient{},
+			expectedError: testutil.ExpectedError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache.Cache = newRatingCache(tt.ratings...)
+			cache.Client = tt.client
+
+			msg, err := Get(context.Background(), newMessage(tt.commandStr), tt.origin)
+			if tt.expectedError != "" {
+				if err == nil {
+					t.Fatalf("expected error '%s' to be returned but was nil", tt.expectedError)
+				}
+				if !strings.Contains(err.Error(), tt.expectedError) {
+					t.Errorf("expected error '%s', but got error '%v'", tt.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error but got error: '%v'", err)
+			}
+			if !strings.Contains(msg.Content, tt.expectedMessage) {
+				t.Errorf("expected '%s' to be in '%s'", tt.expectedMessage, msg.Content)
+			}
+		})
+	}
+}
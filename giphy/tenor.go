@@ -0,0 +1,124 @@
+package giphy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/highsaltlevels/saltbot/lrucache"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+const (
+	tenorSearchEndpoint   = "https://tenor.googleapis.com/v2/search"
+	tenorFeaturedEndpoint = "https://tenor.googleapis.com/v2/featured"
+)
+
+var tenorToken string
+var tenorClient util.HttpClientInterface
+
+// Responses keyed on the endpoint and normalized query. TTL is set with TENOR_CACHE_TTL.
+var tenorCache *lrucache.Cache
+
+// Tenor's equivalent of each giphy rating
+var tenorContentFilters = map[string]string{
+	"g":     "high",
+	"pg":    "medium",
+	"pg-13": "low",
+	"r":     "off",
+}
+
+type TenorResult struct {
+	// Short link to the gif's page, which discord embeds
+	Url     string `json:"url"`
+	ItemUrl string `json:"itemurl"`
+}
+
+type TenorResponse struct {
+	Results []TenorResult `json:"results"`
+}
+
+type tenorProvider struct{}
+
+func (t *tenorProvider) Name() string {
+	return "tenor"
+}
+
+func (t *tenorProvider) Search(ctx context.Context, query, rating string) ([]Gif, error) {
+	return fetchTenor(ctx, tenorSearchEndpoint, url.Values{"q": {query}, "contentfilter": {tenorContentFilters[rating]}}, true)
+}
+
+func (t *tenorProvider) Random(ctx context.Context, tag, rating string) ([]Gif, error) {
+	params := url.Values{
+		"q":             {tag},
+		"contentfilter": {tenorContentFilters[rating]},
+		"random":        {"true"},
+		"limit":         {"1"},
+	}
+	return fetchTenor(ctx, tenorSearchEndpoint, params, false)
+}
+
+func (t *tenorProvider) Trending(ctx context.Context, rating string) ([]Gif, error) {
+	return fetchTenor(ctx, tenorFeaturedEndpoint, url.Values{"contentfilter": {tenorContentFilters[rating]}}, true)
+}
+
+func fetchTenor(ctx context.Context, endpoint string, params url.Values, cacheable bool) ([]Gif, error) {
+	key := cacheKey(endpoint, params)
+	if cacheable {
+		if cached, ok := tenorCache.Get(key); ok {
+			return cached.([]Gif), nil
+		}
+	}
+
+	query := url.Values{}
+	for k, v := range params {
+		query[k] = v
+	}
+	query.Set("key", tenorToken)
+	query.Set("media_filter", "gif")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := tenorClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenor gif: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code %d from tenor", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenor response: %w", err)
+	}
+
+	var tenor TenorResponse
+	err = json.Unmarshal(body, &tenor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tenor response: %w", err)
+	}
+
+	gifs := make([]Gif, 0, len(tenor.Results))
+	for _, result := range tenor.Results {
+		gifUrl := result.Url
+		if gifUrl == "" {
+			gifUrl = result.ItemUrl
+		}
+		if gifUrl != "" {
+			gifs = append(gifs, Gif{Url: gifUrl})
+		}
+	}
+
+	if cacheable {
+		tenorCache.Add(key, gifs)
+	}
+	return gifs, nil
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/config"
+	"github.com/highsaltlevels/saltbot/giphy"
+	"github.com/highsaltlevels/saltbot/jeopardy"
+	"github.com/highsaltlevels/saltbot/poll"
+	"github.com/highsaltlevels/saltbot/ratelimit"
+	"github.com/highsaltlevels/saltbot/reminder"
+	"github.com/highsaltlevels/saltbot/trivia"
+	"github.com/highsaltlevels/saltbot/util"
+	"github.com/highsaltlevels/saltbot/waifu"
+	"github.com/highsaltlevels/saltbot/youtube"
+)
+
+// A command saltbot understands. Name and Aliases don't include the prefix.
+// Run returns nil if it already answered on its own. Admin commands are only
+// for bot admins, commands without a cooldown can be used as often as anyone
+// likes, and commands without a timeout get CommandTimeout.
+type Command struct {
+	Name     string
+	Aliases  []string
+	Admin    bool
+	Cooldown ratelimit.Cooldown
+	Timeout  time.Duration
+	Run      func(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) (*discordgo.MessageSend, error)
+}
+
+// Limits commands that call out to apis or that anyone could spam. Its stats
+// count how often each command was allowed and limited.
+var Limiter = ratelimit.New(util.SystemClock)
+
+// Where commands get the time from, for cooldowns, games and expiries.
+var clock = util.SystemClock
+
+// Run commands going by a different clock, like the console's, which can be
+// skipped ahead.
+func SetClock(c util.Clock) {
+	clock = c
+	Limiter = ratelimit.New(c)
+}
+
+var Commands = []Command{
+	{
+		Name:    "help",
+		Aliases: []string{"h"},
+		Run: func(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+			return GetHelpMsg(), nil
+		},
+	},
+	{
+		Name:    "waifu",
+		Aliases: []string{"w"},
+		Cooldown: ratelimit.Cooldown{
+			User:    ratelimit.Rate{Burst: 3, Every: 20 * time.Second},
+			Channel: ratelimit.Rate{Burst: 5, Every: 10 * time.Second},
+		},
+		Run: func(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+			return waifu.Get(ctx, m)
+		},
+	},
+	{
+		Name:    "jeopardy",
+		Aliases: []string{"j"},
+		Cooldown: ratelimit.Cooldown{
+			User:    ratelimit.Rate{Burst: 3, Every: 20 * time.Second},
+			Channel: ratelimit.Rate{Burst: 5, Every: 10 * time.Second},
+		},
+		Run: func(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+			return jeopardy.Handle(ctx, s, m, isAdmin(ctx, s, m), clock)
+		},
+	},
+	{
+		Name:    "trivia",
+		Aliases: []string{"t"},
+		Cooldown: ratelimit.Cooldown{
+			User: ratelimit.Rate{Burst: 3, Every: 10 * time.Second},
+		},
+		Run: func(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+			return trivia.Handle(ctx, s, m, clock)
+		},
+	},
+	{
+		Name:    "whisper",
+		Aliases: []string{"pm"},
+		Cooldown: ratelimit.Cooldown{
+			User: ratelimit.Rate{Burst: 2, Every: time.Minute},
+		},
+		Run: func(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+			SendDM(ctx, s, m)
+			return nil, nil
+		},
+	},
+	{
+		Name:    "gif",
+		Aliases: []string{"g"},
+		Cooldown: ratelimit.Cooldown{
+			User:    ratelimit.Rate{Burst: 3, Every: 20 * time.Second},
+			Channel: ratelimit.Rate{Burst: 5, Every: 10 * time.Second},
+			Guild:   ratelimit.Rate{Burst: 20, Every: 3 * time.Second},
+		},
+		Run: func(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+			return giphy.Get(ctx, m, gifOrigin(ctx, s, m))
+		},
+	},
+	{
+		Name:    "youtube",
+		Aliases: []string{"y"},
+		Cooldown: ratelimit.Cooldown{
+			User:    ratelimit.Rate{Burst: 2, Every: 30 * time.Second},
+			Channel: ratelimit.Rate{Burst: 4, Every: 15 * time.Second},
+			Guild:   ratelimit.Rate{Burst: 10, Every: 10 * time.Second},
+		},
+		// A search and then the video details
+		Timeout: time.Minute,
+		Run: func(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+			return youtube.Get(ctx, m, isAdmin(ctx, s, m))
+		},
+	},
+	{
+		Name:    "remind",
+		Aliases: []string{"r"},
+		Cooldown: ratelimit.Cooldown{
+			User: ratelimit.Rate{Burst: 5, Every: time.Minute},
+		},
+		Run: func(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+			return reminder.Handle(ctx, m, clock)
+		},
+	},
+	{
+		Name:    "poll",
+		Aliases: []string{"p"},
+		Cooldown: ratelimit.Cooldown{
+			User: ratelimit.Rate{Burst: 3, Every: time.Minute},
+		},
+		Run: func(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+			return poll.Create(ctx, m, clock)
+		},
+	},
+	{
+		Name:    "vote",
+		Aliases: []string{"v"},
+		Cooldown: ratelimit.Cooldown{
+			User: ratelimit.Rate{Burst: 5, Every: 10 * time.Second},
+		},
+		Run: func(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+			return poll.Vote(ctx, m)
+		},
+	},
+}
+
+// Config needs every command's name, so it's added once Commands exists.
+func init() {
+	Commands = append(Commands, Command{
+		Name:  "config",
+		Admin: true,
+		Run: func(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+			return config.Handle(ctx, s, m, isAdmin(ctx, s, m), CommandNames())
+		},
+	})
+}
+
+// Find a command by its name or an alias.
+func lookup(name string) *Command {
+	for i, command := range Commands {
+		if command.Name == name {
+			return &Commands[i]
+		}
+		for _, alias := range command.Aliases {
+			if alias == name {
+				return &Commands[i]
+			}
+		}
+	}
+	return nil
+}
+
+func CommandNames() []string {
+	names := make([]string, 0, len(Commands))
+	for _, command := range Commands {
+		names = append(names, command.Name)
+	}
+	return names
+}
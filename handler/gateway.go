@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Set while saltbot has a session on discord's gateway
+var connected atomic.Bool
+
+// Whether saltbot is connected to discord and can get messages.
+func Connected() bool {
+	return connected.Load()
+}
+
+// Add these before opening the session, or the first Ready can be missed.
+func OnReady(s *discordgo.Session, r *discordgo.Ready) {
+	connected.Store(true)
+	slog.Info("connected to discord", "session", r.SessionID)
+}
+
+func OnResumed(s *discordgo.Session, r *discordgo.Resumed) {
+	connected.Store(true)
+	slog.Info("resumed discord session")
+}
+
+// discordgo reconnects on its own, and OnResumed or OnReady sees it back.
+func OnDisconnect(s *discordgo.Session, d *discordgo.Disconnect) {
+	connected.Store(false)
+	slog.Warn("disconnected from discord")
+}
@@ -1,35 +1,50 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"math/rand"
+	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/google/uuid"
 
+	"github.com/highsaltlevels/saltbot/config"
 	"github.com/highsaltlevels/saltbot/giphy"
-	"github.com/highsaltlevels/saltbot/jeopardy"
-	"github.com/highsaltlevels/saltbot/poll"
-	"github.com/highsaltlevels/saltbot/reminder"
-	"github.com/highsaltlevels/saltbot/youtube"
+	"github.com/highsaltlevels/saltbot/logging"
+	"github.com/highsaltlevels/saltbot/ratelimit"
+	"github.com/highsaltlevels/saltbot/trivia"
+	"github.com/highsaltlevels/saltbot/util"
 )
 
 const help string = ("```Good salty day to you! Here's a list of commands that I understand:\n\n" +
 	"!help (!h):     Shows this help message.\n" +
 	"!jeopardy (!j): Recieve a category with 5 questions and answers. The answers\n" +
-	"                are marked as spolers and are not revealed until you click them\n" +
+	"                are marked as spolers and are not revealed until you click them.\n" +
+	"                Type \"!jeopardy play\" to play a game in the channel instead,\n" +
+	"                and \"!jeopardy help\" for category search, leaderboards and\n" +
+	"                the daily Final Jeopardy.\n" +
+	"!trivia (!t):   Play multiple choice trivia in the channel. Type \"!trivia help\"\n" +
+	"                for categories and other sources.\n" +
 	"!whipser (!pm): Get a salty DM from SaltBot. This can be used as a playground\n" +
 	"                for experiencing all of the salty features.\n" +
 	"!gif (!g):      Type !gif followed by keywords to get a cool gif. For example\n" +
-	"                \"!gif dog\".\n" +
+	"                \"!gif dog\". Also try \"!gif random dog\", \"!gif trending\" and\n" +
+	"                \"!gif say good morning\". Use \"-a\" to list every result and\n" +
+	"                \"-p 2\" for the next page. Type \"!gif rating help\" to set a\n" +
+	"                content rating.\n" +
 	"!waifu (!w):    Get a picture of a randomized waifu.\n" +
 	"!poll (!p):     Type \"!poll help\" for detailed information\n" +
 	"!vote (!v):     Vote in a poll. Type \"!vote <poll id> <poll choice> to vote\n" +
 	"!youtube (!y):  Get a youtube search result. Use the \"-i\" parameter to specify\n" +
 	"                an index. For example: \"!y dog -i 3\" to get the 3rd query result.\n" +
-	"!remind (!r):   Set a reminder. Type \"remind help \" for detailed information\n\n" +
+	"                Use \"-a\" to list the top results to pick from. Type\n" +
+	"                \"!youtube subscribe help\" to get notified of new uploads.\n" +
+	"!remind (!r):   Set a reminder. Type \"remind help \" for detailed information\n" +
+	"!config:        Server settings like the command prefix and who can use\n" +
+	"                which commands. Only for bot admins. Type \"!config help\"\n" +
+	"                for details.\n\n" +
 	"Check me out on github: https://github.com/highsaltlevels/saltbot```")
 
 func GetHelpMsg() *discordgo.MessageSend {
@@ -38,106 +53,207 @@ func GetHelpMsg() *discordgo.MessageSend {
 	}
 }
 
-func GetWaifu() *discordgo.MessageSend {
-	num := rand.Intn(99999)
-	url := fmt.Sprintf("https://www.thiswaifudoesnotexist.net/example-%d.jpg", num)
-	return &discordgo.MessageSend{
-		Content: "Here's a waifu for you!",
-		Embeds: []*discordgo.MessageEmbed{
-			&discordgo.MessageEmbed{
-				URL:         url,
-				Type:        discordgo.EmbedTypeImage,
-				Title:       "Here's the sauce site",
-				Description: "Check out the link above to go to the site that makes this feature possible",
-				Image: &discordgo.MessageEmbedImage{
-					URL: url,
-				},
-			},
-		},
-	}
-}
-
-func SendDM(s *discordgo.Session, m *discordgo.MessageCreate) {
-	channel, err := s.UserChannelCreate(m.Author.ID)
+// DM the author. The requests give up when ctx is done.
+func SendDM(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) {
+	channel, err := s.UserChannelCreate(m.Author.ID, discordgo.WithContext(ctx))
 	if err != nil {
-		s.ChannelMessageSendComplex(m.ChannelID, CreateError(err))
+		s.ChannelMessageSendComplex(m.ChannelID, CreateError(ctx, err), discordgo.WithContext(ctx))
 		return
 	}
 
 	msg := fmt.Sprintf("```Hello %s! You can talk to me here (where no one can hear our mutual salt)```", m.Author.Username)
-	s.ChannelMessageSend(channel.ID, msg)
+	s.ChannelMessageSend(channel.ID, msg, discordgo.WithContext(ctx))
 }
 
-func CreateError(err error) *discordgo.MessageSend {
-	// Log the exact error but return a generic error message.
-	errId := uuid.NewString()
-	log.Printf("error with id: %s: %v", errId, err)
+// Let the user know an integration is down rather than handing them an error id.
+func CreateUnavailable(ctx context.Context, err *util.UnavailableError) *discordgo.MessageSend {
+	logging.From(ctx).Warn("integration is unavailable", "service", err.Service, "error", err)
+	return &discordgo.MessageSend{
+		Content: fmt.Sprintf("```%s is down right now, try again in a bit```", err.Service),
+	}
+}
+
+// Whether the author is a bot admin, either through Manage Server or one of
+// the guild's admin roles.
+func isAdmin(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) bool {
+	return permissions(ctx, s, m)&discordgo.PermissionManageServer != 0 || config.AdminRole(m.GuildID, memberRoles(m))
+}
+
+func permissions(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) int64 {
+	perms, err := s.UserChannelPermissions(m.Author.ID, m.ChannelID, discordgo.WithContext(ctx))
+	if err != nil {
+		slog.Warn("failed to get permissions", "user", m.Author.ID, "channel", m.ChannelID, "error", err)
+		return 0
+	}
+
+	return perms
+}
+
+func memberRoles(m *discordgo.MessageCreate) []string {
+	if m.Member == nil {
+		return nil
+	}
+	return m.Member.Roles
+}
+
+// Whether the channel the message was sent in is marked NSFW.
+func isNSFW(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) bool {
+	channel, err := s.Channel(m.ChannelID, discordgo.WithContext(ctx))
+	if err != nil {
+		slog.Warn("failed to get channel", "channel", m.ChannelID, "error", err)
+		return false
+	}
+
+	return channel.NSFW
+}
+
+func gifOrigin(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) giphy.Origin {
+	return giphy.Origin{
+		Guild:   m.GuildID,
+		Channel: m.ChannelID,
+		NSFW:    isNSFW(ctx, s, m),
+		Admin:   isAdmin(ctx, s, m),
+	}
+}
+
+// Log the exact error but return a generic error message. The id is the
+// correlation id, so it finds everything logged while handling the command.
+func CreateError(ctx context.Context, err error) *discordgo.MessageSend {
+	if logging.ID(ctx) == "" {
+		ctx = logging.Start(ctx)
+	}
+	errId := logging.ID(ctx)
+	logging.From(ctx).Error("unexpected error", "error", err)
 	return &discordgo.MessageSend{
 		Content: fmt.Sprintf("```Unexpected error with id: %s :(```", errId),
 	}
 }
 
+// Registered with discordgo, which needs the concrete session type. Messages
+// are handled by the workers, and dropped when too many are waiting.
 func OnMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if !Workers.Submit(func() { HandleMessage(discordSession{s}, m) }) {
+		slog.Warn("too busy, dropped message", "message", m.ID, "user", m.Author.ID)
+	}
+}
+
+func HandleMessage(s SessionInterface, m *discordgo.MessageCreate) {
 	// Ignore messages created by saltbot
-	if m.Author.ID == s.State.User.ID {
+	if m.Author.ID == s.StateUser().ID {
 		return
 	}
 
-	// Get the command and retrieve its associated message.
-	var message *discordgo.MessageSend
-	var err error
-	command := strings.Split(m.Content, " ")[0]
-	switch command {
-	case "!help":
-		message = GetHelpMsg()
-	case "!h":
-		message = GetHelpMsg()
-	case "!waifu":
-		message = GetWaifu()
-	case "!w":
-		message = GetWaifu()
-	case "!jeopardy":
-		message, err = jeopardy.Get()
-	case "!j":
-		message, err = jeopardy.Get()
-	case "!whisper":
-		SendDM(s, m)
-		return
-	case "!pm":
-		SendDM(s, m)
-		return
-	case "!gif":
-		message, err = giphy.Get(m.Content)
-	case "!g":
-		message, err = giphy.Get(m.Content)
-	case "!youtube":
-		message, err = youtube.Get(m.Content)
-	case "!y":
-		message, err = youtube.Get(m.Content)
-	case "!remind":
-		message, err = reminder.Handle(m)
-	case "!r":
-		message, err = reminder.Handle(m)
-	case "!poll":
-		message, err = poll.Create(m)
-	case "!p":
-		message, err = poll.Create(m)
-	case "!vote":
-		message, err = poll.Vote(m)
-	case "!v":
-		message, err = poll.Vote(m)
-	// If saltbot doesn't know the command, do nothing
-	default:
+	// Get the command. "!config" always works, so a guild can't lock itself
+	// out with a bad prefix.
+	prefix := config.Prefix(m.GuildID)
+	word := strings.Split(m.Content, " ")[0]
+	var command *Command
+	if word == config.DefaultPrefix+"config" {
+		command = lookup("config")
+	} else if strings.HasPrefix(word, prefix) {
+		command = lookup(strings.TrimPrefix(word, prefix))
+	}
+
+	// If saltbot doesn't know the command, it might be an answer to a
+	// trivia question. Otherwise do nothing
+	if command == nil {
+		if message := trivia.Answer(m); message != nil {
+			send(context.Background(), s, m.ChannelID, message)
+		}
 		return
 	}
 
+	// Everything logged while handling the command shares a correlation id
+	start := time.Now()
+	ctx := logging.Start(context.Background(),
+		"guild", m.GuildID, "channel", m.ChannelID, "user", m.Author.ID, "command", command.Name)
+	message, outcome, err := dispatch(ctx, s, m, command, prefix)
+
 	// If there was an error, send an error message instead.
+	var unavailable *util.UnavailableError
+	if errors.As(err, &unavailable) {
+		message = CreateUnavailable(ctx, unavailable)
+		outcome = "unavailable"
+	} else if err != nil {
+		message = CreateError(ctx, err)
+		outcome = errorOutcome(err)
+	}
+
+	if message != nil {
+		send(ctx, s, m.ChannelID, message)
+	}
+	latency := time.Since(start)
+	commandsHandled.WithLabelValues(command.Name, outcome).Inc()
+	commandSeconds.WithLabelValues(command.Name).Observe(latency.Seconds())
+	logging.From(ctx).Info("handled command", "outcome", outcome, "latency_ms", latency.Milliseconds())
+}
+
+// Check whether the command can be used and run it. The outcome says which
+// happened, for logging.
+func dispatch(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate, command *Command, prefix string) (*discordgo.MessageSend, string, error) {
+	if !config.Enabled(m.GuildID, command.Name) {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```%s%s is turned off in this server```", prefix, command.Name),
+		}, "disabled", nil
+	}
+
+	if denied := authorize(ctx, s, m, command); denied != "" {
+		return &discordgo.MessageSend{
+			Content: denied,
+		}, "denied", nil
+	}
+
+	wait, notify := Limiter.Take(command.Name, command.Cooldown, m.Author.ID, m.ChannelID, m.GuildID)
+	if wait > 0 {
+		// Only say so once, so that being limited doesn't become spam too
+		if !notify {
+			return nil, "limited", nil
+		}
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```Slow down, try again in %ds```", ratelimit.Seconds(wait)),
+		}, "limited", nil
+	}
+
+	message, err := run(ctx, s, withDefaultPrefix(m, prefix), command)
+	return message, "ok", err
+}
+
+func send(ctx context.Context, s SessionInterface, channel string, message *discordgo.MessageSend) {
+	_, err := s.ChannelMessageSendComplex(channel, message)
 	if err != nil {
-		message = CreateError(err)
+		logging.From(ctx).Error("failed to send message", "error", err)
+	}
+}
+
+// Commands parse their arguments expecting "!", so swap in the default
+// prefix for guilds that use their own. The original message isn't changed.
+func withDefaultPrefix(m *discordgo.MessageCreate, prefix string) *discordgo.MessageCreate {
+	if prefix == config.DefaultPrefix || !strings.HasPrefix(m.Content, prefix) {
+		return m
+	}
+
+	message := *m.Message
+	message.Content = config.DefaultPrefix + strings.TrimPrefix(m.Content, prefix)
+	return &discordgo.MessageCreate{Message: &message}
+}
+
+func OnInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !Workers.Submit(func() { HandleInteraction(discordSession{s}, i) }) {
+		slog.Warn("too busy, dropped interaction", "interaction", i.ID)
+	}
+}
+
+// Answer button presses on trivia questions.
+func HandleInteraction(s SessionInterface, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+	if !strings.HasPrefix(i.MessageComponentData().CustomID, trivia.ButtonPrefix) {
+		return
 	}
 
-	_, err = s.ChannelMessageSendComplex(m.ChannelID, message)
+	err := s.InteractionRespond(i.Interaction, trivia.OnButton(i))
 	if err != nil {
-		log.Printf("unexpected error sending message: %v\n", err)
+		slog.Error("failed to respond to interaction", "interaction", i.ID, "error", err)
 	}
 }
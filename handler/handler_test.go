@@ -0,0 +1,355 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/giphy"
+	"github.com/highsaltlevels/saltbot/testutil"
+	"github.com/highsaltlevels/saltbot/trivia"
+	"github.com/highsaltlevels/saltbot/util"
+	"github.com/highsaltlevels/saltbot/waifu"
+	"github.com/highsaltlevels/saltbot/youtube"
+)
+
+// Stands in for every third party api so that commands run end to end
+// without the network.
+type MockClient struct{}
+
+func (c MockClient) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+func (c MockClient) Do(req *http.Request) (*http.Response, error) {
+	status := http.StatusOK
+	var body interface{}
+	switch {
+	case req.URL.Host == "api.giphy.com" && req.URL.Query().Get("q") == "broken":
+		status = http.StatusNotFound
+	case req.URL.Host == "api.giphy.com":
+		body = map[string]interface{}{"data": []map[string]string{{"url": "https://giphy.com/gifs/salty"}}}
+	case req.URL.Path == "/youtube/v3/search":
+		body = map[string]interface{}{"items": []map[string]interface{}{{
+			"id":      map[string]string{"videoId": "salty"},
+			"snippet": map[string]string{"title": "Salty video", "channelTitle": "Salt"},
+		}}}
+	case req.URL.Path == "/youtube/v3/videos":
+		body = map[string]interface{}{"items": []interface{}{}}
+	case req.URL.Host == "www.thiswaifudoesnotexist.net":
+	default:
+		status = http.StatusNotFound
+	}
+
+	data, _ := json.Marshal(body)
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"image/jpeg"}},
+		Body:       io.NopCloser(strings.NewReader(string(data))),
+		Request:    req,
+	}, nil
+}
+
+func newMessage(content, author string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			Content:   content,
+			ChannelID: "channel",
+			GuildID:   "guild",
+			Author: &discordgo.User{
+				ID:       author,
+				Username: "user" + author,
+			},
+		},
+	}
+}
+
+// The integrations and the cache are set up once, before any commands run,
+// since commands and their background work read them from other goroutines.
+// Tests that change a guild's settings use a guild of their own.
+func TestMain(m *testing.M) {
+	giphy.SetClient(MockClient{})
+	waifu.SetClient(MockClient{})
+	youtube.SetClient(MockClient{})
+	cache.Cache = cache.NewInMemConfigMapCache(map[string]cache.Poll{}, map[string]cache.Reminder{})
+	cache.Client = testutil.MockK8sClient{}
+	os.Exit(m.Run())
+}
+
+// Sets up a session with fresh cooldowns for the rest of the test.
+func newSession(t *testing.T) *testutil.RecordingSession {
+	SetClock(util.SystemClock)
+	return &testutil.RecordingSession{
+		BotID:       "bot",
+		Permissions: map[string]int64{"admin:channel": discordgo.PermissionManageServer},
+		Channels:    map[string]*discordgo.Channel{"channel": {ID: "channel"}},
+	}
+}
+
+func TestHandleMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		author   string
+		channel  string
+		contains string
+	}{
+		{name: "Test help", command: "!help", contains: "Here's a list of commands"},
+		{name: "Test help alias", command: "!h", contains: "Here's a list of commands"},
+		{name: "Test waifu", command: "!waifu", contains: "Here's a waifu for you!"},
+		{name: "Test jeopardy", command: "!j", contains: "The Category is:"},
+		{name: "Test jeopardy help", command: "!jeopardy help", contains: "Jeopardy commands"},
+		{name: "Test trivia help", command: "!t help", contains: "Trivia commands"},
+		{name: "Test gif", command: "!g dog", contains: "https://giphy.com/gifs/salty"},
+		{name: "Test gif error", command: "!gif broken", contains: "Unexpected error with id"},
+		{name: "Test youtube", command: "!y salt", contains: "Salty video"},
+		{name: "Test youtube quota as a user", command: "!youtube quota", contains: "Only server admins"},
+		{name: "Test youtube quota as an admin", command: "!youtube quota", author: "admin", contains: "YouTube quota for"},
+		{name: "Test remind help", command: "!r help", contains: "Set a reminder"},
+		{name: "Test remind", command: "!remind set stretch in 2 hours", contains: "Created reminder with id"},
+		{name: "Test poll help", command: "!p help", contains: "How to set a poll"},
+		{name: "Test poll", command: "!poll Salty? ; yes ; no ; ends in 2 hours", contains: "Salty?"},
+		{name: "Test vote help", command: "!v", contains: "To vote on a poll"},
+		{name: "Test whisper", command: "!pm", channel: "dm-1", contains: "Hello user1!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSession(t)
+			author := tt.author
+			if author == "" {
+				author = "1"
+			}
+			channel := tt.channel
+			if channel == "" {
+				channel = "channel"
+			}
+
+			HandleMessage(s, newMessage(tt.command, author))
+			if len(s.Sent) != 1 {
+				t.Fatalf("expected 1 message, but got %d", len(s.Sent))
+			}
+
+			sent := s.Sent[0]
+			content := sent.Message.Content
+			for _, embed := range sent.Message.Embeds {
+				content += embed.Title + embed.Description
+			}
+			if sent.ChannelID != channel || !strings.Contains(content, tt.contains) {
+				t.Errorf("expected '%s' in %s, but got '%s' in %s", tt.contains, channel, content, sent.ChannelID)
+			}
+		})
+	}
+}
+
+func TestHandleMessageIgnores(t *testing.T) {
+	s := newSession(t)
+	HandleMessage(s, newMessage("!help", "bot"))
+	HandleMessage(s, newMessage("just chatting", "1"))
+	HandleMessage(s, newMessage("!unknown", "1"))
+	if len(s.Sent) != 0 {
+		t.Errorf("expected no messages, but got %+v", s.Sent)
+	}
+}
+
+func TestTriviaGame(t *testing.T) {
+	trivia.AnswerTime = time.Hour
+	s := newSession(t)
+	defer trivia.Stop("channel", "!trivia")
+
+	HandleMessage(s, newMessage("!trivia play --count 1", "1"))
+	sent := s.Flush()
+	if len(sent) != 1 || len(sent[0].Message.Components) != 1 {
+		t.Fatalf("expected a question with buttons, but got %+v", sent)
+	}
+
+	button := sent[0].Message.Components[0].(discordgo.ActionsRow).Components[0].(discordgo.Button)
+	HandleInteraction(s, &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type:      discordgo.InteractionMessageComponent,
+			ChannelID: "channel",
+			Data:      discordgo.MessageComponentInteractionData{CustomID: button.CustomID},
+			Member:    &discordgo.Member{User: &discordgo.User{ID: "1", Username: "user1"}},
+		},
+	})
+	if len(s.Responses) != 1 || !strings.Contains(s.Responses[0].Data.Content, "You picked") {
+		t.Errorf("expected the pick to be acknowledged, but got %+v", s.Responses)
+	}
+
+	HandleMessage(s, newMessage("!trivia stop", "1"))
+	sent = s.Flush()
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Message.Content, "Game over!") {
+		t.Errorf("expected the game to stop, but got %+v", sent)
+	}
+}
+
+func TestGuildConfig(t *testing.T) {
+	s := newSession(t)
+	send := func(content, author string) string {
+		m := newMessage(content, author)
+		m.GuildID = "configured"
+		HandleMessage(s, m)
+		sent := s.Flush()
+		if len(sent) != 1 {
+			return ""
+		}
+		text := sent[0].Message.Content
+		for _, embed := range sent[0].Message.Embeds {
+			text += embed.Title + embed.Description
+		}
+		return text
+	}
+
+	if msg := send("!config set prefix ?", "1"); !strings.Contains(msg, "only for bot admins") {
+		t.Errorf("expected members to be turned away, but got '%s'", msg)
+	}
+	if msg := send("!config set prefix ?", "admin"); !strings.Contains(msg, "Updated prefix") {
+		t.Fatalf("expected the prefix to change, but got '%s'", msg)
+	}
+	if msg := send("!help", "1"); msg != "" {
+		t.Errorf("expected the old prefix to be ignored, but got '%s'", msg)
+	}
+	if msg := send("?help", "1"); !strings.Contains(msg, "Here's a list of commands") {
+		t.Errorf("expected the new prefix to work, but got '%s'", msg)
+	}
+	if msg := send("?r help", "1"); !strings.Contains(msg, "Set a reminder") {
+		t.Errorf("expected aliases to take the new prefix, but got '%s'", msg)
+	}
+
+	send("!config set disabled gif", "admin")
+	if msg := send("?g dog", "1"); msg != "```?gif is turned off in this server```" {
+		t.Errorf("expected gif to be off, but got '%s'", msg)
+	}
+	if msg := send("?y salt", "1"); !strings.Contains(msg, "Salty video") {
+		t.Errorf("expected other commands to still work, but got '%s'", msg)
+	}
+
+	// Turning on only some commands doesn't lock the server out of config
+	if msg := send("!config set enabled help", "admin"); !strings.Contains(msg, "Updated enabled") {
+		t.Fatalf("expected only help to be enabled, but got '%s'", msg)
+	}
+	if msg := send("?y salt", "1"); msg != "```?youtube is turned off in this server```" {
+		t.Errorf("expected youtube to be off, but got '%s'", msg)
+	}
+	if msg := send("!config reset enabled", "admin"); !strings.Contains(msg, "Updated enabled") {
+		t.Errorf("expected config to still work, but got '%s'", msg)
+	}
+
+	// Other servers keep the defaults
+	HandleMessage(s, newMessage("!g dog", "1"))
+	if sent := s.Flush(); len(sent) != 1 || !strings.Contains(sent[0].Message.Content, "giphy") {
+		t.Errorf("expected gif to work in other servers, but got %+v", sent)
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	s := newSession(t)
+	s.Permissions["everyone:channel"] = discordgo.PermissionMentionEveryone
+	send := func(content, author string, roles ...string) string {
+		m := newMessage(content, author)
+		m.GuildID = "policed"
+		m.Member = &discordgo.Member{Roles: roles}
+		HandleMessage(s, m)
+		sent := s.Flush()
+		if len(sent) != 1 {
+			return ""
+		}
+		return sent[0].Message.Content
+	}
+
+	send("!config set admin-roles <@&1000>", "admin")
+	send("!config set remind.roles <@&2000>", "admin")
+	send("!config set denied-users <@3>", "admin")
+
+	tests := []struct {
+		name     string
+		command  string
+		author   string
+		roles    []string
+		contains string
+	}{
+		{name: "Test admin role can use config", command: "!config get prefix", author: "1", roles: []string{"1000"}, contains: "prefix"},
+		{name: "Test members can't use config", command: "!config get prefix", author: "1", contains: "!config is only for bot admins"},
+		{name: "Test missing role", command: "!r help", author: "1", contains: "You don't have a role that can use !remind"},
+		{name: "Test role", command: "!r help", author: "1", roles: []string{"2000"}, contains: "Set a reminder"},
+		{name: "Test denied user", command: "!help", author: "3", contains: "You can't use saltbot in this server"},
+		{name: "Test poll with everyone", command: "!poll @everyone lunch? ; yes ; no ; ends in 2 hours", author: "1", contains: "permission to mention everyone"},
+		{name: "Test poll with everyone permission", command: "!poll @here lunch? ; yes ; no ; ends in 2 hours", author: "everyone", contains: "lunch?"},
+		{name: "Test poll with everyone as admin", command: "!poll @everyone lunch? ; yes ; no ; ends in 2 hours", author: "admin", contains: "lunch?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if msg := send(tt.command, tt.author, tt.roles...); !strings.Contains(msg, tt.contains) {
+				t.Errorf("expected '%s', but got '%s'", tt.contains, msg)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	s := newSession(t)
+	clock := util.NewFakeClock(time.Now())
+	SetClock(clock)
+
+	for i := 0; i < 3; i++ {
+		HandleMessage(s, newMessage("!gif dog", "1"))
+	}
+	if sent := s.Flush(); len(sent) != 3 {
+		t.Fatalf("expected 3 gifs, but got %+v", sent)
+	}
+
+	HandleMessage(s, newMessage("!gif dog", "1"))
+	HandleMessage(s, newMessage("!g dog", "1"))
+	sent := s.Flush()
+	if len(sent) != 1 || sent[0].Message.Content != "```Slow down, try again in 20s```" {
+		t.Fatalf("expected to be told to slow down once, but got %+v", sent)
+	}
+
+	HandleMessage(s, newMessage("!help", "1"))
+	if sent := s.Flush(); len(sent) != 1 || !strings.Contains(sent[0].Message.Content, "Here's a list of commands") {
+		t.Errorf("expected commands without a cooldown to work, but got %+v", sent)
+	}
+
+	clock.Advance(20 * time.Second)
+	HandleMessage(s, newMessage("!gif dog", "1"))
+	if sent := s.Flush(); len(sent) != 1 || !strings.Contains(sent[0].Message.Content, "giphy") {
+		t.Errorf("expected a gif after waiting, but got %+v", sent)
+	}
+
+	stats := Limiter.Stats()["gif"]
+	if stats.Allowed != 4 || stats.Limited != 2 || stats.Notified != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	expected := `# HELP saltbot_ratelimit_notified_total Limited commands that got told to wait.
+# TYPE saltbot_ratelimit_notified_total counter
+saltbot_ratelimit_notified_total{command="gif"} 1
+saltbot_ratelimit_notified_total{command="help"} 0
+# HELP saltbot_ratelimit_total Commands allowed and limited by the cooldowns.
+# TYPE saltbot_ratelimit_total counter
+saltbot_ratelimit_total{command="gif",result="allowed"} 4
+saltbot_ratelimit_total{command="gif",result="limited"} 2
+saltbot_ratelimit_total{command="help",result="allowed"} 1
+saltbot_ratelimit_total{command="help",result="limited"} 0
+`
+	err := promtest.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(expected),
+		"saltbot_ratelimit_total", "saltbot_ratelimit_notified_total")
+	if err != nil {
+		t.Errorf("expected all three counters to be exported: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/highsaltlevels/saltbot/metrics"
+)
+
+var (
+	commandsHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "saltbot_commands_total",
+		Help: "Commands handled, by command and outcome.",
+	}, []string{"command", "outcome"})
+
+	commandSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "saltbot_command_duration_seconds",
+		Help:    "How long commands took, from dispatch to the reply being sent.",
+		Buckets: metrics.DefaultBuckets,
+	}, []string{"command"})
+
+	// Registered here rather than in StartWorkers so that starting the
+	// workers again doesn't register them twice
+	workerQueueLength = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "saltbot_worker_queue_length",
+		Help: "Events waiting for a worker.",
+	}, func() float64 {
+		if p := metered.Load(); p != nil {
+			return float64(p.Queued())
+		}
+		return 0
+	})
+
+	workerDropped = promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "saltbot_worker_dropped_total",
+		Help: "Events dropped because the queue was full.",
+	}, func() float64 {
+		if p := metered.Load(); p != nil {
+			return float64(p.Dropped())
+		}
+		return 0
+	})
+)
+
+func init() {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "saltbot_discord_connected",
+		Help: "1 while connected to discord's gateway.",
+	}, func() float64 {
+		if Connected() {
+			return 1
+		}
+		return 0
+	})
+
+	metrics.NewCounterVecFunc("saltbot_ratelimit_total", "Commands allowed and limited by the cooldowns.",
+		[]string{"command", "result"}, func() []metrics.Sample {
+			var samples []metrics.Sample
+			for command, stats := range Limiter.Stats() {
+				samples = append(samples,
+					metrics.Sample{Labels: []string{command, "allowed"}, Value: float64(stats.Allowed)},
+					metrics.Sample{Labels: []string{command, "limited"}, Value: float64(stats.Limited)},
+				)
+			}
+			return samples
+		})
+
+	// Only the first limited command in a cooldown gets a reply, so this is
+	// part of the limited count rather than another result
+	metrics.NewCounterVecFunc("saltbot_ratelimit_notified_total", "Limited commands that got told to wait.",
+		[]string{"command"}, func() []metrics.Sample {
+			var samples []metrics.Sample
+			for command, stats := range Limiter.Stats() {
+				samples = append(samples, metrics.Sample{Labels: []string{command}, Value: float64(stats.Notified)})
+			}
+			return samples
+		})
+}
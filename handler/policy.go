@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/config"
+)
+
+// Why the author can't run the command, or "" if they can. Policies only
+// apply in servers.
+func authorize(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate, command *Command) string {
+	if m.GuildID == "" {
+		return ""
+	}
+
+	perms := permissions(ctx, s, m)
+	admin := perms&discordgo.PermissionManageServer != 0 || config.AdminRole(m.GuildID, memberRoles(m))
+	name := config.Prefix(m.GuildID) + command.Name
+	if command.Admin && !admin {
+		return fmt.Sprintf("```%s is only for bot admins```", name)
+	}
+
+	// Saltbot would post the mention for them, like in a poll's prompt
+	if mentionsEveryone(m.Content) && !admin && perms&discordgo.PermissionMentionEveryone == 0 {
+		return "```You need permission to mention everyone to use @everyone or @here```"
+	}
+
+	return config.Check(m, command.Name, admin)
+}
+
+func mentionsEveryone(content string) bool {
+	return strings.Contains(content, "@everyone") || strings.Contains(content, "@here")
+}
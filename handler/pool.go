@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"sync/atomic"
+)
+
+// How many events are handled at once, and how many can wait for a turn.
+// Set with SALTBOT_WORKERS and SALTBOT_QUEUE.
+var workerCount = 16
+var queueSize = 256
+
+func init() {
+	workerCount = intFromEnv("SALTBOT_WORKERS", workerCount)
+	queueSize = intFromEnv("SALTBOT_QUEUE", queueSize)
+}
+
+func intFromEnv(envVar string, def int) int {
+	value, ok := os.LookupEnv(envVar)
+	if !ok {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		slog.Warn("invalid number, using the default", "env", envVar, "value", value, "default", def, "error", err)
+		return def
+	}
+	return n
+}
+
+// A fixed number of goroutines that run jobs from a bounded queue. A panic
+// in a job is logged and the worker carries on.
+type Pool struct {
+	jobs    chan func()
+	dropped uint64
+}
+
+func NewPool(ctx context.Context, workers, queue int) *Pool {
+	p := &Pool{jobs: make(chan func(), queue)}
+	for i := 0; i < workers; i++ {
+		go p.work(ctx)
+	}
+	return p
+}
+
+func (p *Pool) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-p.jobs:
+			safely(job)
+		}
+	}
+}
+
+func safely(job func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("recovered from panic", "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+	job()
+}
+
+// Queue a job. If the queue is full the job is dropped and false is returned,
+// so that a flood of messages can't pile up goroutines.
+func (p *Pool) Submit(job func()) bool {
+	select {
+	case p.jobs <- job:
+		return true
+	default:
+		atomic.AddUint64(&p.dropped, 1)
+		return false
+	}
+}
+
+// Number of jobs dropped because the queue was full.
+func (p *Pool) Dropped() uint64 {
+	return atomic.LoadUint64(&p.dropped)
+}
+
+// Number of jobs waiting for a worker.
+func (p *Pool) Queued() int {
+	return len(p.jobs)
+}
+
+// Handles discord events. Set up by StartWorkers.
+var Workers *Pool
+
+// The pool the worker metrics report on. Metrics are scraped from another
+// goroutine, so it's kept apart from Workers.
+var metered atomic.Pointer[Pool]
+
+func StartWorkers(ctx context.Context) {
+	p := NewPool(ctx, workerCount, queueSize)
+	Workers = p
+	metered.Store(p)
+}
@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/highsaltlevels/saltbot/logging"
+)
+
+func TestPool(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := NewPool(ctx, 1, 2)
+
+	ran := make(chan int, 2)
+	p.Submit(func() { panic("boom") })
+	p.Submit(func() { ran <- 1 })
+
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the worker to keep going after a panic")
+	}
+}
+
+func TestPoolDrops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := NewPool(ctx, 1, 1)
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	p.Submit(func() {
+		close(started)
+		<-block
+	})
+	<-started
+
+	if !p.Submit(func() {}) {
+		t.Fatalf("expected a job to wait in the queue")
+	}
+	if p.Submit(func() {}) || p.Dropped() != 1 || p.Queued() != 1 {
+		t.Errorf("expected the job to be dropped with a full queue, but dropped %d with %d queued", p.Dropped(), p.Queued())
+	}
+	close(block)
+}
+
+func TestStartWorkersAgain(t *testing.T) {
+	workers, queue := workerCount, queueSize
+	workerCount, queueSize = 1, 1
+	t.Cleanup(func() { workerCount, queueSize = workers, queue })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	StartWorkers(ctx)
+	StartWorkers(ctx)
+
+	// The metrics follow the pool that was started last
+	block := make(chan struct{})
+	started := make(chan struct{})
+	Workers.Submit(func() {
+		close(started)
+		<-block
+	})
+	<-started
+	Workers.Submit(func() {})
+	Workers.Submit(func() {})
+	if testutil.ToFloat64(workerQueueLength) != 1 || testutil.ToFloat64(workerDropped) != 1 {
+		t.Errorf("expected 1 queued and 1 dropped, but got %v and %v",
+			testutil.ToFloat64(workerQueueLength), testutil.ToFloat64(workerDropped))
+	}
+	close(block)
+}
+
+// Add a command for the length of a test.
+func addCommand(t *testing.T, command Command) {
+	commands := Commands
+	Commands = append(append([]Command{}, Commands...), command)
+	t.Cleanup(func() { Commands = commands })
+}
+
+// How many latencies have been observed for a command.
+func observations(t *testing.T, command string) uint64 {
+	var m dto.Metric
+	if err := commandSeconds.WithLabelValues(command).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("failed to read %s's latencies: %v", command, err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestRunRecovers(t *testing.T) {
+	s := newSession(t)
+	addCommand(t, Command{
+		Name: "boom",
+		Run: func(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+			var args []string
+			return &discordgo.MessageSend{Content: args[1]}, nil
+		},
+	})
+
+	panics := testutil.ToFloat64(commandsHandled.WithLabelValues("boom", "panic"))
+	HandleMessage(s, newMessage("!boom", "1"))
+	sent := s.Flush()
+	if len(sent) != 1 || !strings.Contains(sent[0].Message.Content, "Unexpected error with id") {
+		t.Errorf("expected an error id, but got %+v", sent)
+	}
+	if testutil.ToFloat64(commandsHandled.WithLabelValues("boom", "panic")) != panics+1 {
+		t.Errorf("expected the panic to be counted")
+	}
+}
+
+func TestRunTimesOut(t *testing.T) {
+	s := newSession(t)
+	finished := false
+	addCommand(t, Command{
+		Name:    "slow",
+		Timeout: 10 * time.Millisecond,
+		Run: func(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+			<-ctx.Done()
+			finished = true
+			return &discordgo.MessageSend{Content: "too late"}, nil
+		},
+	})
+
+	timeouts := testutil.ToFloat64(commandsHandled.WithLabelValues("slow", "timeout"))
+	observed := observations(t, "slow")
+	HandleMessage(s, newMessage("!slow", "1"))
+	sent := s.Flush()
+	if len(sent) != 1 || !strings.Contains(sent[0].Message.Content, "Unexpected error with id") {
+		t.Errorf("expected an error id, but got %+v", sent)
+	}
+	if testutil.ToFloat64(commandsHandled.WithLabelValues("slow", "timeout")) != timeouts+1 || observations(t, "slow") != observed+1 {
+		t.Errorf("expected the timeout and its latency to be counted")
+	}
+
+	// The command gave up at its deadline rather than being left running
+	if !finished {
+		t.Errorf("expected the command to finish before the message was handled")
+	}
+}
+
+// A session whose DM channels only come back once the request's context is
+// done, like a discord call that hangs.
+type hangingSession struct {
+	SessionInterface
+}
+
+func (s hangingSession) UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	cfg := &discordgo.RequestConfig{Request: httptest.NewRequest(http.MethodPost, "/users/@me/channels", nil)}
+	for _, option := range options {
+		option(cfg)
+	}
+
+	select {
+	case <-cfg.Request.Context().Done():
+		return nil, cfg.Request.Context().Err()
+	case <-time.After(time.Second):
+		return nil, errors.New("the request wasn't given the command's context")
+	}
+}
+
+func TestRunCancelsDiscordCalls(t *testing.T) {
+	whisper := *lookup("whisper")
+	whisper.Timeout = 10 * time.Millisecond
+	s := hangingSession{newSession(t)}
+
+	start := time.Now()
+	_, err := run(context.Background(), s, newMessage("!whisper", "1"), &whisper)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be exceeded, but got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the DM to give up at the deadline, but it took %v", elapsed)
+	}
+}
+
+func TestLogsCorrelationId(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.Default()
+	logging.Setup(&buf)
+	t.Cleanup(func() { slog.SetDefault(logger) })
+
+	s := newSession(t)
+	addCommand(t, Command{
+		Name: "boom",
+		Run: func(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+			panic("boom")
+		},
+	})
+
+	HandleMessage(s, newMessage("!boom", "1"))
+	sent := s.Flush()
+	if len(sent) != 1 {
+		t.Fatalf("expected an error message, but got %+v", sent)
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(sent[0].Message.Content, "```Unexpected error with id: "), " :(```")
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("expected JSON logs, but got %q: %v", line, err)
+		}
+		lines = append(lines, entry)
+	}
+
+	// The panic, the error and the outcome are all logged with the id
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 log lines, but got %+v", lines)
+	}
+	for _, entry := range lines {
+		if entry["correlation_id"] != id || entry["command"] != "boom" || entry["user"] != "1" {
+			t.Errorf("expected the correlation id %s and command, but got %+v", id, entry)
+		}
+	}
+	last := lines[len(lines)-1]
+	if last["msg"] != "handled command" || last["outcome"] != "panic" {
+		t.Errorf("expected a panic outcome, but got %+v", last)
+	}
+	if _, ok := last["latency_ms"]; !ok {
+		t.Errorf("expected the latency, but got %+v", last)
+	}
+}
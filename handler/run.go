@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/logging"
+)
+
+// How long a command can take, unless it sets its own timeout. Giphy and
+// YouTube requests are retried, so this leaves room for a couple of retries.
+var CommandTimeout = 30 * time.Second
+
+// Wrapped by the error a panicking command returns
+var errPanic = errors.New("panic")
+
+// Run a command on the worker, turning a panic or running past its deadline
+// into an error. The command gets the deadline through its context and is
+// expected to give up once it passes, so the worker is freed up: discord
+// calls take it with discordgo.WithContext, and Kubernetes and third party
+// calls take it directly. Discord calls made outside the deadline, like the
+// permission check and sending the reply, are still cut off by discordgo's
+// own client timeout.
+func run(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate, command *Command) (message *discordgo.MessageSend, err error) {
+	timeout := command.Timeout
+	if timeout == 0 {
+		timeout = CommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			logging.From(ctx).Error("command panicked", "panic", r, "stack", string(debug.Stack()))
+			message, err = nil, fmt.Errorf("%w running %s: %v", errPanic, command.Name, r)
+		}
+	}()
+
+	message, err = command.Run(ctx, s, m)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%s took longer than %v: %w", command.Name, timeout, ctx.Err())
+	}
+	return message, err
+}
+
+// Describe a command's error for logging.
+func errorOutcome(err error) string {
+	switch {
+	case errors.Is(err, errPanic):
+		return "panic"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	default:
+		return "error"
+	}
+}
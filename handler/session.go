@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// Everything the handler needs from discord, so that it can be tested with
+// testutil.RecordingSession.
+type SessionInterface interface {
+	ChannelMessageSend(channelID string, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	UserChannelPermissions(userID, channelID string, fetchOptions ...discordgo.RequestOption) (int64, error)
+	Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+
+	// The bot's own user
+	StateUser() *discordgo.User
+}
+
+// A SessionInterface backed by a real discord session.
+type discordSession struct {
+	*discordgo.Session
+}
+
+func (s discordSession) StateUser() *discordgo.User {
+	return s.State.User
+}
+
+// Look in the state cache before asking discord.
+func (s discordSession) Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	channel, err := s.State.Channel(channelID)
+	if err == nil {
+		return channel, nil
+	}
+	return s.Session.Channel(channelID, options...)
+}
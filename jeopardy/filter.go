@@ -0,0 +1,87 @@
+package jeopardy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Clues worth between Min and Max dollars, inclusive. Zero on either end
+// means there's no bound, so the zero filter matches every clue.
+type ValueFilter struct {
+	Min int
+	Max int
+}
+
+// Parse a "--value" like "800", "800+" or "400-1000".
+func parseValueFilter(value string) (ValueFilter, error) {
+	if value == "" {
+		return ValueFilter{}, nil
+	}
+
+	invalid := fmt.Errorf("--value must look like \"800\", \"800+\" or \"400-1000\", not \"%s\"", value)
+	value = strings.TrimPrefix(value, "$")
+	if strings.HasSuffix(value, "+") {
+		min, err := strconv.Atoi(strings.TrimSuffix(value, "+"))
+		if err != nil || min < 0 {
+			return ValueFilter{}, invalid
+		}
+		return ValueFilter{Min: min}, nil
+	}
+
+	if low, high, ok := strings.Cut(value, "-"); ok {
+		min, minErr := strconv.Atoi(strings.TrimPrefix(low, "$"))
+		max, maxErr := strconv.Atoi(strings.TrimPrefix(high, "$"))
+		if minErr != nil || maxErr != nil || min < 0 || max < min {
+			return ValueFilter{}, invalid
+		}
+		return ValueFilter{Min: min, Max: max}, nil
+	}
+
+	exact, err := strconv.Atoi(value)
+	if err != nil || exact <= 0 {
+		return ValueFilter{}, invalid
+	}
+	return ValueFilter{Min: exact, Max: exact}, nil
+}
+
+func (f ValueFilter) matches(clue Clue) bool {
+	return clue.Value >= f.Min && (f.Max == 0 || clue.Value <= f.Max)
+}
+
+// The playable clues that match the filter.
+func (f ValueFilter) apply(clues []Clue) []Clue {
+	matching := []Clue{}
+	for _, clue := range playableClues(clues) {
+		if f.matches(clue) {
+			matching = append(matching, clue)
+		}
+	}
+	return matching
+}
+
+func (f ValueFilter) String() string {
+	switch {
+	case f.Min == 0 && f.Max == 0:
+		return "any amount"
+	case f.Max == 0:
+		return fmt.Sprintf("$%d or more", f.Min)
+	case f.Min == f.Max:
+		return fmt.Sprintf("$%d", f.Min)
+	}
+	return fmt.Sprintf("$%d to $%d", f.Min, f.Max)
+}
+
+// Air dates come from jservice as timestamps, but only the day matters.
+func formatAirDate(airDate string) string {
+	if len(airDate) < len("2006-01-02") {
+		return ""
+	}
+
+	date, err := time.Parse("2006-01-02", airDate[:len("2006-01-02")])
+	if err != nil {
+		return ""
+	}
+	return date.Format("Jan 2, 2006")
+}
@@ -0,0 +1,190 @@
+package jeopardy
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/highsaltlevels/saltbot/trivia"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+func TestParseValueFilter(t *testing.T) {
+	tests := []struct {
+		value         string
+		expected      ValueFilter
+		expectedError bool
+	}{
+		{value: "", expected: ValueFilter{}},
+		{value: "800+", expected: ValueFilter{Min: 800}},
+		{value: "$800+", expected: ValueFilter{Min: 800}},
+		{value: "400", expected: ValueFilter{Min: 400, Max: 400}},
+		{value: "200-600", expected: ValueFilter{Min: 200, Max: 600}},
+		{value: "600-200", expectedError: true},
+		{value: "lots", expectedError: true},
+		{value: "0", expectedError: true},
+		{value: "+", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			filter, err := parseValueFilter(tt.value)
+			if tt.expectedError {
+				if err == nil {
+					t.Errorf("expected an error but got filter %+v", filter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+			if filter != tt.expected {
+				t.Errorf("expected filter %+v, but got %+v", tt.expected, filter)
+			}
+		})
+	}
+}
+
+func TestFormatAirDate(t *testing.T) {
+	tests := map[string]string{
+		"2004-03-12T12:00:00.000Z": "Mar 12, 2004",
+		"2004-03-12":               "Mar 12, 2004",
+		"":                         "",
+		"not a date":               "",
+	}
+	for airDate, expected := range tests {
+		if actual := formatAirDate(airDate); actual != expected {
+			t.Errorf("expected '%s' for '%s', but got '%s'", expected, airDate, actual)
+		}
+	}
+}
+
+func TestSearchAndFilter(t *testing.T) {
+	sources = []ClueSource{&localSource{categories: []JeopardyResponse{
+		{
+			Title: "world capitals",
+			Clues: []Clue{
+				{Question: "France's capital", Answer: "Paris", Value: 200, AirDate: "2004-03-12T12:00:00.000Z"},
+				{Question: "Peru's capital", Answer: "Lima", Value: 1000},
+			},
+		},
+		{
+			Title: "potpourri",
+			Clues: []Clue{
+				{Question: "A baker's dozen", Answer: "13", Value: 400},
+			},
+		},
+	}}}
+
+	tests := []struct {
+		name        string
+		command     string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:     "Test search by title",
+			command:  "!jeopardy CAPITALS",
+			contains: []string{"The Category is: world capitals", "Question 1 ($200, aired Mar 12, 2004): France's capital", "Question 2 ($1000): Peru's capital"},
+		},
+		{
+			name:        "Test search with value filter",
+			command:     "!jeopardy capitals --value 800+",
+			contains:    []string{"Question 1 ($1000): Peru's capital"},
+			notContains: []string{"France"},
+		},
+		{
+			name:     "Test random category with value filter",
+			command:  "!jeopardy --value 400",
+			contains: []string{"The Category is: potpourri", "A baker's dozen"},
+		},
+		{
+			name:     "Test search without a match",
+			command:  "!jeopardy sports",
+			contains: []string{"```Couldn't find a category matching \"sports\"```"},
+		},
+		{
+			name:     "Test filter without a match",
+			command:  "!jeopardy potpourri --value 200-300",
+			contains: []string{"```Couldn't find a category matching \"potpourri\" with clues worth $200 to $300```"},
+		},
+		{
+			name:     "Test invalid value",
+			command:  "!jeopardy --value lots",
+			contains: []string{"--value must look like"},
+		},
+		{
+			name:     "Test play with search and filter",
+			command:  "!jeopardy play capitals --value 1000",
+			contains: []string{"Category: world capitals\n$1,000 (clue 1 of 1)"},
+		},
+		{
+			name:     "Test help",
+			command:  "!jeopardy help",
+			contains: []string{"!jeopardy --value 800+", "!jeopardy final here"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := Handle(context.Background(), &MockSession{}, newMessage(tt.command, "1"), false, util.NewFakeClock(time.Now()))
+			if err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+			trivia.Stop("channel", "!jeopardy")
+			for _, expected := range tt.contains {
+				if !strings.Contains(msg.Content, expected) {
+					t.Errorf("expected '%s' in message '%s'", expected, msg.Content)
+				}
+			}
+			for _, unexpected := range tt.notContains {
+				if strings.Contains(msg.Content, unexpected) {
+					t.Errorf("expected no '%s' in message '%s'", unexpected, msg.Content)
+				}
+			}
+		})
+	}
+}
+
+// Hands out the same category every time, like categoryCache does.
+type sharedSource struct {
+	category *JeopardyResponse
+}
+
+func (s *sharedSource) Name() string {
+	return "shared"
+}
+
+func (s *sharedSource) RandomCategory(ctx context.Context) (*JeopardyResponse, error) {
+	return s.category, nil
+}
+
+func (s *sharedSource) SearchCategories(ctx context.Context, query string) ([]JeopardyResponse, error) {
+	return nil, errSearchUnsupported
+}
+
+func TestPickCategoryKeepsCachedClues(t *testing.T) {
+	shared := &sharedSource{category: &JeopardyResponse{
+		Title: "potpourri",
+		Clues: []Clue{
+			{Question: "A baker's dozen", Answer: "13", Value: 200},
+			{Question: "Sides on a hexagon", Answer: "6", Value: 1000},
+		},
+	}}
+	sources = []ClueSource{shared}
+
+	for _, value := range []int{200, 1000} {
+		category, err := pickCategory(context.Background(), "", ValueFilter{Min: value, Max: value})
+		if err != nil {
+			t.Fatalf("expected no error, but got: %v", err)
+		}
+		if category == nil || len(category.Clues) != 1 || category.Clues[0].Value != value {
+			t.Errorf("expected the $%d clue, but got %+v", value, category)
+		}
+	}
+
+	if len(shared.category.Clues) != 2 {
+		t.Errorf("expected the shared category to keep its clues, but got %+v", shared.category.Clues)
+	}
+}
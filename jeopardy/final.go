@@ -0,0 +1,385 @@
+package jeopardy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/config"
+	"github.com/highsaltlevels/saltbot/trivia"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+// Players with a low score can still wager up to this much
+const minMaxWager = 1000
+
+// Dropped from wagers, so they can be written like "$1,200" or with the digits
+// grouped the way any locale does, like "1.200"
+var wagerSeparators = strings.NewReplacer("$", "", ",", "", ".", "", "'", "", "’", "", "\u00a0", "", "\u202f", "")
+
+// When the daily Final Jeopardy is posted, as time since midnight. Set with
+// JEOPARDY_FINAL_TIME like "20:00".
+var finalTime time.Duration
+
+// How long players have to wager and answer. Set with JEOPARDY_FINAL_WINDOW.
+var finalWindow time.Duration
+
+type finalWager struct {
+	name   string
+	amount int
+	answer string
+}
+
+// A Final Jeopardy clue that's open for wagers in a guild.
+type finalRound struct {
+	channel  string
+	category string
+	clue     Clue
+	closes   time.Time
+	wagers   map[string]finalWager
+}
+
+// Open rounds keyed on guild id
+var finalRounds = map[string]*finalRound{}
+var finalLock sync.Mutex
+
+// The day each guild's Final Jeopardy was last posted. The cache has this
+// too, but our own writes take a moment to show up in it.
+var finalPosted = map[string]string{}
+
+// Parse JEOPARDY_FINAL_TIME, falling back to 8 PM.
+func parseFinalTime(value string) time.Duration {
+	def := 20 * time.Hour
+	if value == "" {
+		return def
+	}
+
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		slog.Warn("invalid JEOPARDY_FINAL_TIME, using 20:00", "value", value, "error", err)
+		return def
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+func formatFinalTime() string {
+	return time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).Add(finalTime).Format("3:04 PM")
+}
+
+// Handle "!jeopardy final". Admins pick the channel it's posted to.
+func final(ctx context.Context, args []string, m *discordgo.MessageCreate, admin bool) (*discordgo.MessageSend, error) {
+	if m.GuildID == "" {
+		return &discordgo.MessageSend{
+			Content: "```Final Jeopardy is only played in servers```",
+		}, nil
+	}
+
+	existing := cache.Cache.GetFinal(m.GuildID)
+	if len(args) == 0 {
+		if existing == nil {
+			return &discordgo.MessageSend{
+				Content: "```Final Jeopardy isn't set up for this server. An admin can type \"!jeopardy final here\" " +
+					"in the channel it should be posted to```",
+			}, nil
+		}
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("Final Jeopardy is posted in <#%s> every day at %s", existing.Channel, formatFinalTime()),
+		}, nil
+	}
+
+	if args[0] != "here" && args[0] != "off" {
+		return &discordgo.MessageSend{
+			Content: helpMessage,
+		}, nil
+	}
+
+	if !admin {
+		return &discordgo.MessageSend{
+			Content: "```Only server admins can change where Final Jeopardy is posted```",
+		}, nil
+	}
+
+	if args[0] == "off" {
+		if existing == nil {
+			return &discordgo.MessageSend{
+				Content: "```Final Jeopardy is already off for this server```",
+			}, nil
+		}
+		err := cache.Cache.Delete(ctx, "final-"+m.GuildID)
+		if err != nil {
+			return nil, fmt.Errorf("error deleting final jeopardy from k8s: %w", err)
+		}
+		return &discordgo.MessageSend{
+			Content: "```Final Jeopardy is turned off for this server```",
+		}, nil
+	}
+
+	f := cache.FinalJeopardy{
+		Author:  m.Author.ID,
+		Guild:   m.GuildID,
+		Channel: m.ChannelID,
+		Id:      m.GuildID,
+	}
+	if existing != nil {
+		f.LastPosted = existing.LastPosted
+	}
+
+	err := cache.Cache.SetFinal(ctx, &f)
+	if err != nil {
+		return nil, fmt.Errorf("error setting final jeopardy channel in k8s: %w", err)
+	}
+
+	return &discordgo.MessageSend{
+		Content: fmt.Sprintf("```Final Jeopardy will be posted in this channel every day at %s```", formatFinalTime()),
+	}, nil
+}
+
+// The most a player can wager, going by their all-time score.
+func maxWager(guild, player string) int {
+	leaderboardsLock.Lock()
+	defer leaderboardsLock.Unlock()
+
+	score := leaderboardFor(guild).Players[player].Score
+	if score < minMaxWager {
+		return minMaxWager
+	}
+	return score
+}
+
+// Handle "!jeopardy wager <amount> ||<answer>||".
+func wager(args []string, m *discordgo.MessageCreate) *discordgo.MessageSend {
+	finalLock.Lock()
+	defer finalLock.Unlock()
+
+	round, ok := finalRounds[m.GuildID]
+	if !ok {
+		return &discordgo.MessageSend{
+			Content: "```There's no Final Jeopardy to wager on right now```",
+		}
+	}
+
+	if len(args) < 2 {
+		return &discordgo.MessageSend{
+			Content: "```Wager like: \"!jeopardy wager 1000 ||what is Paris||\"```",
+		}
+	}
+
+	amount, err := strconv.Atoi(wagerSeparators.Replace(args[0]))
+	if err != nil || amount < 0 {
+		return &discordgo.MessageSend{
+			Content: "```Your wager must be a number of dollars```",
+		}
+	}
+
+	if max := maxWager(m.GuildID, m.Author.ID); amount > max {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```You can wager up to %s```", formatDollars(m.GuildID, max)),
+		}
+	}
+
+	if _, ok := round.wagers[m.Author.ID]; ok {
+		return &discordgo.MessageSend{
+			Content: "```You've already locked in your wager```",
+		}
+	}
+
+	round.wagers[m.Author.ID] = finalWager{
+		name:   m.Author.Username,
+		amount: amount,
+		answer: strings.ReplaceAll(strings.Join(args[1:], " "), "||", ""),
+	}
+
+	return &discordgo.MessageSend{
+		Content: fmt.Sprintf("```%s, your wager of %s is locked in. Good luck!```", m.Author.Username, formatDollars(m.GuildID, amount)),
+	}
+}
+
+// Posts each guild's Final Jeopardy once a day and closes it after the window.
+type FinalScheduler struct {
+	session SessionInterface
+	ctx     context.Context
+	clock   util.Clock
+}
+
+func NewFinalScheduler(s SessionInterface, ctx context.Context, clock util.Clock) *FinalScheduler {
+	return &FinalScheduler{
+		session: s,
+		ctx:     ctx,
+		clock:   clock,
+	}
+}
+
+func (f *FinalScheduler) Loop() {
+	for {
+		select {
+		case <-f.ctx.Done():
+			slog.Info("final jeopardy scheduler stopped")
+			return
+
+		case <-f.clock.After(time.Minute):
+			f.check(f.clock.Now())
+		}
+	}
+}
+
+// Close rounds that are over and open today's rounds. The rounds are picked
+// under the final lock, but the fetches, sends and cache writes happen after
+// it's released so wagers aren't held up by them.
+func (f *FinalScheduler) check(t time.Time) {
+	closing, opening := f.due(t)
+
+	for guild, round := range closing {
+		f.close(guild, round, t)
+	}
+
+	for _, entry := range opening {
+		f.open(entry, t)
+
+		// Mark it posted even if it failed, rather than retrying every minute
+		err := cache.Cache.SetFinal(f.ctx, &entry)
+		if err != nil {
+			slog.Error("failed to update final jeopardy", "guild", entry.Guild, "error", err)
+		}
+	}
+}
+
+// Take the rounds that are over out of the open rounds, and claim today's
+// post for each guild that hasn't had one yet. Each guild's final is posted
+// at finalTime in its own time zone, and comes back with LastPosted set to
+// its day there.
+func (f *FinalScheduler) due(t time.Time) (map[string]*finalRound, []cache.FinalJeopardy) {
+	finalLock.Lock()
+	defer finalLock.Unlock()
+
+	closing := map[string]*finalRound{}
+	for guild, round := range finalRounds {
+		if !t.Before(round.closes) {
+			closing[guild] = round
+			delete(finalRounds, guild)
+		}
+	}
+
+	opening := []cache.FinalJeopardy{}
+	for _, entry := range cache.Cache.ListFinals() {
+		local := t.In(config.Location(entry.Guild))
+		midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+		if local.Before(midnight.Add(finalTime)) {
+			continue
+		}
+
+		today := local.Format("2006-01-02")
+		if _, ok := finalRounds[entry.Guild]; ok || entry.LastPosted == today || finalPosted[entry.Guild] == today {
+			continue
+		}
+		finalPosted[entry.Guild] = today
+		entry.LastPosted = today
+		opening = append(opening, entry)
+	}
+	return closing, opening
+}
+
+// Post the clue. The hardest clue in the category is used.
+func (f *FinalScheduler) open(entry cache.FinalJeopardy, t time.Time) {
+	category, err := pickCategory(f.ctx, "", ValueFilter{})
+	if err != nil || category == nil {
+		slog.Error("failed to get a final jeopardy category", "guild", entry.Guild, "error", err)
+		return
+	}
+
+	clue, ok := hardestClue(category.Clues)
+	if !ok {
+		slog.Error("final jeopardy category has no playable clues", "guild", entry.Guild, "category", category.Title)
+		return
+	}
+
+	round := &finalRound{
+		channel:  entry.Channel,
+		category: category.Title,
+		clue:     clue,
+		closes:   t.Add(finalWindow),
+		wagers:   map[string]finalWager{},
+	}
+
+	// Open the round before posting it so quick wagers aren't turned away
+	finalLock.Lock()
+	finalRounds[entry.Guild] = round
+	finalLock.Unlock()
+
+	msg := fmt.Sprintf("**It's time for Final Jeopardy!**\n```Category: %s\n\n%s```"+
+		"Wager up to your score (or %s) and answer with \"!jeopardy wager <amount> ||<answer>||\" in the next %s. "+
+		"Keep your answer in spoiler tags!", round.category, trivia.CleanText(round.clue.Question), formatDollars(entry.Guild, minMaxWager), finalWindow)
+	_, err = f.session.ChannelMessageSendComplex(entry.Channel, &discordgo.MessageSend{
+		Content: msg,
+	})
+	if err != nil {
+		slog.Error("failed to send final jeopardy", "channel", entry.Channel, "error", err)
+		finalLock.Lock()
+		delete(finalRounds, entry.Guild)
+		finalLock.Unlock()
+	}
+}
+
+// The playable clue worth the most, and false if there isn't one.
+func hardestClue(clues []Clue) (Clue, bool) {
+	playable := playableClues(clues)
+	if len(playable) == 0 {
+		return Clue{}, false
+	}
+
+	hardest := playable[0]
+	for _, clue := range playable[1:] {
+		if clue.Value > hardest.Value {
+			hardest = clue
+		}
+	}
+	return hardest, true
+}
+
+// Reveal the answer and settle the wagers at t. The round must already be
+// taken out of the open rounds.
+func (f *FinalScheduler) close(guild string, round *finalRound, t time.Time) {
+	players := make([]string, 0, len(round.wagers))
+	for id := range round.wagers {
+		players = append(players, id)
+	}
+	sort.Slice(players, func(i, j int) bool {
+		return round.wagers[players[i]].name < round.wagers[players[j]].name
+	})
+
+	names := map[string]string{}
+	results := map[string][]trivia.Result{}
+	msg := fmt.Sprintf("Final Jeopardy is over! The answer was **%s**.\n", trivia.CleanText(round.clue.Answer))
+	if len(players) == 0 {
+		msg += "```Nobody played today```"
+	} else {
+		msg += "```"
+		for _, id := range players {
+			w := round.wagers[id]
+			correct := trivia.IsCorrect(w.answer, round.clue.Answer)
+			names[id] = w.name
+			results[id] = []trivia.Result{{Correct: correct, Value: w.amount, Wager: true}}
+			if correct {
+				msg += fmt.Sprintf("%s got it right: +%s\n", w.name, formatDollars(guild, w.amount))
+			} else {
+				msg += fmt.Sprintf("%s said \"%s\": -%s\n", w.name, w.answer, formatDollars(guild, w.amount))
+			}
+		}
+		msg += "```"
+	}
+
+	recordGame(guild, t, names, results)
+	_, err := f.session.ChannelMessageSendComplex(round.channel, &discordgo.MessageSend{
+		Content: msg,
+	})
+	if err != nil {
+		slog.Error("failed to send final jeopardy results", "channel", round.channel, "error", err)
+	}
+}
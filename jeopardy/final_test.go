@@ -0,0 +1,329 @@
+package jeopardy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/testutil"
+	"github.com/highsaltlevels/saltbot/trivia"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+func TestParseFinalTime(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":      20 * time.Hour,
+		"18:30": 18*time.Hour + 30*time.Minute,
+		"8pm":   20 * time.Hour,
+	}
+	for value, expected := range tests {
+		if actual := parseFinalTime(value); actual != expected {
+			t.Errorf("expected %s for '%s', but got %s", expected, value, actual)
+		}
+	}
+}
+
+func TestFinalCommand(t *testing.T) {
+	finalTime = 20 * time.Hour
+	existing := cache.FinalJeopardy{Guild: "guild", Channel: "trivia", Id: "guild"}
+
+	tests := []struct {
+		name          string
+		command       string
+		guild         string
+		admin         bool
+		configured    bool
+		k8sError      bool
+		expected      string
+		expectedError error
+	}{
+		{
+			name:     "Test final in a DM",
+			command:  "!jeopardy final",
+			expected: "```Final Jeopardy is only played in servers```",
+		},
+		{
+			name:     "Test final not set up",
+			command:  "!jeopardy final",
+			guild:    "guild",
+			expected: "```Final Jeopardy isn't set up for this server. An admin can type \"!jeopardy final here\" in the channel it should be posted to```",
+		},
+		{
+			name:       "Test final set up",
+			command:    "!jeopardy final",
+			guild:      "guild",
+			configured: true,
+			expected:   "Final Jeopardy is posted in <#trivia> every day at 8:00 PM",
+		},
+		{
+			name:     "Test non-admin can't set the channel",
+			command:  "!jeopardy final here",
+			guild:    "guild",
+			expected: "```Only server admins can change where Final Jeopardy is posted```",
+		},
+		{
+			name:     "Test admin sets the channel",
+			command:  "!jeopardy final here",
+			guild:    "guild",
+			admin:    true,
+			expected: "```Final Jeopardy will be posted in this channel every day at 8:00 PM```",
+		},
+		{
+			name:          "Test setting the channel k8s error",
+			command:       "!jeopardy final here",
+			guild:         "guild",
+			admin:         true,
+			k8sError:      true,
+			expectedError: errors.New(testutil.ExpectedError),
+		},
+		{
+			name:       "Test admin turns it off",
+			command:    "!jeopardy final off",
+			guild:      "guild",
+			admin:      true,
+			configured: true,
+			expected:   "```Final Jeopardy is turned off for this server```",
+		},
+		{
+			name:          "Test turning it off k8s error",
+			command:       "!jeopardy final off",
+			guild:         "guild",
+			admin:         true,
+			configured:    true,
+			k8sError:      true,
+			expectedError: errors.New(testutil.ExpectedError),
+		},
+		{
+			name:     "Test turning it off when it's already off",
+			command:  "!jeopardy final off",
+			guild:    "guild",
+			admin:    true,
+			expected: "```Final Jeopardy is already off for this server```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetLeaderboards(time.Now())
+			if tt.k8sError {
+				cache.Client = &testutil.MockErrorK8sClient{}
+			}
+			if tt.configured {
+				configMap, _ := existing.ToConfigMap()
+				cache.Cache.Store(configMap)
+			}
+
+			m := newMessage(tt.command, "1")
+			m.GuildID = tt.guild
+			msg, err := Handle(context.Background(), &MockSession{}, m, tt.admin, util.SystemClock)
+			if tt.expectedError != nil {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError.Error()) {
+					t.Errorf("expected error '%v', but got '%v'", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+			if msg.Content != tt.expected {
+				t.Errorf("expected message: '%s', but got '%s'", tt.expected, msg.Content)
+			}
+		})
+	}
+}
+
+// Set the guild's time zone, which final jeopardy is scheduled in.
+func setTimezone(guild, timezone string) {
+	settings := cache.GuildConfig{Id: guild, Timezone: timezone}
+	configMap, _ := settings.ToConfigMap()
+	cache.Cache.Store(configMap)
+}
+
+func TestFinalRound(t *testing.T) {
+	day := time.Date(2023, 7, 5, 0, 0, 0, 0, time.UTC)
+	resetLeaderboards(day)
+	finalTime = 20 * time.Hour
+	finalWindow = 30 * time.Minute
+	finalRounds = map[string]*finalRound{}
+	finalPosted = map[string]string{}
+	sources = []ClueSource{&localSource{categories: []JeopardyResponse{
+		{
+			Title: "capitals",
+			Clues: []Clue{
+				{Question: "France's capital", Answer: "Paris", Value: 200},
+				{Question: "Peru's capital", Answer: "Lima", Value: 1000},
+			},
+		},
+	}}}
+	config := cache.FinalJeopardy{Guild: "guild", Channel: "trivia", Id: "guild"}
+	configMap, _ := config.ToConfigMap()
+	cache.Cache.Store(configMap)
+	setTimezone("guild", "UTC")
+
+	// A player with a big score can wager more than the minimum
+	recordGame("guild", day, map[string]string{"2": "user2"}, map[string][]trivia.Result{
+		"2": {{Correct: true, Value: 3000}},
+	})
+
+	session := &MockSession{}
+	scheduler := NewFinalScheduler(session, context.Background(), util.SystemClock)
+
+	m := newMessage("!jeopardy wager 100 ||lima||", "1")
+	m.GuildID = "guild"
+	msg, _ := Handle(context.Background(), session, m, false, util.SystemClock)
+	if msg.Content != "```There's no Final Jeopardy to wager on right now```" {
+		t.Errorf("expected no round before it's posted, but got '%s'", msg.Content)
+	}
+
+	scheduler.check(day.Add(19 * time.Hour))
+	if len(session.sent) != 0 {
+		t.Fatalf("expected nothing to be posted before 8 PM, but got %d messages", len(session.sent))
+	}
+
+	scheduler.check(day.Add(20 * time.Hour))
+	if len(session.sent) != 1 || !strings.Contains(session.sent[0].Content, "Category: capitals\n\nPeru's capital") {
+		t.Fatalf("expected the hardest clue to be posted, but got %+v", session.sent)
+	}
+
+	// Posted once a day, not every check
+	scheduler.check(day.Add(20*time.Hour + time.Minute))
+	if len(session.sent) != 1 {
+		t.Fatalf("expected final jeopardy to be posted once, but got %d messages", len(session.sent))
+	}
+
+	wagers := []struct {
+		author   string
+		command  string
+		expected string
+	}{
+		{author: "1", command: "!jeopardy wager 2000 ||lima||", expected: "```You can wager up to $1,000```"},
+		{author: "1", command: "!jeopardy wager lots ||lima||", expected: "```Your wager must be a number of dollars```"},
+		{author: "1", command: "!jeopardy wager 500", expected: "```Wager like: \"!jeopardy wager 1000 ||what is Paris||\"```"},
+		{author: "1", command: "!jeopardy wager 500 ||what is lima||", expected: "```user1, your wager of $500 is locked in. Good luck!```"},
+		{author: "1", command: "!jeopardy wager 100 ||lima||", expected: "```You've already locked in your wager```"},
+		{author: "2", command: "!jeopardy wager $2,000 ||bogota||", expected: "```user2, your wager of $2,000 is locked in. Good luck!```"},
+	}
+	for _, w := range wagers {
+		m := newMessage(w.command, w.author)
+		m.GuildID = "guild"
+		msg, _ := Handle(context.Background(), session, m, false, util.SystemClock)
+		if msg.Content != w.expected {
+			t.Errorf("expected '%s' for '%s', but got '%s'", w.expected, w.command, msg.Content)
+		}
+	}
+
+	scheduler.check(day.Add(20*time.Hour + 30*time.Minute))
+	if len(session.sent) != 2 {
+		t.Fatalf("expected the results to be posted, but got %d messages", len(session.sent))
+	}
+	expected := "Final Jeopardy is over! The answer was **Lima**.\n" +
+		"```user1 got it right: +$500\nuser2 said \"bogota\": -$2,000\n```"
+	if session.sent[1].Content != expected {
+		t.Errorf("expected results:\n%s\nbut got:\n%s", expected, session.sent[1].Content)
+	}
+
+	players := leaderboards["guild"].Players
+	if players["1"].Score != 500 || players["2"].Score != 1000 {
+		t.Errorf("expected scores 500 and 1000, but got %d and %d", players["1"].Score, players["2"].Score)
+	}
+	if len(finalRounds) != 0 {
+		t.Errorf("expected the round to be closed")
+	}
+}
+
+// Blocks sends until it's released
+type blockingSession struct {
+	MockSession
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSession) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	close(s.started)
+	<-s.release
+	return s.MockSession.ChannelMessageSendComplex(channelID, data, options...)
+}
+
+func TestFinalSendDoesNotBlockWagers(t *testing.T) {
+	day := time.Date(2023, 7, 6, 0, 0, 0, 0, time.UTC)
+	resetLeaderboards(day)
+	finalTime = 20 * time.Hour
+	finalWindow = 30 * time.Minute
+	finalRounds = map[string]*finalRound{}
+	finalPosted = map[string]string{}
+	sources = []ClueSource{&localSource{categories: []JeopardyResponse{
+		{
+			Title: "capitals",
+			Clues: []Clue{{Question: "Peru's capital", Answer: "Lima", Value: 1000}},
+		},
+	}}}
+	config := cache.FinalJeopardy{Guild: "guild", Channel: "trivia", Id: "guild"}
+	configMap, _ := config.ToConfigMap()
+	cache.Cache.Store(configMap)
+	setTimezone("guild", "UTC")
+
+	session := &blockingSession{started: make(chan struct{}), release: make(chan struct{})}
+	scheduler := NewFinalScheduler(session, context.Background(), util.SystemClock)
+	done := make(chan struct{})
+	go func() {
+		scheduler.check(day.Add(20 * time.Hour))
+		close(done)
+	}()
+	<-session.started
+
+	m := newMessage("!jeopardy wager 500 ||lima||", "1")
+	m.GuildID = "guild"
+	msg, _ := Handle(context.Background(), &MockSession{}, m, false, util.SystemClock)
+	close(session.release)
+	<-done
+
+	if msg.Content != "```user1, your wager of $500 is locked in. Good luck!```" {
+		t.Errorf("expected the wager to be taken while the clue is posted, but got '%s'", msg.Content)
+	}
+}
+
+func TestFinalRoundInGuildTimezone(t *testing.T) {
+	day := time.Date(2023, 7, 5, 0, 0, 0, 0, time.UTC)
+	resetLeaderboards(day)
+	finalTime = 20 * time.Hour
+	finalWindow = 30 * time.Minute
+	finalRounds = map[string]*finalRound{}
+	finalPosted = map[string]string{}
+	sources = []ClueSource{&localSource{categories: []JeopardyResponse{
+		{
+			Title: "capitals",
+			Clues: []Clue{
+				{Question: "Peru's capital", Answer: "Lima", Value: 1000},
+				{Question: "Chile's capital", Answer: "", Value: 2000},
+				{Question: "France's capital", Answer: "Paris", Value: 200},
+			},
+		},
+	}}}
+	config := cache.FinalJeopardy{Guild: "guild", Channel: "trivia", Id: "guild"}
+	configMap, _ := config.ToConfigMap()
+	cache.Cache.Store(configMap)
+	setTimezone("guild", "America/Chicago")
+
+	session := &MockSession{}
+	scheduler := NewFinalScheduler(session, context.Background(), util.SystemClock)
+
+	// 8 PM UTC is 3 PM in Chicago
+	scheduler.check(day.Add(20 * time.Hour))
+	if len(session.sent) != 0 {
+		t.Fatalf("expected nothing to be posted before 8 PM in Chicago, but got %d messages", len(session.sent))
+	}
+
+	// The clue without an answer is skipped
+	scheduler.check(day.Add(25 * time.Hour))
+	if len(session.sent) != 1 || !strings.Contains(session.sent[0].Content, "Category: capitals\n\nPeru's capital") {
+		t.Fatalf("expected the hardest playable clue to be posted, but got %+v", session.sent)
+	}
+	if finalPosted["guild"] != "2023-07-05" {
+		t.Errorf("expected it to be posted on the 5th in Chicago, but got %s", finalPosted["guild"])
+	}
+}
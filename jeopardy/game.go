@@ -0,0 +1,111 @@
+package jeopardy
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/config"
+	"github.com/highsaltlevels/saltbot/trivia"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+type SessionInterface interface {
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+// Jeopardy clues as trivia questions, so "!trivia play --source jeopardy"
+// works too.
+type Bank struct {
+	Filter ValueFilter
+}
+
+func (b Bank) Name() string {
+	return "jeopardy"
+}
+
+// A whole category of clues, cheapest first. Count is ignored since a
+// category is played together.
+func (b Bank) Questions(ctx context.Context, search string, count int) ([]trivia.Question, error) {
+	category, err := pickCategory(ctx, search, b.Filter)
+	if err != nil || category == nil {
+		return nil, err
+	}
+	return questions(category), nil
+}
+
+func questions(category *JeopardyResponse) []trivia.Question {
+	questions := []trivia.Question{}
+	for _, clue := range playableClues(category.Clues) {
+		questions = append(questions, trivia.Question{
+			Category: category.Title,
+			Prompt:   trivia.CleanText(clue.Question),
+			Answer:   trivia.CleanText(clue.Answer),
+			Kind:     trivia.FreeText,
+			Value:    clue.Value,
+		})
+	}
+	return questions
+}
+
+// Drop clues that can't be played and fill in missing values from the clue's
+// position in the category, like the board would.
+func playableClues(clues []Clue) []Clue {
+	playable := []Clue{}
+	for i, clue := range clues {
+		if strings.TrimSpace(clue.Question) == "" || strings.TrimSpace(clue.Answer) == "" {
+			continue
+		}
+		if clue.Value <= 0 {
+			clue.Value = (i%5 + 1) * 200
+		}
+		playable = append(playable, clue)
+	}
+
+	sort.SliceStable(playable, func(i, j int) bool {
+		return playable[i].Value < playable[j].Value
+	})
+	return playable
+}
+
+func play(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate, search string, filter ValueFilter, clock util.Clock) (*discordgo.MessageSend, error) {
+	if trivia.Running(m.ChannelID) {
+		return &discordgo.MessageSend{
+			Content: "```There's already a game going in this channel. Type \"!jeopardy stop\" to end it```",
+		}, nil
+	}
+
+	// Fetched before trivia.Start takes the games lock, so a slow source
+	// doesn't hold up answers in every other channel
+	category, err := pickCategory(ctx, search, filter)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return noCategoryMessage(search, filter), nil
+	}
+
+	return trivia.Start(s, trivia.Options{
+		Command:   "!jeopardy",
+		Intro:     "Let's play Jeopardy! Answer in chat before the time runs out.",
+		Guild:     m.GuildID,
+		Channel:   m.ChannelID,
+		Questions: questions(category),
+		Dollars:   true,
+		Locale:    config.Language(m.GuildID),
+		Clock:     clock,
+		OnFinish: func(guild string, names map[string]string, results map[string][]trivia.Result) {
+			recordGame(guild, clock.Now(), names, results)
+		},
+	}), nil
+}
+
+func stop(m *discordgo.MessageCreate) *discordgo.MessageSend {
+	return trivia.Stop(m.ChannelID, "!jeopardy")
+}
+
+func scores(m *discordgo.MessageCreate) *discordgo.MessageSend {
+	return trivia.Scores(m.ChannelID, "!jeopardy")
+}
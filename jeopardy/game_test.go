@@ -0,0 +1,222 @@
+package jeopardy
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/trivia"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+type MockSession struct {
+	sent []*discordgo.MessageSend
+}
+
+func (s *MockSession) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.sent = append(s.sent, data)
+	return &discordgo.Message{}, nil
+}
+
+func newMessage(content, author string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			Content:   content,
+			ChannelID: "channel",
+			Author: &discordgo.User{
+				ID:       author,
+				Username: "user" + author,
+			},
+		},
+	}
+}
+
+// Start a game with a long timer so that it only runs out when the test
+// says so. The game is stopped when the test ends.
+func startGame(t *testing.T, session *MockSession, guild string, clock util.Clock) {
+	sources = []ClueSource{&localSource{categories: []JeopardyResponse{
+		{
+			Title: "capitals",
+			Clues: []Clue{
+				{Question: "France's capital", Answer: "Paris", Value: 400},
+				{Question: "Japan's capital", Answer: "Tokyo", Value: 200},
+				{Question: "", Answer: "unplayable"},
+			},
+		},
+	}}}
+
+	m := newMessage("!jeopardy play", "1")
+	m.GuildID = guild
+	msg, err := Handle(context.Background(), session, m, false, clock)
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if !strings.Contains(msg.Content, "Category: capitals\n$200 (clue 1 of 2)\n\nJapan's capital") {
+		t.Fatalf("expected the cheapest clue first, but got '%s'", msg.Content)
+	}
+
+	t.Cleanup(func() {
+		trivia.Stop("channel", "!jeopardy")
+	})
+}
+
+func TestPlayableClues(t *testing.T) {
+	clues := playableClues([]Clue{
+		{Question: "q1", Answer: "a1"},
+		{Question: "q2", Answer: "a2", Value: 1000},
+		{Question: "", Answer: "a3"},
+		{Question: "q4", Answer: "a4"},
+	})
+
+	values := []int{}
+	for _, clue := range clues {
+		values = append(values, clue.Value)
+	}
+	if len(values) != 3 || values[0] != 200 || values[1] != 800 || values[2] != 1000 {
+		t.Errorf("expected values [200 800 1000], but got %v", values)
+	}
+}
+
+func TestPlayGame(t *testing.T) {
+	session := &MockSession{}
+	startGame(t, session, "", util.NewFakeClock(time.Now()))
+
+	msg, _ := Handle(context.Background(), session, newMessage("!jeopardy play", "2"), false, util.NewFakeClock(time.Now()))
+	if !strings.Contains(msg.Content, "already a game going") {
+		t.Errorf("expected a second game to be refused, but got '%s'", msg.Content)
+	}
+
+	if trivia.Answer(newMessage("what is osaka", "2")) != nil {
+		t.Errorf("expected wrong answers to be ignored")
+	}
+
+	msg = trivia.Answer(newMessage("What is Tokyo?", "2"))
+	if msg == nil {
+		t.Fatalf("expected a correct answer to be accepted")
+	}
+	if !strings.Contains(msg.Content, "Correct, <@2>! The answer was **Tokyo**. +$200 (you have $200)") {
+		t.Errorf("unexpected correct answer message: '%s'", msg.Content)
+	}
+	if !strings.Contains(msg.Content, "$400 (clue 2 of 2)") {
+		t.Errorf("expected the next clue to be asked, but got '%s'", msg.Content)
+	}
+
+	msg = trivia.Answer(newMessage("paris", "3"))
+	if !strings.Contains(msg.Content, "That's the end of the game!") ||
+		!strings.Contains(msg.Content, "1. user3: $400\n2. user2: $200\n") {
+		t.Errorf("expected the final scores, but got '%s'", msg.Content)
+	}
+
+	if trivia.Running("channel") {
+		t.Errorf("expected the game to be over")
+	}
+	if trivia.Answer(newMessage("paris", "3")) != nil {
+		t.Errorf("expected answers to be ignored after the game")
+	}
+}
+
+// A source that doesn't answer until it's released, like a slow api.
+type slowSource struct {
+	MockSource
+	fetching chan struct{}
+	release  chan struct{}
+}
+
+func (s *slowSource) RandomCategory(ctx context.Context) (*JeopardyResponse, error) {
+	close(s.fetching)
+	<-s.release
+	return s.category, nil
+}
+
+func TestSlowFetchDoesNotBlockGames(t *testing.T) {
+	session := &MockSession{}
+	startGame(t, session, "", util.NewFakeClock(time.Now()))
+
+	slow := &slowSource{
+		MockSource: MockSource{category: &JeopardyResponse{
+			Title: "rivers",
+			Clues: []Clue{{Question: "Egypt's river", Answer: "Nile", Value: 200}},
+		}},
+		fetching: make(chan struct{}),
+		release:  make(chan struct{}),
+	}
+	sources = []ClueSource{slow}
+
+	started := make(chan *discordgo.MessageSend)
+	go func() {
+		m := newMessage("!jeopardy play", "1")
+		m.ChannelID = "other"
+		msg, _ := Handle(context.Background(), session, m, false, util.NewFakeClock(time.Now()))
+		started <- msg
+	}()
+	<-slow.fetching
+	t.Cleanup(func() { trivia.Stop("other", "!jeopardy") })
+
+	// The game already going in another channel carries on during the fetch
+	answered := make(chan *discordgo.MessageSend)
+	go func() {
+		answered <- trivia.Answer(newMessage("tokyo", "2"))
+	}()
+	select {
+	case msg := <-answered:
+		if msg == nil {
+			t.Errorf("expected the answer to be judged")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected answers not to wait for another channel's category")
+	}
+
+	close(slow.release)
+	if msg := <-started; !strings.Contains(msg.Content, "Category: rivers") {
+		t.Errorf("expected the new game to start once the category came back, but got '%s'", msg.Content)
+	}
+}
+
+func TestStopAndScores(t *testing.T) {
+	session := &MockSession{}
+	msg, _ := Handle(context.Background(), session, newMessage("!jeopardy scores", "1"), false, util.NewFakeClock(time.Now()))
+	if !strings.Contains(msg.Content, "There's no game going") {
+		t.Errorf("expected no game, but got '%s'", msg.Content)
+	}
+
+	startGame(t, session, "", util.NewFakeClock(time.Now()))
+	trivia.Answer(newMessage("tokyo", "1"))
+
+	msg, _ = Handle(context.Background(), session, newMessage("!jeopardy scores", "1"), false, util.NewFakeClock(time.Now()))
+	if msg.Content != "```Scores:\n1. user1: $200\n```" {
+		t.Errorf("unexpected scores: '%s'", msg.Content)
+	}
+
+	msg, _ = Handle(context.Background(), session, newMessage("!jeopardy stop", "1"), false, util.NewFakeClock(time.Now()))
+	if msg.Content != "Game over!\n```Scores:\n1. user1: $200\n```" {
+		t.Errorf("unexpected stop message: '%s'", msg.Content)
+	}
+	if trivia.Running("channel") {
+		t.Errorf("expected the game to be stopped")
+	}
+}
+
+func TestBankQuestions(t *testing.T) {
+	sources = []ClueSource{&localSource{categories: []JeopardyResponse{
+		{
+			Title: "capitals",
+			Clues: []Clue{
+				{Question: "France's <i>capital</i>", Answer: "Paris", Value: 400},
+				{Question: "Japan's capital", Answer: "Tokyo", Value: 200},
+			},
+		},
+	}}}
+
+	questions, err := Bank{Filter: ValueFilter{Min: 400}}.Questions(context.Background(), "", 0)
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	expected := trivia.Question{Category: "capitals", Prompt: "France's capital", Answer: "Paris", Kind: trivia.FreeText, Value: 400}
+	if len(questions) != 1 || !reflect.DeepEqual(questions[0], expected) {
+		t.Errorf("expected %+v, but got %+v", expected, questions)
+	}
+}
@@ -1,42 +1,87 @@
 package jeopardy
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 
+	"github.com/highsaltlevels/saltbot/lrucache"
+	"github.com/highsaltlevels/saltbot/trivia"
 	"github.com/highsaltlevels/saltbot/util"
 )
 
+// The highest category id on jservice
+const maxCategoryId = 18417
+
+// Random categories to try before giving up on finding clues for a filter
+const maxPickAttempts = 10
+
+const helpMessage string = ("```Jeopardy commands:\n\n" +
+	"\"!jeopardy\" posts a random category with the answers hidden\n" +
+	"\"!jeopardy history\" picks a category with \"history\" in its title\n" +
+	"\"!jeopardy --value 800+\" only shows clues worth $800 or more. Also try\n" +
+	"    \"--value 400\" or \"--value 200-600\"\n" +
+	"\"!jeopardy play\" starts a game in the channel. It takes a category\n" +
+	"    and \"--value\" too\n" +
+	"\"!jeopardy scores\" and \"!jeopardy stop\" for the game in the channel\n" +
+	"\"!jeopardy leaderboard [weekly|monthly|all]\" shows the server's best players\n" +
+	"\"!jeopardy final\" shows when the daily Final Jeopardy is, and admins can\n" +
+	"    use \"!jeopardy final here\" or \"!jeopardy final off\" to set its channel\n" +
+	"\"!jeopardy wager <amount> ||<answer>||\" to play Final Jeopardy```")
+
+// The flags understood by "!jeopardy"
+var flagSpec = util.FlagSpec{"--value": true}
+
 var client util.HttpClientInterface
 
+// Categories keyed on their id. TTL is set with JEOPARDY_CACHE_TTL.
+var categoryCache *lrucache.Cache
+
 type Clue struct {
 	Question string `json:"question"`
 	Answer   string `json:"answer"`
+	Value    int    `json:"value"`
+	AirDate  string `json:"airdate"`
 }
 
 type JeopardyResponse struct {
+	Id    int    `json:"id"`
 	Title string `json:"title"`
 	Clues []Clue `json:"clues"`
 }
 
 func init() {
 	if client == nil {
-		client = &http.Client{}
+		client = util.NewResilientClient("jeopardy", util.HttpTimeout, util.SystemClock)
 	}
+
+	categoryCache = lrucache.New("jeopardy", lrucache.DefaultSize, lrucache.TTLFromEnv("JEOPARDY_CACHE_TTL", 24*time.Hour), util.SystemClock)
+	finalTime = parseFinalTime(os.Getenv("JEOPARDY_FINAL_TIME"))
+	finalWindow = util.IntervalFromEnv("JEOPARDY_FINAL_WINDOW", 30*time.Minute, time.Minute)
+	sources = selectSources(os.Getenv("JEOPARDY_SOURCE"), loadLocalSource(os.Getenv("JEOPARDY_DATASET")))
+	trivia.RegisterBank(Bank{})
 }
 
-func Get() (*discordgo.MessageSend, error) {
-	num := rand.Intn(18417)
-	url := fmt.Sprintf("http://jservice.io/api/category?id=%d", num)
+func fetchCategory(ctx context.Context, id int) (*JeopardyResponse, error) {
+	key := strconv.Itoa(id)
+	if cached, ok := categoryCache.Get(key); ok {
+		return cached.(*JeopardyResponse), nil
+	}
+
+	url := fmt.Sprintf("http://jservice.io/api/category?id=%d", id)
 
-	resp, err := client.Get(url)
+	resp, err := client.Get(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("error getting jeopardy questions: %v", err)
+		return nil, fmt.Errorf("error getting jeopardy questions: %w", err)
 	}
 
 	defer resp.Body.Close()
@@ -56,10 +101,125 @@ func Get() (*discordgo.MessageSend, error) {
 		return nil, fmt.Errorf("failed to unmarshal jeopardy response: %v", err)
 	}
 
+	categoryCache.Add(key, &jeopardyResp)
+	return &jeopardyResp, nil
+}
+
+// Handle "!jeopardy". On its own it posts a whole category, while
+// "!jeopardy play" starts a game in the channel. Either can be given a
+// category to search for and a "--value" to filter clues by. Games are timed
+// and leaderboards kept going by the clock.
+func Handle(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate, admin bool, clock util.Clock) (*discordgo.MessageSend, error) {
+	flags, err := util.ParseFlags(m.Content, flagSpec)
+	if err != nil {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```%v```", err),
+		}, nil
+	}
+
+	filter, err := parseValueFilter(flags.Value("--value", ""))
+	if err != nil {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```%v```", err),
+		}, nil
+	}
+
+	terms := flags.Terms
+	if len(terms) > 0 {
+		switch terms[0] {
+		case "play":
+			return play(ctx, s, m, strings.Join(terms[1:], " "), filter, clock)
+		case "stop":
+			return stop(m), nil
+		case "scores":
+			return scores(m), nil
+		case "leaderboard":
+			return leaderboard(terms[1:], m, clock.Now()), nil
+		case "final":
+			return final(ctx, terms[1:], m, admin)
+		case "wager":
+			return wager(terms[1:], m), nil
+		case "help":
+			return &discordgo.MessageSend{
+				Content: helpMessage,
+			}, nil
+		}
+	}
+
+	return Get(ctx, flags.Query(), filter)
+}
+
+// Find a category with clues that match the filter. With a search, only
+// categories with the search in their title are picked from. Returns a nil
+// category if nothing matched.
+func pickCategory(ctx context.Context, search string, filter ValueFilter) (*JeopardyResponse, error) {
+	if search != "" {
+		categories, err := searchCategories(ctx, search)
+		if err != nil {
+			return nil, err
+		}
+
+		rand.Shuffle(len(categories), func(i, j int) {
+			categories[i], categories[j] = categories[j], categories[i]
+		})
+		for _, category := range categories {
+			if clues := filter.apply(category.Clues); len(clues) > 0 {
+				category.Clues = clues
+				return &category, nil
+			}
+		}
+		return nil, nil
+	}
+
+	for i := 0; i < maxPickAttempts; i++ {
+		category, err := randomCategory(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// The category can be the one in categoryCache, so filter a copy.
+		// apply always makes a new slice of clues.
+		if clues := filter.apply(category.Clues); len(clues) > 0 {
+			picked := *category
+			picked.Clues = clues
+			return &picked, nil
+		}
+	}
+	return nil, nil
+}
+
+// Explain why pickCategory didn't find anything.
+func noCategoryMessage(search string, filter ValueFilter) *discordgo.MessageSend {
+	msg := "```Couldn't find a category"
+	if search != "" {
+		msg += fmt.Sprintf(" matching \"%s\"", search)
+	}
+	if filter != (ValueFilter{}) {
+		msg += fmt.Sprintf(" with clues worth %s", filter)
+	}
+
+	return &discordgo.MessageSend{
+		Content: msg + "```",
+	}
+}
+
+func Get(ctx context.Context, search string, filter ValueFilter) (*discordgo.MessageSend, error) {
+	jeopardyResp, err := pickCategory(ctx, search, filter)
+	if err != nil {
+		return nil, err
+	}
+	if jeopardyResp == nil {
+		return noCategoryMessage(search, filter), nil
+	}
+
 	// Build the message string
 	msg := fmt.Sprintf("```The Category is: %s```\n", jeopardyResp.Title)
 	for i, clue := range jeopardyResp.Clues {
-		msg = fmt.Sprintf("%sQuestion %d: %s\nAnswer: ||%s||\n\n", msg, i+1, clue.Question, clue.Answer)
+		details := fmt.Sprintf("$%d", clue.Value)
+		if aired := formatAirDate(clue.AirDate); aired != "" {
+			details += ", aired " + aired
+		}
+		msg = fmt.Sprintf("%sQuestion %d (%s): %s\nAnswer: ||%s||\n\n", msg, i+1, details, trivia.CleanText(clue.Question), trivia.CleanText(clue.Answer))
 	}
 
 	return &discordgo.MessageSend{
@@ -1,6 +1,7 @@
 package jeopardy
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -43,7 +44,7 @@ type MockHttpClient struct {
 	responseCode int
 }
 
-func (c *MockHttpClient) Get(url string) (*http.Response, error) {
+func (c *MockHttpClient) Get(ctx context.Context, url string) (*http.Response, error) {
 	if c.expectError {
 		return nil, errors.New(expectedError)
 	}
@@ -130,6 +131,8 @@ func TestGet(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			categoryCache.Purge()
+			sources = []ClueSource{jserviceSource{}}
 			client = &MockHttpClient{
 				expectError:      tt.shouldClientError,
 				expectIOError:    tt.shouldReadCloserError,
@@ -137,7 +140,7 @@ func TestGet(t *testing.T) {
 				jeopardyResponse: tt.jeopardyResponse,
 			}
 
-			msg, err := Get()
+			msg, err := Get(context.Background(), "", ValueFilter{})
 			if tt.expectedError != nil {
 				if err == nil {
 					t.Errorf("expected error '%v' to be returned but was nil", tt.expectedError)
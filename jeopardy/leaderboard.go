@@ -0,0 +1,235 @@
+package jeopardy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/config"
+	"github.com/highsaltlevels/saltbot/trivia"
+)
+
+// Number of players shown on a leaderboard
+const leaderboardSize = 10
+
+// Leaderboards keyed on guild id. The cache is only read the first time a
+// guild's leaderboard is needed, since our own writes take a moment to show
+// up in it.
+var leaderboards = map[string]*cache.Leaderboard{}
+var leaderboardsLock sync.Mutex
+
+func weekOf(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+func monthOf(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+// Must hold the leaderboards lock.
+func leaderboardFor(guild string) *cache.Leaderboard {
+	if l, ok := leaderboards[guild]; ok {
+		return l
+	}
+
+	// The cached players are shared with the informer, so they're copied
+	// rather than changed in place
+	l := &cache.Leaderboard{Id: guild, Players: map[string]cache.PlayerStats{}}
+	if cached := cache.Cache.GetLeaderboard(guild); cached != nil {
+		for id, stats := range cached.Players {
+			l.Players[id] = stats
+		}
+	}
+
+	leaderboards[guild] = l
+	return l
+}
+
+// Add a game that finished at t to the guild's leaderboard. Results are in the
+// order the clues were asked so that streaks carry across games, and weeks and
+// months go by the guild's time zone.
+func recordGame(guild string, t time.Time, names map[string]string, results map[string][]trivia.Result) {
+	if guild == "" || len(results) == 0 {
+		return
+	}
+	t = t.In(config.Location(guild))
+
+	leaderboardsLock.Lock()
+	defer leaderboardsLock.Unlock()
+
+	l := leaderboardFor(guild)
+	week, month := weekOf(t), monthOf(t)
+	for id, answers := range results {
+		stats := l.Players[id]
+		stats.Name = names[id]
+		if stats.Weekly.Period != week {
+			stats.Weekly = cache.PeriodStats{Period: week}
+		}
+		if stats.Monthly.Period != month {
+			stats.Monthly = cache.PeriodStats{Period: month}
+		}
+
+		for _, answer := range answers {
+			stats.Attempts++
+			stats.Weekly.Attempts++
+			stats.Monthly.Attempts++
+			stats.Score += answer.Points()
+			stats.Weekly.Score += answer.Points()
+			stats.Monthly.Score += answer.Points()
+			if !answer.Correct {
+				stats.Streak = 0
+				continue
+			}
+
+			stats.Correct++
+			stats.Weekly.Correct++
+			stats.Monthly.Correct++
+			stats.Streak++
+			if stats.Streak > stats.BestStreak {
+				stats.BestStreak = stats.Streak
+			}
+		}
+
+		l.Players[id] = stats
+	}
+
+	err := cache.Cache.SetLeaderboard(context.TODO(), l)
+	if err != nil {
+		slog.Error("failed to save jeopardy leaderboard", "guild", guild, "error", err)
+	}
+}
+
+type leaderboardRow struct {
+	name       string
+	score      int
+	correct    int
+	attempts   int
+	streak     int
+	bestStreak int
+}
+
+func (r leaderboardRow) accuracy() int {
+	if r.attempts == 0 {
+		return 0
+	}
+	return r.correct * 100 / r.attempts
+}
+
+// Show a guild's leaderboard for the week or month of t, or of all time.
+func leaderboard(args []string, m *discordgo.MessageCreate, t time.Time) *discordgo.MessageSend {
+	period := "all"
+	if len(args) > 0 {
+		period = strings.ToLower(args[0])
+	}
+
+	t = t.In(config.Location(m.GuildID))
+	var title string
+	switch period {
+	case "weekly":
+		title = fmt.Sprintf("Weekly leaderboard (%s)", weekOf(t))
+	case "monthly":
+		title = fmt.Sprintf("Monthly leaderboard (%s)", t.Format("January 2006"))
+	case "all":
+		title = "All-time leaderboard"
+	default:
+		return &discordgo.MessageSend{
+			Content: "```Pick a leaderboard like: \"!jeopardy leaderboard weekly\", \"monthly\" or \"all\"```",
+		}
+	}
+
+	if m.GuildID == "" {
+		return &discordgo.MessageSend{
+			Content: "```Leaderboards are only kept for servers```",
+		}
+	}
+
+	rows := leaderboardRows(m.GuildID, period, t)
+	if len(rows) == 0 {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```%s:\nNobody has played yet. Type \"!jeopardy play\" to start a game```", title),
+		}
+	}
+
+	msg := fmt.Sprintf("```%s:\n    %-16s %9s %9s %s\n", title, "Player", "Score", "Accuracy", "Streak (best)")
+	for i, row := range rows {
+		msg += fmt.Sprintf("%2d. %-16s %9s %8d%% %d (%d)\n", i+1, truncate(row.name, 16), formatDollars(m.GuildID, row.score),
+			row.accuracy(), row.streak, row.bestStreak)
+	}
+
+	return &discordgo.MessageSend{
+		Content: msg + "```",
+	}
+}
+
+// The top players for a period, best first.
+func leaderboardRows(guild, period string, t time.Time) []leaderboardRow {
+	leaderboardsLock.Lock()
+	defer leaderboardsLock.Unlock()
+
+	t = t.In(config.Location(guild))
+	week, month := weekOf(t), monthOf(t)
+	rows := []leaderboardRow{}
+	for _, stats := range leaderboardFor(guild).Players {
+		row := leaderboardRow{
+			name:       stats.Name,
+			score:      stats.Score,
+			correct:    stats.Correct,
+			attempts:   stats.Attempts,
+			streak:     stats.Streak,
+			bestStreak: stats.BestStreak,
+		}
+
+		var periodStats *cache.PeriodStats
+		switch {
+		case period == "weekly" && stats.Weekly.Period == week:
+			periodStats = &stats.Weekly
+		case period == "monthly" && stats.Monthly.Period == month:
+			periodStats = &stats.Monthly
+		case period != "all":
+			continue
+		}
+		if periodStats != nil {
+			row.score, row.correct, row.attempts = periodStats.Score, periodStats.Correct, periodStats.Attempts
+		}
+
+		if row.attempts > 0 {
+			rows = append(rows, row)
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].score != rows[j].score {
+			return rows[i].score > rows[j].score
+		}
+		if rows[i].accuracy() != rows[j].accuracy() {
+			return rows[i].accuracy() > rows[j].accuracy()
+		}
+		return rows[i].name < rows[j].name
+	})
+
+	if len(rows) > leaderboardSize {
+		rows = rows[:leaderboardSize]
+	}
+	return rows
+}
+
+// Format a score like "$12,400" or "-$200" in the guild's locale.
+func formatDollars(guild string, amount int) string {
+	return trivia.FormatScore(amount, true, config.Language(guild))
+}
+
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	return string(runes[:length-1]) + "…"
+}
@@ -0,0 +1,222 @@
+package jeopardy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/testutil"
+	"github.com/highsaltlevels/saltbot/trivia"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+// Start each test with empty leaderboards on a fixed day
+func resetLeaderboards(day time.Time) *util.FakeClock {
+	cache.Cache = cache.NewInMemConfigMapCache(map[string]cache.Poll{}, map[string]cache.Reminder{})
+	cache.Client = &testutil.MockK8sClient{}
+	leaderboards = map[string]*cache.Leaderboard{}
+	return util.NewFakeClock(day)
+}
+
+func TestRecordGame(t *testing.T) {
+	clock := resetLeaderboards(time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC))
+	names := map[string]string{"1": "salty", "2": "pepper"}
+
+	recordGame("guild", clock.Now(), names, map[string][]trivia.Result{
+		"1": {{Correct: true, Value: 200}, {Correct: true, Value: 400}, {Correct: false, Value: 600}, {Correct: true, Value: 800}},
+		"2": {{Correct: false, Value: 200}},
+	})
+	recordGame("guild", clock.Now(), names, map[string][]trivia.Result{
+		"1": {{Correct: true, Value: 200}},
+	})
+
+	stats := leaderboards["guild"].Players["1"]
+	if stats.Score != 1600 || stats.Correct != 4 || stats.Attempts != 5 {
+		t.Errorf("unexpected totals: %+v", stats)
+	}
+	if stats.Streak != 2 || stats.BestStreak != 2 {
+		t.Errorf("expected streak 2 and best streak 2, but got %d and %d", stats.Streak, stats.BestStreak)
+	}
+	if stats.Weekly.Period != "2023-W27" || stats.Weekly.Score != 1600 {
+		t.Errorf("unexpected weekly stats: %+v", stats.Weekly)
+	}
+
+	// A new week starts the weekly stats over but keeps the monthly ones
+	clock.Advance(7 * 24 * time.Hour)
+	recordGame("guild", clock.Now(), names, map[string][]trivia.Result{
+		"1": {{Correct: true, Value: 1000}},
+	})
+	stats = leaderboards["guild"].Players["1"]
+	if stats.Weekly.Period != "2023-W28" || stats.Weekly.Score != 1000 || stats.Weekly.Attempts != 1 {
+		t.Errorf("expected weekly stats to start over, but got %+v", stats.Weekly)
+	}
+	if stats.Monthly.Score != 2600 || stats.Score != 2600 || stats.BestStreak != 3 {
+		t.Errorf("unexpected stats after a new week: %+v", stats)
+	}
+
+	// DMs don't have a leaderboard
+	recordGame("", clock.Now(), names, map[string][]trivia.Result{"1": {{Correct: true, Value: 200}}})
+	if _, ok := leaderboards[""]; ok {
+		t.Errorf("expected no leaderboard without a guild")
+	}
+}
+
+func TestLeaderboardLoadsFromCache(t *testing.T) {
+	clock := resetLeaderboards(time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC))
+	saved := cache.Leaderboard{
+		Id: "guild",
+		Players: map[string]cache.PlayerStats{
+			"1": {Name: "salty", Score: 5000, Correct: 10, Attempts: 20},
+		},
+	}
+	configMap, _ := saved.ToConfigMap()
+	cache.Cache.Store(configMap)
+
+	recordGame("guild", clock.Now(), map[string]string{"1": "salty"}, map[string][]trivia.Result{"1": {{Correct: true, Value: 200}}})
+	if score := leaderboards["guild"].Players["1"].Score; score != 5200 {
+		t.Errorf("expected saved score to be added to, but got %d", score)
+	}
+
+	// The cache's copy only changes once the informer sees the write
+	if score := cache.Cache.GetLeaderboard("guild").Players["1"].Score; score != 5000 {
+		t.Errorf("expected the cached leaderboard to be left alone, but got a score of %d", score)
+	}
+}
+
+func TestLeaderboardCommand(t *testing.T) {
+	clock := resetLeaderboards(time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC))
+	names := map[string]string{"1": "salty", "2": "pepper", "3": "a-very-long-username-indeed"}
+	recordGame("guild", clock.Now(), names, map[string][]trivia.Result{
+		"1": {{Correct: true, Value: 200}, {Correct: false, Value: 400}},
+		"2": {{Correct: true, Value: 1000}, {Correct: true, Value: 200}},
+	})
+	clock.Advance(7 * 24 * time.Hour)
+	recordGame("guild", clock.Now(), names, map[string][]trivia.Result{
+		"3": {{Correct: true, Value: 12000}},
+	})
+
+	tests := []struct {
+		name     string
+		command  string
+		guild    string
+		expected string
+	}{
+		{
+			name:    "Test all-time leaderboard",
+			command: "!jeopardy leaderboard",
+			guild:   "guild",
+			expected: "```All-time leaderboard:\n" +
+				"    Player               Score  Accuracy Streak (best)\n" +
+				" 1. a-very-long-use…   $12,000      100% 1 (1)\n" +
+				" 2. pepper              $1,200      100% 2 (2)\n" +
+				" 3. salty                 $200       50% 0 (1)\n```",
+		},
+		{
+			name:    "Test weekly leaderboard",
+			command: "!jeopardy leaderboard weekly",
+			guild:   "guild",
+			expected: "```Weekly leaderboard (2023-W28):\n" +
+				"    Player               Score  Accuracy Streak (best)\n" +
+				" 1. a-very-long-use…   $12,000      100% 1 (1)\n```",
+		},
+		{
+			name:    "Test monthly leaderboard",
+			command: "!jeopardy leaderboard Monthly",
+			guild:   "guild",
+			expected: "```Monthly leaderboard (July 2023):\n" +
+				"    Player               Score  Accuracy Streak (best)\n" +
+				" 1. a-very-long-use…   $12,000      100% 1 (1)\n" +
+				" 2. pepper              $1,200      100% 2 (2)\n" +
+				" 3. salty                 $200       50% 0 (1)\n```",
+		},
+		{
+			name:     "Test empty leaderboard",
+			command:  "!jeopardy leaderboard",
+			guild:    "other",
+			expected: "```All-time leaderboard:\nNobody has played yet. Type \"!jeopardy play\" to start a game```",
+		},
+		{
+			name:     "Test leaderboard in a DM",
+			command:  "!jeopardy leaderboard",
+			expected: "```Leaderboards are only kept for servers```",
+		},
+		{
+			name:     "Test unknown leaderboard",
+			command:  "!jeopardy leaderboard daily",
+			guild:    "guild",
+			expected: "```Pick a leaderboard like: \"!jeopardy leaderboard weekly\", \"monthly\" or \"all\"```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMessage(tt.command, "1")
+			m.GuildID = tt.guild
+			msg, err := Handle(context.Background(), &MockSession{}, m, false, clock)
+			if err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+			if msg.Content != tt.expected {
+				t.Errorf("expected message:\n%s\nbut got:\n%s", tt.expected, msg.Content)
+			}
+		})
+	}
+}
+
+func TestGameRecordsLeaderboard(t *testing.T) {
+	clock := resetLeaderboards(time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC))
+	session := &MockSession{}
+	startGame(t, session, "guild", clock)
+
+	// A wrong guess then the right one still counts as a single correct attempt
+	trivia.Answer(newMessage("osaka", "1"))
+	trivia.Answer(newMessage("osaka", "2"))
+	trivia.Answer(newMessage("tokyo", "1"))
+	Handle(context.Background(), session, newMessage("!jeopardy stop", "1"), false, clock)
+
+	players := leaderboards["guild"].Players
+	if players["1"].Correct != 1 || players["1"].Attempts != 1 || players["1"].Score != 200 {
+		t.Errorf("unexpected stats for player 1: %+v", players["1"])
+	}
+	if players["2"].Correct != 0 || players["2"].Attempts != 1 || players["2"].Name != "user2" {
+		t.Errorf("unexpected stats for player 2: %+v", players["2"])
+	}
+}
+
+func TestLeaderboardInGuildSettings(t *testing.T) {
+	// Sunday night in Chicago, but already Monday in UTC
+	clock := resetLeaderboards(time.Date(2023, 7, 10, 3, 0, 0, 0, time.UTC))
+	settings := cache.GuildConfig{Id: "german", Timezone: "America/Chicago", Locale: "de"}
+	configMap, _ := settings.ToConfigMap()
+	cache.Cache.Store(configMap)
+
+	recordGame("german", clock.Now(), map[string]string{"1": "salty"}, map[string][]trivia.Result{
+		"1": {{Correct: true, Value: 1200}},
+	})
+	if stats := leaderboards["german"].Players["1"]; stats.Weekly.Period != "2023-W27" {
+		t.Errorf("expected the game in the guild's week 2023-W27, but got %+v", stats.Weekly)
+	}
+
+	m := newMessage("!jeopardy leaderboard weekly", "1")
+	m.GuildID = "german"
+	msg, err := Handle(context.Background(), &MockSession{}, m, false, clock)
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	expected := "```Weekly leaderboard (2023-W27):\n" +
+		"    Player               Score  Accuracy Streak (best)\n" +
+		" 1. salty               $1.200      100% 1 (1)\n```"
+	if msg.Content != expected {
+		t.Errorf("expected message:\n%s\nbut got:\n%s", expected, msg.Content)
+	}
+}
+
+func TestFormatDollars(t *testing.T) {
+	tests := map[int]string{0: "$0", 200: "$200", 1200: "$1,200", 1234567: "$1,234,567", -400: "-$400"}
+	for amount, expected := range tests {
+		if actual := formatDollars("guild", amount); actual != expected {
+			t.Errorf("expected %s, but got %s", expected, actual)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package jeopardy
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/rand"
+	"os"
+	"strings"
+
+	"github.com/highsaltlevels/saltbot/logging"
+)
+
+// A small set of categories so that jeopardy works without any network or
+// mounted dataset.
+//
+//go:embed data/clues.json
+var bundledDataset []byte
+
+// Returned by sources that can't look categories up by title.
+var errSearchUnsupported = errors.New("searching categories isn't supported")
+
+// Somewhere to get jeopardy categories from.
+type ClueSource interface {
+	Name() string
+	RandomCategory(ctx context.Context) (*JeopardyResponse, error)
+
+	// Categories with query somewhere in their title
+	SearchCategories(ctx context.Context, query string) ([]JeopardyResponse, error)
+}
+
+// Sources in the order they're tried. The first is the primary source.
+var sources []ClueSource
+
+// Pick which source is primary from JEOPARDY_SOURCE. The local dataset is
+// always kept as a fallback when jservice is primary.
+func selectSources(primary string, local ClueSource) []ClueSource {
+	switch strings.ToLower(primary) {
+	case "", "local":
+		return []ClueSource{local}
+	case "jservice":
+		return []ClueSource{jserviceSource{}, local}
+	}
+
+	slog.Warn("unknown jeopardy source, using the local dataset", "source", primary)
+	return []ClueSource{local}
+}
+
+// Get a random category from the first source that has one.
+func randomCategory(ctx context.Context) (*JeopardyResponse, error) {
+	var err error
+	for _, source := range sources {
+		var category *JeopardyResponse
+		category, err = source.RandomCategory(ctx)
+		if err == nil {
+			return category, nil
+		}
+		logging.From(ctx).Warn("jeopardy source failed", "source", source.Name(), "error", err)
+	}
+
+	if err == nil {
+		err = errors.New("no jeopardy sources are configured")
+	}
+	return nil, err
+}
+
+// Search the first source that supports it.
+func searchCategories(ctx context.Context, query string) ([]JeopardyResponse, error) {
+	var err error
+	for _, source := range sources {
+		var categories []JeopardyResponse
+		categories, err = source.SearchCategories(ctx, query)
+		if err == nil {
+			return categories, nil
+		}
+		if !errors.Is(err, errSearchUnsupported) {
+			logging.From(ctx).Warn("jeopardy source failed to search", "source", source.Name(), "error", err)
+		}
+	}
+
+	return nil, err
+}
+
+// Categories read from a JSON dataset, either the bundled one or a file
+// mounted at JEOPARDY_DATASET.
+type localSource struct {
+	categories []JeopardyResponse
+}
+
+func newLocalSource(data []byte) (*localSource, error) {
+	var categories []JeopardyResponse
+	err := json.Unmarshal(data, &categories)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal jeopardy dataset: %w", err)
+	}
+
+	// Skip anything that can't be shown
+	playable := []JeopardyResponse{}
+	for _, category := range categories {
+		if len(playableClues(category.Clues)) > 0 {
+			playable = append(playable, category)
+		}
+	}
+
+	if len(playable) == 0 {
+		return nil, errors.New("jeopardy dataset has no categories with clues")
+	}
+
+	return &localSource{categories: playable}, nil
+}
+
+// Load the dataset at path, falling back to the bundled dataset if path is
+// empty or can't be loaded.
+func loadLocalSource(path string) *localSource {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			var local *localSource
+			if local, err = newLocalSource(data); err == nil {
+				slog.Info("loaded jeopardy categories", "count", len(local.categories), "path", path)
+				return local
+			}
+		}
+		slog.Warn("failed to load jeopardy dataset, using the bundled one", "path", path, "error", err)
+	}
+
+	local, err := newLocalSource(bundledDataset)
+	if err != nil {
+		log.Fatalf("bundled jeopardy dataset is broken: %v", err)
+	}
+	return local
+}
+
+func (l *localSource) Name() string {
+	return "local"
+}
+
+func (l *localSource) RandomCategory(ctx context.Context) (*JeopardyResponse, error) {
+	category := l.categories[rand.Intn(len(l.categories))]
+	return &category, nil
+}
+
+func (l *localSource) SearchCategories(ctx context.Context, query string) ([]JeopardyResponse, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	matching := []JeopardyResponse{}
+	for _, category := range l.categories {
+		if strings.Contains(strings.ToLower(category.Title), query) {
+			matching = append(matching, category)
+		}
+	}
+
+	return matching, nil
+}
+
+// The jservice.io API. It has far more categories than the local dataset,
+// but has been unreliable.
+type jserviceSource struct{}
+
+func (j jserviceSource) Name() string {
+	return "jservice"
+}
+
+func (j jserviceSource) RandomCategory(ctx context.Context) (*JeopardyResponse, error) {
+	return fetchCategory(ctx, rand.Intn(maxCategoryId))
+}
+
+// jservice has no way to search categories by title.
+func (j jserviceSource) SearchCategories(ctx context.Context, query string) ([]JeopardyResponse, error) {
+	return nil, errSearchUnsupported
+}
@@ -0,0 +1,165 @@
+package jeopardy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type MockSource struct {
+	name     string
+	category *JeopardyResponse
+	err      error
+	calls    int
+}
+
+func (s *MockSource) Name() string {
+	return s.name
+}
+
+func (s *MockSource) RandomCategory(ctx context.Context) (*JeopardyResponse, error) {
+	s.calls++
+	return s.category, s.err
+}
+
+func (s *MockSource) SearchCategories(ctx context.Context, query string) ([]JeopardyResponse, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []JeopardyResponse{*s.category}, nil
+}
+
+func TestBundledDataset(t *testing.T) {
+	local, err := newLocalSource(bundledDataset)
+	if err != nil {
+		t.Fatalf("expected the bundled dataset to load but got: %v", err)
+	}
+
+	for _, category := range local.categories {
+		if category.Title == "" {
+			t.Errorf("category %d has no title", category.Id)
+		}
+		for _, clue := range category.Clues {
+			if clue.Question == "" || clue.Answer == "" || clue.Value == 0 || clue.AirDate == "" {
+				t.Errorf("incomplete clue in %s: %+v", category.Title, clue)
+			}
+		}
+	}
+}
+
+func TestLoadLocalSource(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	os.WriteFile(valid, []byte(`[{"id": 7, "title": "mounted", "clues": [{"question": "q", "answer": "a", "value": 200}]}]`), 0644)
+	empty := filepath.Join(dir, "empty.json")
+	os.WriteFile(empty, []byte(`[{"id": 8, "title": "no clues", "clues": []}]`), 0644)
+
+	bundled, _ := newLocalSource(bundledDataset)
+	tests := []struct {
+		name          string
+		path          string
+		expectedCount int
+	}{
+		{name: "Test bundled dataset by default", path: "", expectedCount: len(bundled.categories)},
+		{name: "Test mounted dataset", path: valid, expectedCount: 1},
+		{name: "Test missing dataset falls back", path: filepath.Join(dir, "missing.json"), expectedCount: len(bundled.categories)},
+		{name: "Test dataset without clues falls back", path: empty, expectedCount: len(bundled.categories)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := loadLocalSource(tt.path)
+			if len(local.categories) != tt.expectedCount {
+				t.Errorf("expected %d categories, but got %d", tt.expectedCount, len(local.categories))
+			}
+		})
+	}
+}
+
+func TestSelectSources(t *testing.T) {
+	local := &localSource{}
+	tests := []struct {
+		primary  string
+		expected []string
+	}{
+		{primary: "", expected: []string{"local"}},
+		{primary: "local", expected: []string{"local"}},
+		{primary: "JService", expected: []string{"jservice", "local"}},
+		{primary: "bogus", expected: []string{"local"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.primary, func(t *testing.T) {
+			names := []string{}
+			for _, source := range selectSources(tt.primary, local) {
+				names = append(names, source.Name())
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected sources %v, but got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestRandomCategoryFallback(t *testing.T) {
+	failing := &MockSource{name: "failing", err: errors.New(expectedError)}
+	working := &MockSource{name: "working", category: &JeopardyResponse{Title: "fallback"}}
+
+	sources = []ClueSource{failing, working}
+	category, err := randomCategory(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if category.Title != "fallback" || failing.calls != 1 || working.calls != 1 {
+		t.Errorf("expected to fall back to the working source, got %+v", category)
+	}
+
+	sources = []ClueSource{failing}
+	_, err = randomCategory(context.Background())
+	if err == nil || err.Error() != expectedError {
+		t.Errorf("expected error '%s', but got '%v'", expectedError, err)
+	}
+}
+
+func TestJserviceFallsBackToLocal(t *testing.T) {
+	categoryCache.Purge()
+	client = &MockHttpClient{expectError: true, responseCode: http.StatusOK}
+	sources = selectSources("jservice", loadLocalSource(""))
+
+	msg, err := Get(context.Background(), "", ValueFilter{})
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if !strings.Contains(msg.Content, "The Category is:") {
+		t.Errorf("expected a category from the local dataset, but got '%s'", msg.Content)
+	}
+}
+
+func TestSearchCategories(t *testing.T) {
+	local, _ := newLocalSource(bundledDataset)
+	sources = []ClueSource{jserviceSource{}, local}
+
+	categories, err := searchCategories(context.Background(), "  Capitals ")
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if len(categories) != 1 || categories[0].Title != "state capitals" {
+		t.Errorf("expected to find state capitals, but got %+v", categories)
+	}
+
+	categories, _ = searchCategories(context.Background(), "no such category")
+	if len(categories) != 0 {
+		t.Errorf("expected no categories, but got %d", len(categories))
+	}
+
+	sources = []ClueSource{jserviceSource{}}
+	_, err = searchCategories(context.Background(), "capitals")
+	if !errors.Is(err, errSearchUnsupported) {
+		t.Errorf("expected search to be unsupported, but got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// The lowest level that's logged. Set with SALTBOT_LOG_LEVEL to one of
+// debug, info, warn or error.
+var Level = new(slog.LevelVar)
+
+func init() {
+	value, ok := os.LookupEnv("SALTBOT_LOG_LEVEL")
+	if !ok {
+		return
+	}
+
+	err := Level.UnmarshalText([]byte(strings.ToUpper(value)))
+	if err != nil {
+		log.Printf("invalid SALTBOT_LOG_LEVEL %q, using info: %v", value, err)
+	}
+}
+
+// Log JSON to w, including anything still logged with the log package.
+func Setup(w io.Writer) {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: Level})))
+}
+
+type key struct{}
+
+type trace struct {
+	id     string
+	logger *slog.Logger
+}
+
+// Start a trace with a new correlation id. Everything logged with the
+// context's logger has the id and attrs.
+func Start(ctx context.Context, attrs ...any) context.Context {
+	id := uuid.NewString()
+	return context.WithValue(ctx, key{}, trace{
+		id:     id,
+		logger: slog.Default().With("correlation_id", id).With(attrs...),
+	})
+}
+
+// Add attrs to the context's logger, keeping its correlation id.
+func With(ctx context.Context, attrs ...any) context.Context {
+	t, ok := ctx.Value(key{}).(trace)
+	if !ok {
+		return ctx
+	}
+
+	t.logger = t.logger.With(attrs...)
+	return context.WithValue(ctx, key{}, t)
+}
+
+// The context's logger, or the default one outside of a trace.
+func From(ctx context.Context) *slog.Logger {
+	if t, ok := ctx.Value(key{}).(trace); ok {
+		return t.logger
+	}
+	return slog.Default()
+}
+
+// The context's correlation id, or "" outside of a trace.
+func ID(ctx context.Context) string {
+	t, _ := ctx.Value(key{}).(trace)
+	return t.id
+}
@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.Default()
+	Setup(&buf)
+	t.Cleanup(func() { slog.SetDefault(logger) })
+
+	ctx := Start(context.Background(), "guild", "guild")
+	ctx = With(ctx, "command", "gif")
+	From(ctx).Info("hello")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected JSON, but got %q: %v", buf.String(), err)
+	}
+
+	if ID(ctx) == "" || entry["correlation_id"] != ID(ctx) {
+		t.Errorf("expected correlation id %q, but got %+v", ID(ctx), entry)
+	}
+	if entry["guild"] != "guild" || entry["command"] != "gif" || entry["msg"] != "hello" {
+		t.Errorf("expected the attrs to be logged, but got %+v", entry)
+	}
+}
+
+func TestOutsideTrace(t *testing.T) {
+	ctx := With(context.Background(), "command", "gif")
+	if ID(ctx) != "" {
+		t.Errorf("expected no correlation id, but got %s", ID(ctx))
+	}
+	if From(ctx) != slog.Default() {
+		t.Errorf("expected the default logger outside of a trace")
+	}
+}
+
+func TestLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.Default()
+	Setup(&buf)
+	t.Cleanup(func() {
+		slog.SetDefault(logger)
+		Level.Set(slog.LevelInfo)
+	})
+
+	Level.Set(slog.LevelWarn)
+	slog.Info("quiet")
+	if buf.Len() != 0 {
+		t.Errorf("expected info to be dropped at warn, but got %q", buf.String())
+	}
+
+	slog.Warn("loud")
+	if buf.Len() == 0 {
+		t.Errorf("expected warn to be logged")
+	}
+}
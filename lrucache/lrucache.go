@@ -0,0 +1,187 @@
+package lrucache
+
+import (
+	"container/list"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/highsaltlevels/saltbot/metrics"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+// Number of entries each provider cache holds unless told otherwise.
+const DefaultSize = 256
+
+type entry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// Hit and miss counters for a cache.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// A size bounded cache where entries also expire after a TTL. When the cache
+// is full, the least recently used entry is evicted.
+type Cache struct {
+	name    string
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List
+	stats   Stats
+	clock   util.Clock
+	lock    sync.Mutex
+}
+
+// Every cache by name, for metrics. A new cache replaces one with its name.
+var (
+	cachesLock sync.Mutex
+	caches     = map[string]*Cache{}
+)
+
+func init() {
+	metrics.NewCounterVecFunc("saltbot_response_cache_hits_total", "Lookups found in each response cache.",
+		[]string{"cache"}, func() []metrics.Sample {
+			return samples(func(c *Cache) float64 { return float64(c.Stats().Hits) })
+		})
+	metrics.NewCounterVecFunc("saltbot_response_cache_misses_total", "Lookups missing from each response cache.",
+		[]string{"cache"}, func() []metrics.Sample {
+			return samples(func(c *Cache) float64 { return float64(c.Stats().Misses) })
+		})
+	metrics.NewGaugeVecFunc("saltbot_response_cache_entries", "Entries in each response cache.",
+		[]string{"cache"}, func() []metrics.Sample {
+			return samples(func(c *Cache) float64 { return float64(c.Len()) })
+		})
+}
+
+func samples(value func(*Cache) float64) []metrics.Sample {
+	cachesLock.Lock()
+	defer cachesLock.Unlock()
+
+	var s []metrics.Sample
+	for name, c := range caches {
+		s = append(s, metrics.Sample{Labels: []string{name}, Value: value(c)})
+	}
+	return s
+}
+
+// Create a cache. Entries expire going by the clock.
+func New(name string, size int, ttl time.Duration, clock util.Clock) *Cache {
+	c := &Cache{
+		name:    name,
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+		clock:   clock,
+	}
+
+	cachesLock.Lock()
+	defer cachesLock.Unlock()
+	caches[name] = c
+	return c
+}
+
+// Get a value from the cache. Expired entries are treated as a miss.
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	e := elem.Value.(*entry)
+	if c.clock.Now().After(e.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	c.stats.Hits++
+	return e.value, true
+}
+
+// Add or replace a value in the cache. A TTL or size of 0 disables caching.
+func (c *Cache) Add(key string, value interface{}) {
+	if c.ttl <= 0 || c.size <= 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	expires := c.clock.Now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*entry)
+		e.value = value
+		e.expires = expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+}
+
+// Remove every entry and reset the counters.
+func (c *Cache) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.entries = make(map[string]*list.Element, c.size)
+	c.order.Init()
+	c.stats = Stats{}
+}
+
+func (c *Cache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.order.Len()
+}
+
+func (c *Cache) Name() string {
+	return c.name
+}
+
+func (c *Cache) Stats() Stats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.stats
+}
+
+// Normalize a search query so that "Dog", "dog " and "DOG" all share an entry.
+func NormalizeKey(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// Read a TTL such as "10m" or "1h" from an env var, falling back to def.
+func TTLFromEnv(envVar string, def time.Duration) time.Duration {
+	value, ok := os.LookupEnv(envVar)
+	if !ok {
+		return def
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Warn("invalid duration, using the default", "env", envVar, "value", value, "default", def, "error", err)
+		return def
+	}
+
+	return ttl
+}
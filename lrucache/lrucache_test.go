@@ -0,0 +1,146 @@
+package lrucache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name           string
+		size           int
+		ttl            time.Duration
+		adds           []string
+		elapsed        time.Duration
+		key            string
+		expectedFound  bool
+		expectedStats  Stats
+		expectedLength int
+	}{
+		{
+			name:           "Test cache hit",
+			size:           2,
+			ttl:            time.Minute,
+			adds:           []string{"foo"},
+			key:            "foo",
+			expectedFound:  true,
+			expectedStats:  Stats{Hits: 1},
+			expectedLength: 1,
+		},
+		{
+			name:           "Test cache miss",
+			size:           2,
+			ttl:            time.Minute,
+			adds:           []string{"foo"},
+			key:            "bar",
+			expectedStats:  Stats{Misses: 1},
+			expectedLength: 1,
+		},
+		{
+			name:           "Test expired entry is a miss and is removed",
+			size:           2,
+			ttl:            time.Minute,
+			adds:           []string{"foo"},
+			elapsed:        2 * time.Minute,
+			key:            "foo",
+			expectedStats:  Stats{Misses: 1},
+			expectedLength: 0,
+		},
+		{
+			name:           "Test least recently used entry is evicted",
+			size:           2,
+			ttl:            time.Minute,
+			adds:           []string{"foo", "bar", "baz"},
+			key:            "foo",
+			expectedStats:  Stats{Misses: 1},
+			expectedLength: 2,
+		},
+		{
+			name:           "Test zero ttl disables caching",
+			size:           2,
+			ttl:            0,
+			adds:           []string{"foo"},
+			key:            "foo",
+			expectedStats:  Stats{Misses: 1},
+			expectedLength: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := util.NewFakeClock(time.Unix(0, 0))
+			c := New("test", tt.size, tt.ttl, clock)
+
+			for _, key := range tt.adds {
+				c.Add(key, key+"-value")
+			}
+			clock.Advance(tt.elapsed)
+
+			value, found := c.Get(tt.key)
+			if found != tt.expectedFound {
+				t.Errorf("expected found to be %v, but got %v", tt.expectedFound, found)
+			}
+			if found && value != tt.key+"-value" {
+				t.Errorf("expected value '%s-value', but got '%v'", tt.key, value)
+			}
+			if c.Stats() != tt.expectedStats {
+				t.Errorf("expected stats %+v, but got %+v", tt.expectedStats, c.Stats())
+			}
+			if c.Len() != tt.expectedLength {
+				t.Errorf("expected %d entries, but got %d", tt.expectedLength, c.Len())
+			}
+		})
+	}
+}
+
+func TestGetRefreshesRecency(t *testing.T) {
+	c := New("test", 2, time.Minute, util.SystemClock)
+	c.Add("foo", 1)
+	c.Add("bar", 2)
+
+	// Touching foo makes bar the least recently used entry
+	c.Get("foo")
+	c.Add("baz", 3)
+
+	if _, found := c.Get("bar"); found {
+		t.Errorf("expected bar to be evicted")
+	}
+	if _, found := c.Get("foo"); !found {
+		t.Errorf("expected foo to still be cached")
+	}
+}
+
+func TestNormalizeKey(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{query: "dog", expected: "dog"},
+		{query: "  Funny   DOG ", expected: "funny dog"},
+		{query: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		if actual := NormalizeKey(tt.query); actual != tt.expected {
+			t.Errorf("expected '%s' to normalize to '%s', but got '%s'", tt.query, tt.expected, actual)
+		}
+	}
+}
+
+func TestTTLFromEnv(t *testing.T) {
+	t.Setenv("TEST_CACHE_TTL", "5m")
+	if ttl := TTLFromEnv("TEST_CACHE_TTL", time.Hour); ttl != 5*time.Minute {
+		t.Errorf("expected 5m, but got %s", ttl)
+	}
+
+	t.Setenv("TEST_CACHE_TTL", "not a duration")
+	if ttl := TTLFromEnv("TEST_CACHE_TTL", time.Hour); ttl != time.Hour {
+		t.Errorf("expected fallback of 1h, but got %s", ttl)
+	}
+
+	if ttl := TTLFromEnv("TEST_CACHE_TTL_UNSET", time.Hour); ttl != time.Hour {
+		t.Errorf("expected fallback of 1h, but got %s", ttl)
+	}
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Latency buckets in seconds, from a cached lookup to a slow API call.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// One value of a metric read when it's scraped, with a value per label.
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// Metrics with labels kept somewhere else, like per cache hit counts, read
+// when they're scraped. client_golang's func metrics can't have labels.
+type vecFunc struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	read      func() []Sample
+}
+
+func (v *vecFunc) Describe(ch chan<- *prometheus.Desc) {
+	ch <- v.desc
+}
+
+func (v *vecFunc) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range v.read() {
+		ch <- prometheus.MustNewConstMetric(v.desc, v.valueType, s.Value, s.Labels...)
+	}
+}
+
+func newVecFunc(name, help string, valueType prometheus.ValueType, labels []string, read func() []Sample) {
+	prometheus.MustRegister(&vecFunc{
+		desc:      prometheus.NewDesc(name, help, labels, nil),
+		valueType: valueType,
+		read:      read,
+	})
+}
+
+// A counter with labels kept somewhere else, read when it's scraped. Each
+// sample has a value for each label.
+func NewCounterVecFunc(name, help string, labels []string, read func() []Sample) {
+	newVecFunc(name, help, prometheus.CounterValue, labels, read)
+}
+
+// A gauge with labels read when it's scraped.
+func NewGaugeVecFunc(name, help string, labels []string, read func() []Sample) {
+	newVecFunc(name, help, prometheus.GaugeValue, labels, read)
+}
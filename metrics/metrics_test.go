@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestVecFuncs(t *testing.T) {
+	hits := []Sample{{Labels: []string{"youtube"}, Value: 2}, {Labels: []string{"giphy"}, Value: 1}}
+	NewCounterVecFunc("test_hits_total", "Read when scraped.", []string{"cache"}, func() []Sample {
+		return hits
+	})
+	NewGaugeVecFunc("test_entries", "Read when scraped.", []string{"cache"}, func() []Sample {
+		return []Sample{{Labels: []string{"giphy"}, Value: 7}}
+	})
+
+	expected := `# HELP test_entries Read when scraped.
+# TYPE test_entries gauge
+test_entries{cache="giphy"} 7
+# HELP test_hits_total Read when scraped.
+# TYPE test_hits_total counter
+test_hits_total{cache="giphy"} 1
+test_hits_total{cache="youtube"} 2
+`
+	err := testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(expected), "test_entries", "test_hits_total")
+	if err != nil {
+		t.Error(err)
+	}
+
+	// The values are read again on every scrape
+	hits = hits[:1]
+	expected = `# HELP test_hits_total Read when scraped.
+# TYPE test_hits_total counter
+test_hits_total{cache="youtube"} 2
+`
+	err = testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(expected), "test_hits_total")
+	if err != nil {
+		t.Error(err)
+	}
+}
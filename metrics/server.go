@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Where /metrics, /healthz and /readyz are served. Set with SALTBOT_METRICS_ADDR.
+var Addr = ":8080"
+
+func init() {
+	if addr, ok := os.LookupEnv("SALTBOT_METRICS_ADDR"); ok {
+		Addr = addr
+	}
+}
+
+var (
+	checksLock sync.Mutex
+	checks     = map[string]func() error{}
+)
+
+// Add a check that has to pass for saltbot to be ready, like being connected
+// to discord. The error says what's wrong.
+func AddCheck(name string, check func() error) {
+	checksLock.Lock()
+	defer checksLock.Unlock()
+	checks[name] = check
+}
+
+// Run the readiness checks, returning a message for each one that failed.
+func failing() []string {
+	checksLock.Lock()
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	current := make(map[string]func() error, len(checks))
+	for name, check := range checks {
+		current[name] = check
+	}
+	checksLock.Unlock()
+
+	sort.Strings(names)
+	var failed []string
+	for _, name := range names {
+		if err := current[name](); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	return failed
+}
+
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	// Saltbot is alive as long as it can answer
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		failed := failing()
+		if len(failed) > 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			for _, f := range failed {
+				fmt.Fprintln(w, f)
+			}
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+
+	return mux
+}
+
+// Serve the endpoints on addr until the context is done.
+func Serve(ctx context.Context, addr string) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	slog.Info("serving metrics and health checks", "addr", addr)
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+func get(t *testing.T, server *httptest.Server, path string) (int, string) {
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatalf("failed to get %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestHandler(t *testing.T) {
+	var connected, synced bool
+	AddCheck("discord", func() error {
+		if !connected {
+			return errors.New("not connected")
+		}
+		return nil
+	})
+	AddCheck("cache", func() error {
+		if !synced {
+			return errors.New("not synced")
+		}
+		return nil
+	})
+	t.Cleanup(func() {
+		checksLock.Lock()
+		defer checksLock.Unlock()
+		checks = map[string]func() error{}
+	})
+	promauto.NewCounter(prometheus.CounterOpts{Name: "test_handler_total", Help: "Served."}).Inc()
+
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		path           string
+		connected      bool
+		synced         bool
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Test healthz while not ready",
+			path:           "/healthz",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "ok\n",
+		},
+		{
+			name:           "Test readyz lists failed checks",
+			path:           "/readyz",
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "cache: not synced\ndiscord: not connected\n",
+		},
+		{
+			name:           "Test readyz waits for every check",
+			path:           "/readyz",
+			connected:      true,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "cache: not synced\n",
+		},
+		{
+			name:           "Test readyz when ready",
+			path:           "/readyz",
+			connected:      true,
+			synced:         true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "ok\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connected, synced = tt.connected, tt.synced
+			status, body := get(t, server, tt.path)
+			if status != tt.expectedStatus || body != tt.expectedBody {
+				t.Errorf("expected %d %q, but got %d %q", tt.expectedStatus, tt.expectedBody, status, body)
+			}
+		})
+	}
+
+	status, body := get(t, server, "/metrics")
+	if status != http.StatusOK || !strings.Contains(body, "test_handler_total 1\n") {
+		t.Errorf("expected the metrics, but got %d %q", status, body)
+	}
+}
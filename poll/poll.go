@@ -1,14 +1,18 @@
 package poll
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/google/uuid"
 
 	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/config"
+	"github.com/highsaltlevels/saltbot/logging"
 	"github.com/highsaltlevels/saltbot/util"
 )
 
@@ -24,12 +28,12 @@ const helpMessage string = ("```How to set a poll:\n" +
 const voteHelpMessage string = ("```To vote on a poll, use \"!vote <poll id> " +
 	"<choice num>\". For example: \"!vote dd32251a 1\"```")
 
-func parsePoll(args []string, m *discordgo.MessageCreate) (*cache.Poll, error) {
+func parsePoll(args []string, m *discordgo.MessageCreate, now time.Time) (*cache.Poll, error) {
 	prompt := strings.Replace(strings.Replace(args[0], "!poll", "", 1), "!p", "", 1)
 	units := strings.Split(args[len(args)-1], " ")
 	unit := units[len(units)-1]
 	duration := units[len(units)-2]
-	expiry, err := util.ParseExpiry(unit, duration)
+	expiry, err := util.ParseExpiry(now, unit, duration)
 	if err != nil {
 		return nil, fmt.Errorf("```Error parsing expiry: %w```%s\n", err, helpMessage)
 	}
@@ -43,15 +47,17 @@ func parsePoll(args []string, m *discordgo.MessageCreate) (*cache.Poll, error) {
 	return &cache.Poll{
 		Author:  m.Author.ID,
 		Channel: m.ChannelID,
+		Guild:   m.GuildID,
 		Prompt:  strings.TrimSpace(prompt),
 		Choices: choices,
 		Expiry:  expiry,
 		Id:      id,
-		Votes:   map[string][]interface{}{},
+		Votes:   map[string][]string{},
 	}, nil
 }
 
-func Create(m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+// Create a poll that ends going by the clock.
+func Create(ctx context.Context, m *discordgo.MessageCreate, clock util.Clock) (*discordgo.MessageSend, error) {
 	args := strings.Split(m.Content, " ")[1:]
 	if len(args) < 4 || args[0] == "help" {
 		return &discordgo.MessageSend{
@@ -74,23 +80,25 @@ func Create(m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
 		}, nil
 	}
 
-	poll, err := parsePoll(args, m)
+	poll, err := parsePoll(args, m, clock.Now())
 	if err != nil {
 		return &discordgo.MessageSend{
 			Content: err.Error(),
 		}, nil
 	}
 
-	err = cache.Cache.AddPoll(poll)
+	err = cache.Cache.AddPoll(ctx, poll)
 	if err != nil {
 		return nil, fmt.Errorf("error adding poll to k8s: %w", err)
 	}
+	logging.From(ctx).Info("created poll", "poll", poll.Id, "expiry", poll.Expiry)
 
 	// Create the poll string
 	msg := fmt.Sprintf("```%s\n\n", poll.Prompt)
 	for idx, choice := range poll.Choices {
 		msg += fmt.Sprintf("%d. %s\n", idx+1, choice)
 	}
+	msg += fmt.Sprintf("\nEnds on %s\n", util.TimeFromExpiry(poll.Expiry, config.Location(m.GuildID)))
 	msg += fmt.Sprintf("Type or DM me \"!vote %s <choice number>\" to vote```", poll.Id)
 
 	return &discordgo.MessageSend{
@@ -98,7 +106,7 @@ func Create(m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
 	}, nil
 }
 
-func Vote(m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+func Vote(ctx context.Context, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
 	args := strings.Split(m.Content, " ")[1:]
 	if len(args) < 2 {
 		return &discordgo.MessageSend{
@@ -126,36 +134,34 @@ func Vote(m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
 		}, nil
 	}
 
-	// Strip the users current vote so that they can't double vote.
-	votes := make(map[string][]interface{}, len(poll.Votes))
-	for choiceNum, choosers := range poll.Votes {
-		updatedChoosers := []interface{}{}
-		for _, chooser := range choosers {
-			if chooser != m.Author.Username {
-				updatedChoosers = append(updatedChoosers, chooser)
-			}
-		}
-		votes[choiceNum] = updatedChoosers
-	}
-
-	choiceStr := fmt.Sprintf("%d", choiceNum-1)
-	votes[choiceStr] = append(votes[choiceStr], m.Author.Username)
-
-	updatedPoll := cache.Poll{
-		Author:  poll.Author,
-		Channel: poll.Channel,
-		Prompt:  poll.Prompt,
-		Choices: poll.Choices,
-		Expiry:  poll.Expiry,
-		Id:      poll.Id,
-		Votes:   votes,
-	}
-	err = cache.Cache.UpdatePoll(&updatedPoll)
+	err = cache.Cache.UpdatePoll(ctx, poll.Id, func(p *cache.Poll) {
+		vote(p, m.Author.Username, choiceNum)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to update poll: %w", err)
 	}
+	logging.From(ctx).Info("voted", "poll", poll.Id, "choice", choiceNum)
 
 	return &discordgo.MessageSend{
 		Content: fmt.Sprintf("```You have voted for %s```", poll.Choices[choiceNum-1]),
 	}, nil
 }
+
+// Vote for choiceNum as the chooser, replacing their old vote so that they
+// can't double vote.
+func vote(p *cache.Poll, chooser string, choiceNum int) {
+	votes := make(map[string][]string, len(p.Votes))
+	for choice, choosers := range p.Votes {
+		updatedChoosers := []string{}
+		for _, c := range choosers {
+			if c != chooser {
+				updatedChoosers = append(updatedChoosers, c)
+			}
+		}
+		votes[choice] = updatedChoosers
+	}
+
+	choiceStr := fmt.Sprintf("%d", choiceNum-1)
+	votes[choiceStr] = append(votes[choiceStr], chooser)
+	p.Votes = votes
+}
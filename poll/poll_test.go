@@ -1,22 +1,24 @@
 package poll
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
-	"k8s.io/client-go/kubernetes"
 
 	"github.com/highsaltlevels/saltbot/cache"
 	"github.com/highsaltlevels/saltbot/testutil"
+	"github.com/highsaltlevels/saltbot/util"
 )
 
 func TestCreate(t *testing.T) {
 	tests := []struct {
 		name             string
 		cache            *cache.ConfigMapCache
-		client           kubernetes.Interface
+		client           cache.KubeClient
 		commandStr       string
 		expectedMessages []string
 		expectedError    error
@@ -107,7 +109,7 @@ func TestCreate(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cache.Client = tt.client.(kubernetes.Interface)
+			cache.Client = tt.client
 			cache.Cache = tt.cache
 			msg := discordgo.MessageCreate{
 				Message: &discordgo.Message{
@@ -119,7 +121,7 @@ func TestCreate(t *testing.T) {
 				},
 			}
 
-			resp, err := Create(&msg)
+			resp, err := Create(context.Background(), &msg, util.SystemClock)
 			if tt.expectedError == nil {
 				if err != nil {
 					t.Fatalf("expected nil error but got: %v", err)
@@ -149,7 +151,7 @@ func TestVote(t *testing.T) {
 		name            string
 		polls           map[string]cache.Poll
 		commandStr      string
-		client          kubernetes.Interface
+		client          cache.KubeClient
 		expectedMessage string
 		expectedError   error
 	}{
@@ -227,8 +229,8 @@ func TestVote(t *testing.T) {
 						"choice1",
 						"choice2",
 					},
-					Votes: map[string][]interface{}{
-						"0": []interface{}{
+					Votes: map[string][]string{
+						"0": []string{
 							"user",
 							"other user",
 						},
@@ -270,8 +272,8 @@ func TestVote(t *testing.T) {
 			}
 
 			cache.Cache = cache.NewInMemConfigMapCache(tt.polls, map[string]cache.Reminder{})
-			cache.Client = tt.client.(kubernetes.Interface)
-			resp, err := Vote(&msg)
+			cache.Client = tt.client
+			resp, err := Vote(context.Background(), &msg)
 
 			if tt.expectedError == nil {
 				if err != nil {
@@ -294,3 +296,36 @@ func TestVote(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateExpiry(t *testing.T) {
+	start := time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC)
+	cache.Cache = cache.NewInMemConfigMapCache(map[string]cache.Poll{}, map[string]cache.Reminder{})
+	cache.Client = cache.InMemClient{}
+	settings := cache.GuildConfig{Id: "guild", Timezone: "America/Chicago"}
+	configMap, _ := settings.ToConfigMap()
+	cache.Cache.Store(configMap)
+
+	msg, err := Create(context.Background(), &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			Content:   "!poll prompt ; choice1 ; choice2 ; ends in 1 hour",
+			ChannelID: "1234",
+			GuildID:   "guild",
+			Author:    &discordgo.User{ID: "1234"},
+		},
+	}, util.NewFakeClock(start))
+	if err != nil {
+		t.Fatalf("expected nil error but got: %v", err)
+	}
+	if !strings.Contains(msg.Content, "Ends on Wed, 05 Jul 2023 08:00:00 CDT") {
+		t.Errorf("expected the end in the guild's time zone, but got %s", msg.Content)
+	}
+
+	for _, poll := range cache.Cache.ListPolls() {
+		if poll.Expiry != start.Add(time.Hour).Unix() {
+			t.Errorf("expected the poll to end an hour after %s, but got %s", start, time.Unix(poll.Expiry, 0))
+		}
+	}
+	if len(cache.Cache.ListPolls()) != 1 {
+		t.Errorf("expected 1 poll, but got %d", len(cache.Cache.ListPolls()))
+	}
+}
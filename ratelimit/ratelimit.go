@@ -0,0 +1,187 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+// How often something can happen: Burst times at once, and then once more
+// every Every. A zero rate doesn't limit anything.
+type Rate struct {
+	Burst int
+	Every time.Duration
+}
+
+func (r Rate) unlimited() bool {
+	return r.Burst <= 0 || r.Every <= 0
+}
+
+// How often a command can be used by one user, in one channel and in one
+// guild.
+type Cooldown struct {
+	User    Rate
+	Channel Rate
+	Guild   Rate
+}
+
+// Counters for a command.
+type Stats struct {
+	Allowed uint64
+	Limited uint64
+
+	// Times someone was told to slow down
+	Notified uint64
+}
+
+// How often idle buckets are cleaned up.
+const sweepInterval = time.Minute
+
+// A token bucket. Tokens are only topped up when the bucket is used.
+type bucket struct {
+	rate    Rate
+	tokens  float64
+	updated time.Time
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated)
+	b.tokens = math.Min(float64(b.rate.Burst), b.tokens+float64(elapsed)/float64(b.rate.Every))
+	b.updated = now
+}
+
+// How long until the bucket has a token.
+func (b *bucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(b.rate.Every))
+}
+
+type Limiter struct {
+	clock   util.Clock
+	buckets map[string]*bucket
+	stats   map[string]*Stats
+	lock    sync.Mutex
+
+	// When each user can next be told to slow down for a command
+	quiet     map[string]time.Time
+	lastSweep time.Time
+}
+
+func New(clock util.Clock) *Limiter {
+	return &Limiter{
+		clock:     clock,
+		buckets:   map[string]*bucket{},
+		stats:     map[string]*Stats{},
+		quiet:     map[string]time.Time{},
+		lastSweep: clock.Now(),
+	}
+}
+
+// Use a command. If any of its buckets is empty, nothing is used up and the
+// wait is how long until it can be used again. Notify is only true the first
+// time a user is limited in each wait, so they're told to slow down once.
+func (l *Limiter) Take(command string, c Cooldown, user, channel, guild string) (wait time.Duration, notify bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.clock.Now()
+	l.sweep(now)
+
+	stats, ok := l.stats[command]
+	if !ok {
+		stats = &Stats{}
+		l.stats[command] = stats
+	}
+
+	buckets := []*bucket{}
+	for _, limit := range []struct {
+		key  string
+		rate Rate
+	}{
+		{key: "user:" + user, rate: c.User},
+		{key: "channel:" + channel, rate: c.Channel},
+		{key: "guild:" + guild, rate: c.Guild},
+	} {
+		// DMs don't have a guild
+		if limit.rate.unlimited() || limit.key == "guild:" {
+			continue
+		}
+
+		key := command + ":" + limit.key
+		b, ok := l.buckets[key]
+		if !ok {
+			b = &bucket{rate: limit.rate, tokens: float64(limit.rate.Burst), updated: now}
+			l.buckets[key] = b
+		}
+		b.refill(now)
+		if b.wait() > wait {
+			wait = b.wait()
+		}
+		buckets = append(buckets, b)
+	}
+
+	if wait > 0 {
+		stats.Limited++
+		quietKey := command + ":" + user
+		if now.Before(l.quiet[quietKey]) {
+			return wait, false
+		}
+		l.quiet[quietKey] = now.Add(wait)
+		stats.Notified++
+		return wait, true
+	}
+
+	for _, b := range buckets {
+		b.tokens--
+	}
+	stats.Allowed++
+	return 0, false
+}
+
+// Forget buckets that have refilled, since a new one would be the same.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.rate.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	for key, until := range l.quiet {
+		if !now.Before(until) {
+			delete(l.quiet, key)
+		}
+	}
+}
+
+// Counters for every command that's been used, keyed on the command.
+func (l *Limiter) Stats() map[string]Stats {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	stats := make(map[string]Stats, len(l.stats))
+	for command, s := range l.stats {
+		stats[command] = *s
+	}
+	return stats
+}
+
+// Number of buckets being tracked.
+func (l *Limiter) Len() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return len(l.buckets)
+}
+
+// Round a wait up to whole seconds for telling people about it.
+func Seconds(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+var cooldown = Cooldown{
+	User:    Rate{Burst: 2, Every: 10 * time.Second},
+	Channel: Rate{Burst: 3, Every: 5 * time.Second},
+}
+
+func TestTake(t *testing.T) {
+	tests := []struct {
+		name    string
+		after   time.Duration
+		user    string
+		channel string
+		wait    time.Duration
+		notify  bool
+	}{
+		{name: "test first use", user: "1", channel: "a"},
+		{name: "test burst", user: "1", channel: "a"},
+		{name: "test user limited", user: "1", channel: "a", wait: 10 * time.Second, notify: true},
+		{name: "test user told once", after: time.Second, user: "1", channel: "a", wait: 9 * time.Second},
+		{name: "test user elsewhere still limited", user: "1", channel: "b", wait: 9 * time.Second},
+		{name: "test other user", user: "2", channel: "a"},
+		{name: "test channel limited", user: "3", channel: "a", wait: 4 * time.Second, notify: true},
+		{name: "test other channel", user: "3", channel: "b"},
+		{name: "test refilled", after: 9 * time.Second, user: "1", channel: "a"},
+		{name: "test told again next window", user: "1", channel: "a", wait: 10 * time.Second, notify: true},
+	}
+
+	clock := util.NewFakeClock(time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC))
+	l := New(clock)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Advance(tt.after)
+			wait, notify := l.Take("gif", cooldown, tt.user, tt.channel, "guild")
+			if wait != tt.wait || notify != tt.notify {
+				t.Errorf("expected wait %v and notify %v, but got %v and %v", tt.wait, tt.notify, wait, notify)
+			}
+		})
+	}
+
+	stats := l.Stats()["gif"]
+	if stats.Allowed != 5 || stats.Limited != 5 || stats.Notified != 3 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestTakeGuild(t *testing.T) {
+	clock := util.NewFakeClock(time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC))
+	l := New(clock)
+	guild := Cooldown{Guild: Rate{Burst: 1, Every: time.Minute}}
+
+	if wait, _ := l.Take("youtube", guild, "1", "a", "guild"); wait != 0 {
+		t.Fatalf("expected the first use to be allowed, but had to wait %v", wait)
+	}
+	if wait, _ := l.Take("youtube", guild, "2", "b", "guild"); wait != time.Minute {
+		t.Errorf("expected the guild to be limited, but had to wait %v", wait)
+	}
+	if wait, _ := l.Take("gif", guild, "2", "b", "guild"); wait != 0 {
+		t.Errorf("expected other commands to have their own limits, but had to wait %v", wait)
+	}
+
+	// DMs don't have a guild to limit
+	l.Take("youtube", guild, "1", "dm", "")
+	if wait, _ := l.Take("youtube", guild, "1", "dm", ""); wait != 0 {
+		t.Errorf("expected DMs to only be limited by user and channel, but had to wait %v", wait)
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	l := New(util.NewFakeClock(time.Now()))
+	for i := 0; i < 100; i++ {
+		if wait, _ := l.Take("help", Cooldown{}, "1", "a", "guild"); wait != 0 {
+			t.Fatalf("expected no limit, but had to wait %v", wait)
+		}
+	}
+	if l.Len() != 0 {
+		t.Errorf("expected no buckets, but got %d", l.Len())
+	}
+}
+
+func TestSweep(t *testing.T) {
+	clock := util.NewFakeClock(time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC))
+	l := New(clock)
+	l.Take("gif", cooldown, "1", "a", "guild")
+	if l.Len() != 2 {
+		t.Fatalf("expected a user and channel bucket, but got %d", l.Len())
+	}
+
+	clock.Advance(sweepInterval)
+	l.Take("gif", Cooldown{}, "1", "a", "guild")
+	if l.Len() != 0 {
+		t.Errorf("expected refilled buckets to be forgotten, but got %d", l.Len())
+	}
+}
+
+func TestSeconds(t *testing.T) {
+	if Seconds(11100*time.Millisecond) != 12 || Seconds(12*time.Second) != 12 {
+		t.Errorf("expected waits to round up to whole seconds")
+	}
+}
@@ -1,14 +1,18 @@
 package reminder
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/google/uuid"
 
 	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/config"
+	"github.com/highsaltlevels/saltbot/logging"
 	"github.com/highsaltlevels/saltbot/util"
 )
 
@@ -17,14 +21,14 @@ const helpMessage string = ("```Set a reminder, show reminders or delete a remin
 	"reminders:\n\"!remind list\"\n\nTo delete a reminder:\n\"!remind delete" +
 	"<ID>\" where <ID> is the id of the reminder given by \"!remind list\"```")
 
-func parseReminder(args []string, m *discordgo.MessageCreate) (*cache.Reminder, error) {
+func parseReminder(args []string, m *discordgo.MessageCreate, now time.Time) (*cache.Reminder, error) {
 	if args[len(args)-3] != "in" {
 		return nil, errors.New(helpMessage)
 	}
 
 	unit := args[len(args)-1]
 	duration := args[len(args)-2]
-	expiry, err := util.ParseExpiry(unit, duration)
+	expiry, err := util.ParseExpiry(now, unit, duration)
 	if err != nil {
 		return nil, fmt.Errorf("```Error parsing expiry: %w```%s\n", err, helpMessage)
 	}
@@ -41,7 +45,8 @@ func parseReminder(args []string, m *discordgo.MessageCreate) (*cache.Reminder,
 	return &reminder, nil
 }
 
-func Handle(m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+// Handle "!remind". Reminders go off going by the clock.
+func Handle(ctx context.Context, m *discordgo.MessageCreate, clock util.Clock) (*discordgo.MessageSend, error) {
 	args := strings.Split(m.Content, " ")[1:]
 	if len(args) == 0 || args[0] == "help" {
 		return &discordgo.MessageSend{
@@ -51,20 +56,22 @@ func Handle(m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
 
 	switch args[0] {
 	case "set":
-		reminder, err := parseReminder(args[1:], m)
+		reminder, err := parseReminder(args[1:], m, clock.Now())
 		if err != nil {
 			return &discordgo.MessageSend{
 				Content: err.Error(),
 			}, nil
 		}
 
-		err = cache.Cache.AddReminder(reminder, m.Author.Username)
+		err = cache.Cache.AddReminder(ctx, reminder, m.Author.Username)
 		if err != nil {
 			return nil, fmt.Errorf("error adding reminder to k8s: %w", err)
 		}
+		logging.From(ctx).Info("created reminder", "reminder", reminder.Id, "expiry", reminder.Expiry)
 
 		return &discordgo.MessageSend{
-			Content: fmt.Sprintf("```Created reminder with id: %s```", reminder.Id),
+			Content: fmt.Sprintf("```Created reminder with id: %s, it goes off on %s```", reminder.Id,
+				util.TimeFromExpiry(reminder.Expiry, config.Location(m.GuildID))),
 		}, nil
 
 	case "list":
@@ -72,7 +79,7 @@ func Handle(m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
 		allReminders := cache.Cache.ListReminders()
 		for _, reminder := range allReminders {
 			if reminder.Author == m.Author.ID {
-				expiry := util.TimeFromExpiry(reminder.Expiry)
+				expiry := util.TimeFromExpiry(reminder.Expiry, config.Location(m.GuildID))
 				msg += fmt.Sprintf("%s: %s on %s\n", reminder.Id, reminder.Message, expiry)
 			}
 		}
@@ -95,7 +102,8 @@ func Handle(m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
 			}, nil
 		}
 
-		cache.Cache.Delete("reminder-" + reminder.Id)
+		cache.Cache.DeleteReminder(ctx, reminder.Id)
+		logging.From(ctx).Info("deleted reminder", "reminder", reminder.Id)
 		return &discordgo.MessageSend{
 			Content: fmt.Sprintf("```Deleted reminder %s```", reminder.Id),
 		}, nil
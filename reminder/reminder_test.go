@@ -1,15 +1,17 @@
 package reminder
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
-	"k8s.io/client-go/kubernetes"
 
 	"github.com/highsaltlevels/saltbot/cache"
 	"github.com/highsaltlevels/saltbot/testutil"
+	"github.com/highsaltlevels/saltbot/util"
 )
 
 const expectedError string = "expect me"
@@ -19,7 +21,7 @@ func TestHandle(t *testing.T) {
 		name            string
 		reminders       map[string]cache.Reminder
 		commandStr      string
-		client          kubernetes.Interface
+		client          cache.KubeClient
 		expectedMessage string
 		expectedError   error
 	}{
@@ -168,9 +170,9 @@ func TestHandle(t *testing.T) {
 				},
 			}
 			cache.Cache = cache.NewInMemConfigMapCache(map[string]cache.Poll{}, tt.reminders)
-			cache.Client = tt.client.(kubernetes.Interface)
+			cache.Client = tt.client
 
-			resp, err := Handle(&msg)
+			resp, err := Handle(context.Background(), &msg, util.SystemClock)
 
 			if tt.expectedError == nil {
 				if err != nil {
@@ -193,3 +195,37 @@ func TestHandle(t *testing.T) {
 		})
 	}
 }
+
+func TestSetExpiry(t *testing.T) {
+	start := time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC)
+	cache.Cache = cache.NewInMemConfigMapCache(map[string]cache.Poll{}, map[string]cache.Reminder{})
+	cache.Client = cache.InMemClient{}
+	settings := cache.GuildConfig{Id: "guild", Timezone: "Asia/Tokyo"}
+	configMap, _ := settings.ToConfigMap()
+	cache.Cache.Store(configMap)
+
+	msg, err := Handle(context.Background(), &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			Content:   "!remind set stretch in 2 days",
+			ChannelID: "1234",
+			GuildID:   "guild",
+			Author:    &discordgo.User{ID: "1234"},
+		},
+	}, util.NewFakeClock(start))
+	if err != nil {
+		t.Fatalf("expected nil error but got: %v", err)
+	}
+	if !strings.Contains(msg.Content, "it goes off on Fri, 07 Jul 2023 21:00:00 JST") {
+		t.Errorf("expected the time in the guild's time zone, but got %s", msg.Content)
+	}
+
+	reminders := cache.Cache.ListReminders()
+	if len(reminders) != 1 {
+		t.Fatalf("expected 1 reminder, but got %d", len(reminders))
+	}
+	for _, reminder := range reminders {
+		if reminder.Expiry != start.Add(48*time.Hour).Unix() {
+			t.Errorf("expected the reminder 2 days after %s, but got %s", start, time.Unix(reminder.Expiry, 0))
+		}
+	}
+}
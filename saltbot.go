@@ -2,65 +2,154 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	// Guilds can pick any time zone, and the image has no zoneinfo
+	_ "time/tzdata"
 
 	"github.com/bwmarrin/discordgo"
 
 	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/console"
 	"github.com/highsaltlevels/saltbot/expirychecker"
 	"github.com/highsaltlevels/saltbot/handler"
+	"github.com/highsaltlevels/saltbot/jeopardy"
+	"github.com/highsaltlevels/saltbot/logging"
+	"github.com/highsaltlevels/saltbot/metrics"
+	"github.com/highsaltlevels/saltbot/util"
+	"github.com/highsaltlevels/saltbot/youtube"
 )
 
-// Bot token
-var token string
+// Run against a terminal instead of discord, with everything kept in memory
+var consoleMode = flag.Bool("console", false, "type commands at a terminal instead of connecting to discord")
 
-func init() {
-	var ok bool
-	if token, ok = os.LookupEnv("BOT_TOKEN"); !ok {
-		log.Fatal("failed to get bot token from env var")
+// What the background loops need to post messages
+type sender interface {
+	ChannelMessageSend(channelID string, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+func startLoops(s sender, ctx context.Context, clock util.Clock) {
+	slog.Info("initializing messenger")
+	checker := expirychecker.NewPoller(s, ctx, clock)
+	go checker.Loop()
+
+	slog.Info("initializing youtube upload watcher")
+	watcher := youtube.NewWatcher(s, ctx, clock)
+	go watcher.Loop()
+
+	slog.Info("initializing final jeopardy scheduler")
+	finals := jeopardy.NewFinalScheduler(s, ctx, clock)
+	go finals.Loop()
+}
+
+// Play with saltbot at a terminal. Polls, reminders and everything else only
+// live in memory, and time can be skipped ahead with "/wait".
+func runConsole() {
+	// Keep the logs from drowning out the conversation
+	if _, ok := os.LookupEnv("SALTBOT_LOG_LEVEL"); !ok {
+		logging.Level.Set(slog.LevelWarn)
 	}
+
+	cache.Cache = cache.NewInMemConfigMapCache(map[string]cache.Poll{}, map[string]cache.Reminder{})
+	cache.Client = cache.InMemClient{}
+
+	clock := util.NewFakeClock(time.Now())
+	handler.SetClock(clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	session := console.NewSession(os.Stdout)
+	startLoops(session, ctx, clock)
+	console.New(session, clock).Run(os.Stdin)
 }
 
 func main() {
+	flag.Parse()
+	logging.Setup(os.Stderr)
+
+	timezone, ok := os.LookupEnv("SALTBOT_TIMEZONE")
+	if !ok {
+		timezone = "US/Eastern"
+	}
+
 	var err error
-	time.Local, err = time.LoadLocation("US/Eastern")
+	time.Local, err = time.LoadLocation(timezone)
 	if err != nil {
 		log.Fatalf("failed to load locale: %v", err)
 	}
 
+	if *consoleMode {
+		runConsole()
+		return
+	}
+
+	token, ok := os.LookupEnv("BOT_TOKEN")
+	if !ok {
+		log.Fatal("failed to get bot token from env var")
+	}
+
 	session, err := discordgo.New("Bot " + token)
 	if err != nil {
 		log.Fatalf("failed to initialize saltbot: %v", err)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Serve the probes first, so they answer while saltbot connects and syncs
+	metrics.AddCheck("discord", func() error {
+		if !handler.Connected() {
+			return errors.New("not connected to the gateway")
+		}
+		return nil
+	})
+	metrics.AddCheck("cache", func() error {
+		if !cache.Synced() {
+			return errors.New("informer hasn't synced")
+		}
+		return nil
+	})
+	go func() {
+		if err := metrics.Serve(ctx, metrics.Addr); err != nil {
+			log.Fatalf("failed to serve metrics: %v", err)
+		}
+	}()
+
+	session.AddHandler(handler.OnReady)
+	session.AddHandler(handler.OnResumed)
+	session.AddHandler(handler.OnDisconnect)
+
 	err = session.Open()
 	if err != nil {
 		log.Fatalf("failed to open discord socket: %v", err)
 	}
 	defer session.Close()
 
-	log.Println("initializing poll/reminder cache")
+	slog.Info("initializing poll/reminder cache")
 	cache.NewConfigMapCache()
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	startLoops(session, ctx, util.SystemClock)
 
-	log.Println("initializing messenger")
-	checker := expirychecker.NewPoller(session, ctx)
-	go checker.Loop()
+	slog.Info("starting handler workers")
+	handler.StartWorkers(ctx)
 
-	log.Println("registering message handlers")
+	slog.Info("registering message handlers")
 	session.AddHandler(handler.OnMessageCreate)
+	session.AddHandler(handler.OnInteractionCreate)
 
-	log.Println("salbot initialized and logged in")
+	slog.Info("salbot initialized and logged in")
 
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 	<-sc
 
-	log.Println("closing down gracefully")
+	slog.Info("closing down gracefully")
 }
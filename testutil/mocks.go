@@ -8,6 +8,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	saltbotv1 "github.com/highsaltlevels/saltbot/apis/saltbot/v1"
 )
 
 const ExpectedError string = "expect me"
@@ -44,6 +46,52 @@ func (m MockK8sClient) CoreV1() corev1.CoreV1Interface {
 	return MockCoreV1{}
 }
 
+func (m MockK8sClient) SaltbotV1() saltbotv1.SaltbotV1Interface {
+	return MockSaltbotV1{}
+}
+
+type MockSaltbotV1 struct{}
+
+func (m MockSaltbotV1) Polls(namespace string) saltbotv1.PollInterface {
+	return MockPollClient{}
+}
+
+func (m MockSaltbotV1) Reminders(namespace string) saltbotv1.ReminderInterface {
+	return MockReminderClient{}
+}
+
+type MockPollClient struct {
+	saltbotv1.PollInterface
+}
+
+func (m MockPollClient) Get(ctx context.Context, name string, opts metav1.GetOptions) (*saltbotv1.Poll, error) {
+	return &saltbotv1.Poll{}, nil
+}
+
+func (m MockPollClient) Create(ctx context.Context, poll *saltbotv1.Poll, opts metav1.CreateOptions) (*saltbotv1.Poll, error) {
+	return nil, nil
+}
+
+func (m MockPollClient) Update(ctx context.Context, poll *saltbotv1.Poll, opts metav1.UpdateOptions) (*saltbotv1.Poll, error) {
+	return nil, nil
+}
+
+func (m MockPollClient) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return nil
+}
+
+type MockReminderClient struct {
+	saltbotv1.ReminderInterface
+}
+
+func (m MockReminderClient) Create(ctx context.Context, reminder *saltbotv1.Reminder, opts metav1.CreateOptions) (*saltbotv1.Reminder, error) {
+	return nil, nil
+}
+
+func (m MockReminderClient) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return nil
+}
+
 type MockErrorConfigMapClient struct {
 	corev1.ConfigMapInterface
 }
@@ -75,3 +123,49 @@ type MockErrorK8sClient struct {
 func (m MockErrorK8sClient) CoreV1() corev1.CoreV1Interface {
 	return MockErrorCoreV1{}
 }
+
+func (m MockErrorK8sClient) SaltbotV1() saltbotv1.SaltbotV1Interface {
+	return MockErrorSaltbotV1{}
+}
+
+type MockErrorSaltbotV1 struct{}
+
+func (m MockErrorSaltbotV1) Polls(namespace string) saltbotv1.PollInterface {
+	return MockErrorPollClient{}
+}
+
+func (m MockErrorSaltbotV1) Reminders(namespace string) saltbotv1.ReminderInterface {
+	return MockErrorReminderClient{}
+}
+
+type MockErrorPollClient struct {
+	saltbotv1.PollInterface
+}
+
+func (m MockErrorPollClient) Get(ctx context.Context, name string, opts metav1.GetOptions) (*saltbotv1.Poll, error) {
+	return nil, errors.New(ExpectedError)
+}
+
+func (m MockErrorPollClient) Create(ctx context.Context, poll *saltbotv1.Poll, opts metav1.CreateOptions) (*saltbotv1.Poll, error) {
+	return nil, errors.New(ExpectedError)
+}
+
+func (m MockErrorPollClient) Update(ctx context.Context, poll *saltbotv1.Poll, opts metav1.UpdateOptions) (*saltbotv1.Poll, error) {
+	return nil, errors.New(ExpectedError)
+}
+
+func (m MockErrorPollClient) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return errors.New(ExpectedError)
+}
+
+type MockErrorReminderClient struct {
+	saltbotv1.ReminderInterface
+}
+
+func (m MockErrorReminderClient) Create(ctx context.Context, reminder *saltbotv1.Reminder, opts metav1.CreateOptions) (*saltbotv1.Reminder, error) {
+	return nil, errors.New(ExpectedError)
+}
+
+func (m MockErrorReminderClient) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return errors.New(ExpectedError)
+}
@@ -0,0 +1,112 @@
+package testutil
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A message sent through a RecordingSession.
+type SentMessage struct {
+	ChannelID string
+	Message   *discordgo.MessageSend
+}
+
+// A fake discord session that records everything sent through it. It covers
+// every session interface in saltbot.
+type RecordingSession struct {
+	// The bot's own user id
+	BotID string
+
+	// Permissions keyed on "<user>:<channel>". Missing entries have none.
+	Permissions map[string]int64
+
+	// Channels returned by Channel, keyed on id. Missing channels are an
+	// error.
+	Channels map[string]*discordgo.Channel
+
+	// Make every call fail with ExpectedError
+	Fail bool
+
+	Sent      []SentMessage
+	Responses []*discordgo.InteractionResponse
+
+	// Users a DM channel was created for, in order
+	DMs []string
+
+	lock sync.Mutex
+}
+
+func (s *RecordingSession) ChannelMessageSend(channelID string, content string, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{Content: content})
+}
+
+func (s *RecordingSession) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.Fail {
+		return nil, errors.New(ExpectedError)
+	}
+	s.Sent = append(s.Sent, SentMessage{ChannelID: channelID, Message: data})
+	return &discordgo.Message{ChannelID: channelID, Content: data.Content}, nil
+}
+
+// DM channels have the id "dm-<user>".
+func (s *RecordingSession) UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.Fail {
+		return nil, errors.New(ExpectedError)
+	}
+	s.DMs = append(s.DMs, recipientID)
+	return &discordgo.Channel{ID: "dm-" + recipientID, Type: discordgo.ChannelTypeDM}, nil
+}
+
+func (s *RecordingSession) UserChannelPermissions(userID, channelID string, fetchOptions ...discordgo.RequestOption) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.Fail {
+		return 0, errors.New(ExpectedError)
+	}
+	return s.Permissions[userID+":"+channelID], nil
+}
+
+func (s *RecordingSession) Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	channel, ok := s.Channels[channelID]
+	if s.Fail || !ok {
+		return nil, errors.New(ExpectedError)
+	}
+	return channel, nil
+}
+
+func (s *RecordingSession) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.Fail {
+		return errors.New(ExpectedError)
+	}
+	s.Responses = append(s.Responses, resp)
+	return nil
+}
+
+func (s *RecordingSession) StateUser() *discordgo.User {
+	return &discordgo.User{ID: s.BotID}
+}
+
+// Everything sent so far, then forget it.
+func (s *RecordingSession) Flush() []SentMessage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sent := s.Sent
+	s.Sent = nil
+	return sent
+}
@@ -0,0 +1,117 @@
+package trivia
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	htmlTag      = regexp.MustCompile(`<[^>]*>`)
+	questionForm = regexp.MustCompile(`^(what|who|where|when|which)\s*(is|are|was|were|s)\s+`)
+	parenthetic  = regexp.MustCompile(`\([^)]*\)`)
+)
+
+var articles = map[string]bool{"a": true, "an": true, "the": true}
+
+// Strip the html tags, html escapes and escaped quotes that question sources
+// leave in their text.
+func CleanText(text string) string {
+	text = htmlTag.ReplaceAllString(text, "")
+	return html.UnescapeString(strings.ReplaceAll(text, "\\", ""))
+}
+
+// Lowercase an answer and strip everything that shouldn't matter when judging
+// it: html, "what is" style prefixes, punctuation and articles.
+func normalize(answer string) string {
+	answer = strings.ToLower(strings.TrimSpace(CleanText(answer)))
+	answer = strings.ReplaceAll(answer, "&", " and ")
+
+	// Apostrophes join words ("what's", "o'brien") while other punctuation splits them
+	var b strings.Builder
+	for _, r := range answer {
+		switch {
+		case r == '\'' || r == '’':
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	answer = strings.Join(strings.Fields(b.String()), " ")
+	answer = questionForm.ReplaceAllString(answer, "")
+
+	words := []string{}
+	for _, word := range strings.Fields(answer) {
+		if !articles[word] {
+			words = append(words, word)
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+// Answers like "(John) Adams" accept both "Adams" and "John Adams".
+func acceptedAnswers(answer string) []string {
+	answer = htmlTag.ReplaceAllString(answer, "")
+	accepted := []string{normalize(answer)}
+	if parenthetic.MatchString(answer) {
+		accepted = append(accepted, normalize(parenthetic.ReplaceAllString(answer, "")))
+	}
+
+	return accepted
+}
+
+// Whether a guess is close enough to the answer. Longer answers allow a typo
+// or two.
+func IsCorrect(guess, answer string) bool {
+	guess = normalize(guess)
+	if guess == "" {
+		return false
+	}
+
+	for _, accepted := range acceptedAnswers(answer) {
+		if accepted == "" {
+			continue
+		}
+		if levenshtein(guess, accepted) <= len([]rune(accepted))/5 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func minInt(nums ...int) int {
+	smallest := nums[0]
+	for _, n := range nums[1:] {
+		if n < smallest {
+			smallest = n
+		}
+	}
+	return smallest
+}
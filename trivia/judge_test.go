@@ -0,0 +1,58 @@
+package trivia
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		answer   string
+		expected string
+	}{
+		{answer: "The Beatles", expected: "beatles"},
+		{answer: "What is the Eiffel Tower?", expected: "eiffel tower"},
+		{answer: "who's Mark Twain", expected: "mark twain"},
+		{answer: "what are a few good men", expected: "few good men"},
+		{answer: "<i>Moby Dick</i>", expected: "moby dick"},
+		{answer: "Rock & Roll", expected: "rock and roll"},
+		{answer: "O\\'Brien", expected: "obrien"},
+		{answer: "  Spider-Man!  ", expected: "spider man"},
+		{answer: "&quot;Jaws&quot; &amp; Friends", expected: "jaws and friends"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			actual := normalize(tt.answer)
+			if actual != tt.expected {
+				t.Errorf("expected '%s', but got '%s'", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestIsCorrect(t *testing.T) {
+	tests := []struct {
+		name     string
+		guess    string
+		answer   string
+		expected bool
+	}{
+		{name: "Test exact answer", guess: "Paris", answer: "Paris", expected: true},
+		{name: "Test question form", guess: "what is paris", answer: "Paris", expected: true},
+		{name: "Test missing article", guess: "Beatles", answer: "the Beatles", expected: true},
+		{name: "Test optional part left out", guess: "Adams", answer: "(John) Adams", expected: true},
+		{name: "Test optional part included", guess: "john adams", answer: "(John) Adams", expected: true},
+		{name: "Test small typo", guess: "Missisippi", answer: "Mississippi", expected: true},
+		{name: "Test html in answer", guess: "moby dick", answer: "<i>Moby Dick</i>", expected: true},
+		{name: "Test wrong answer", guess: "London", answer: "Paris", expected: false},
+		{name: "Test short answers need to be exact", guess: "cat", answer: "bat", expected: false},
+		{name: "Test empty guess", guess: "what is", answer: "Paris", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := IsCorrect(tt.guess, tt.answer)
+			if actual != tt.expected {
+				t.Errorf("expected IsCorrect('%s', '%s') to be %t", tt.guess, tt.answer, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package trivia
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A few questions so that trivia works without any files on disk.
+//
+//go:embed data/questions.json
+var bundledQuestions []byte
+
+// Points for each Open Trivia DB difficulty
+var difficultyValues = map[string]int{
+	"easy":   100,
+	"medium": 200,
+	"hard":   300,
+}
+
+// The format served by https://opentdb.com/api.php and used for files on disk.
+type openTriviaResponse struct {
+	ResponseCode int                  `json:"response_code"`
+	Results      []openTriviaQuestion `json:"results"`
+}
+
+type openTriviaQuestion struct {
+	Category         string   `json:"category"`
+	Type             string   `json:"type"`
+	Difficulty       string   `json:"difficulty"`
+	Question         string   `json:"question"`
+	CorrectAnswer    string   `json:"correct_answer"`
+	IncorrectAnswers []string `json:"incorrect_answers"`
+}
+
+func (o openTriviaQuestion) question() (Question, error) {
+	q := Question{
+		Category: CleanText(o.Category),
+		Prompt:   CleanText(o.Question),
+		Answer:   CleanText(o.CorrectAnswer),
+		Value:    difficultyValues[o.Difficulty],
+	}
+	if q.Value == 0 {
+		q.Value = difficultyValues["medium"]
+	}
+
+	switch o.Type {
+	case "boolean":
+		q.Kind = TrueFalse
+		q.Choices = []string{"True", "False"}
+	case "multiple":
+		q.Kind = MultipleChoice
+		q.Choices = []string{q.Answer}
+		for _, incorrect := range o.IncorrectAnswers {
+			q.Choices = append(q.Choices, CleanText(incorrect))
+		}
+		rand.Shuffle(len(q.Choices), func(i, j int) {
+			q.Choices[i], q.Choices[j] = q.Choices[j], q.Choices[i]
+		})
+	default:
+		return q, fmt.Errorf("unknown question type %q", o.Type)
+	}
+
+	if q.Prompt == "" || !containsString(q.Choices, q.Answer) {
+		return q, fmt.Errorf("question %q has no prompt or its answer isn't a choice", o.Question)
+	}
+
+	return q, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Questions loaded from Open Trivia DB JSON files.
+type FileBank struct {
+	questions []Question
+}
+
+func parseOpenTrivia(data []byte) ([]Question, error) {
+	var resp openTriviaResponse
+	err := json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal open trivia questions: %w", err)
+	}
+
+	questions := []Question{}
+	for _, result := range resp.Results {
+		q, err := result.question()
+		if err != nil {
+			slog.Debug("skipping trivia question", "error", err)
+			continue
+		}
+		questions = append(questions, q)
+	}
+
+	return questions, nil
+}
+
+// Load every .json file in dir. Falls back to the bundled questions if dir is
+// empty or has no questions in it.
+func LoadFileBank(dir string) *FileBank {
+	bank := &FileBank{}
+	if dir != "" {
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				slog.Warn("failed to read trivia file", "path", path, "error", err)
+				continue
+			}
+
+			questions, err := parseOpenTrivia(data)
+			if err != nil {
+				slog.Warn("failed to load trivia file", "path", path, "error", err)
+				continue
+			}
+			bank.questions = append(bank.questions, questions...)
+		}
+
+		if len(bank.questions) > 0 {
+			slog.Info("loaded trivia questions", "count", len(bank.questions), "dir", dir)
+			return bank
+		}
+		slog.Warn("no trivia questions found, using the bundled ones", "dir", dir)
+	}
+
+	questions, err := parseOpenTrivia(bundledQuestions)
+	if err != nil {
+		log.Fatalf("bundled trivia questions are broken: %v", err)
+	}
+	bank.questions = questions
+	return bank
+}
+
+func (b *FileBank) Name() string {
+	return "opentdb"
+}
+
+func (b *FileBank) Questions(ctx context.Context, search string, count int) ([]Question, error) {
+	search = strings.ToLower(strings.TrimSpace(search))
+	matching := []Question{}
+	for _, q := range b.questions {
+		if strings.Contains(strings.ToLower(q.Category), search) {
+			matching = append(matching, q)
+		}
+	}
+
+	rand.Shuffle(len(matching), func(i, j int) {
+		matching[i], matching[j] = matching[j], matching[i]
+	})
+	if count > 0 && len(matching) > count {
+		matching = matching[:count]
+	}
+
+	return matching, nil
+}
+
+// Every category in the bank, sorted.
+func (b *FileBank) Categories() []string {
+	seen := map[string]bool{}
+	categories := []string{}
+	for _, q := range b.questions {
+		if !seen[q.Category] {
+			seen[q.Category] = true
+			categories = append(categories, q.Category)
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
@@ -0,0 +1,86 @@
+package trivia
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+const testQuestions = `{"response_code": 0, "results": [
+	{"category": "Science &amp; Nature", "type": "multiple", "difficulty": "hard",
+	 "question": "What is the chemical symbol for &quot;gold&quot;?", "correct_answer": "Au",
+	 "incorrect_answers": ["Ag", "Gd", "Go"]},
+	{"category": "History", "type": "boolean", "difficulty": "easy",
+	 "question": "Rome was built in a day.", "correct_answer": "False", "incorrect_answers": ["True"]},
+	{"category": "History", "type": "riddle", "difficulty": "easy",
+	 "question": "Unsupported", "correct_answer": "x", "incorrect_answers": []}
+]}`
+
+func TestParseOpenTrivia(t *testing.T) {
+	questions, err := parseOpenTrivia([]byte(testQuestions))
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if len(questions) != 2 {
+		t.Fatalf("expected the unsupported question to be skipped, but got %d questions", len(questions))
+	}
+
+	gold := questions[0]
+	if gold.Category != "Science & Nature" || gold.Prompt != "What is the chemical symbol for \"gold\"?" {
+		t.Errorf("expected html entities to be unescaped, but got %+v", gold)
+	}
+	if gold.Kind != MultipleChoice || gold.Value != 300 || len(gold.Choices) != 4 {
+		t.Errorf("unexpected multiple choice question: %+v", gold)
+	}
+	choices := append([]string{}, gold.Choices...)
+	sort.Strings(choices)
+	if choices[0] != "Ag" || choices[1] != "Au" {
+		t.Errorf("expected every answer to be a choice, but got %v", gold.Choices)
+	}
+
+	rome := questions[1]
+	if rome.Kind != TrueFalse || rome.Value != 100 || rome.Choices[0] != "True" || rome.Choices[1] != "False" {
+		t.Errorf("unexpected true or false question: %+v", rome)
+	}
+	if !rome.isChoice(1) || rome.isChoice(0) || rome.isChoice(2) {
+		t.Errorf("expected only \"False\" to be the right choice")
+	}
+
+	_, err = parseOpenTrivia([]byte("not json"))
+	if err == nil {
+		t.Errorf("expected an error for bad json")
+	}
+}
+
+func TestLoadFileBank(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "questions.json"), []byte(testQuestions), 0644)
+	if err != nil {
+		t.Fatalf("failed to write questions: %v", err)
+	}
+
+	bank := LoadFileBank(dir)
+	if len(bank.questions) != 2 {
+		t.Fatalf("expected 2 questions from the file, but got %d", len(bank.questions))
+	}
+	if categories := bank.Categories(); len(categories) != 2 || categories[0] != "History" {
+		t.Errorf("unexpected categories: %v", categories)
+	}
+
+	questions, _ := bank.Questions(context.Background(), "HISTORY", 0)
+	if len(questions) != 1 || questions[0].Prompt != "Rome was built in a day." {
+		t.Errorf("expected to find the history question, but got %+v", questions)
+	}
+
+	// An empty dir falls back to the bundled questions
+	bundled := LoadFileBank(t.TempDir())
+	if len(bundled.questions) < 10 {
+		t.Errorf("expected the bundled questions, but got %d", len(bundled.questions))
+	}
+	questions, _ = bundled.Questions(context.Background(), "", 5)
+	if len(questions) != 5 {
+		t.Errorf("expected the count to limit questions, but got %d", len(questions))
+	}
+}
@@ -0,0 +1,96 @@
+package trivia
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+type Kind int
+
+const (
+	// Answered by typing in chat, judged with fuzzy matching
+	FreeText Kind = iota
+	// Answered by picking one of the choices with a button
+	MultipleChoice
+	// A multiple choice question where the choices are "True" and "False"
+	TrueFalse
+)
+
+type Question struct {
+	Category string
+	Prompt   string
+	Answer   string
+
+	// Every choice including the answer, in the order they're shown. Empty
+	// for free text questions.
+	Choices []string
+	Kind    Kind
+	Value   int
+}
+
+// Somewhere to get questions from, like jeopardy clues or Open Trivia DB
+// files.
+type QuestionBank interface {
+	Name() string
+
+	// Up to count questions, only from categories with search in their name
+	// if it's set. A count of 0 means as many as the bank wants to give.
+	Questions(ctx context.Context, search string, count int) ([]Question, error)
+}
+
+// Banks that "!trivia play" can pick from, keyed on name
+var banks = map[string]QuestionBank{}
+var banksLock sync.Mutex
+
+func RegisterBank(bank QuestionBank) {
+	banksLock.Lock()
+	defer banksLock.Unlock()
+	banks[bank.Name()] = bank
+}
+
+func getBank(name string) (QuestionBank, bool) {
+	banksLock.Lock()
+	defer banksLock.Unlock()
+	bank, ok := banks[name]
+	return bank, ok
+}
+
+func bankNames() string {
+	banksLock.Lock()
+	defer banksLock.Unlock()
+
+	names := make([]string, 0, len(banks))
+	for name := range banks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Whether a pick on a multiple choice question is right.
+func (q Question) isChoice(choice int) bool {
+	return choice >= 0 && choice < len(q.Choices) && q.Choices[choice] == q.Answer
+}
+
+// Format a score like "$1,200" or "1,200 points", grouping the digits the
+// way the locale does.
+func FormatScore(amount int, dollars bool, locale language.Tag) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := message.NewPrinter(locale).Sprintf("%d", amount)
+
+	if dollars {
+		return sign + "$" + digits
+	}
+	return fmt.Sprintf("%s%s points", sign, digits)
+}
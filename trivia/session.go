@@ -0,0 +1,485 @@
+package trivia
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/text/language"
+
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+// How long players have to answer each question
+var AnswerTime = 30 * time.Second
+
+// Button custom ids start with this so the handler knows where to send them
+const ButtonPrefix = "trivia:"
+
+// Labels for the first choices, like "A. Paris"
+var choiceLetters = []string{"A", "B", "C", "D", "E"}
+
+type SessionInterface interface {
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+// Whether a player got a question right, and what it was worth. Wagers lose
+// their value when wrong.
+type Result struct {
+	Correct bool
+	Value   int
+	Wager   bool
+}
+
+func (r Result) Points() int {
+	switch {
+	case r.Correct:
+		return r.Value
+	case r.Wager:
+		return -r.Value
+	}
+	return 0
+}
+
+// How a game is played. Questions are asked in order.
+type Options struct {
+	// The command that started the game, like "!jeopardy", for help messages
+	Command   string
+	Intro     string
+	Guild     string
+	Channel   string
+	Questions []Question
+
+	// Show scores in dollars rather than points
+	Dollars bool
+	// Groups the digits of scores, like English if it isn't set
+	Locale language.Tag
+
+	// Times each question
+	Clock util.Clock
+
+	// Called with every player's results, in the order the questions were
+	// asked, when the game ends. The game's lock isn't held, so it can be slow.
+	OnFinish func(guild string, names map[string]string, results map[string][]Result)
+}
+
+// A game being played in a channel, one question at a time.
+type Game struct {
+	id      int
+	session SessionInterface
+	opts    Options
+	current int
+	scores  map[string]int
+	names   map[string]string
+	timer   util.Timer
+	lock    sync.Mutex
+
+	// Whether each player who answered the current question got it right
+	answered map[string]bool
+
+	// The choice each player picked on the current question
+	picks map[string]int
+
+	results map[string][]Result
+}
+
+// Games keyed on the channel they're played in
+var games = map[string]*Game{}
+var gamesLock sync.Mutex
+var lastGameId int
+
+func getGame(channel string) *Game {
+	gamesLock.Lock()
+	defer gamesLock.Unlock()
+	return games[channel]
+}
+
+func endGame(channel string) *Game {
+	gamesLock.Lock()
+	defer gamesLock.Unlock()
+	game := games[channel]
+	delete(games, channel)
+	return game
+}
+
+// Whether there's a game going in the channel.
+func Running(channel string) bool {
+	return getGame(channel) != nil
+}
+
+// Start a game and return the message with its first question.
+func Start(s SessionInterface, opts Options) *discordgo.MessageSend {
+	gamesLock.Lock()
+	defer gamesLock.Unlock()
+
+	if _, ok := games[opts.Channel]; ok {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```There's already a game going in this channel. Type \"%s stop\" to end it```", opts.Command),
+		}
+	}
+
+	lastGameId++
+	game := &Game{
+		id:       lastGameId,
+		session:  s,
+		opts:     opts,
+		scores:   map[string]int{},
+		names:    map[string]string{},
+		answered: map[string]bool{},
+		picks:    map[string]int{},
+		results:  map[string][]Result{},
+	}
+	games[opts.Channel] = game
+
+	game.lock.Lock()
+	defer game.lock.Unlock()
+	message := game.ask()
+	message.Content = opts.Intro + "\n" + message.Content
+	return message
+}
+
+func noGameMessage(command string) *discordgo.MessageSend {
+	return &discordgo.MessageSend{
+		Content: fmt.Sprintf("```There's no game going in this channel. Type \"%s play\" to start one```", command),
+	}
+}
+
+// End the channel's game early, with the final scores.
+func Stop(channel, command string) *discordgo.MessageSend {
+	game := endGame(channel)
+	if game == nil {
+		return noGameMessage(command)
+	}
+
+	game.lock.Lock()
+	game.stopTimer()
+	game.finishQuestion()
+	results := game.finish()
+	content := "Game over!\n" + game.scoreboard()
+	game.lock.Unlock()
+
+	game.record(results)
+	return &discordgo.MessageSend{
+		Content: content,
+	}
+}
+
+func Scores(channel, command string) *discordgo.MessageSend {
+	game := getGame(channel)
+	if game == nil {
+		return noGameMessage(command)
+	}
+
+	game.lock.Lock()
+	defer game.lock.Unlock()
+	return &discordgo.MessageSend{
+		Content: game.scoreboard(),
+	}
+}
+
+// Judge a chat message as an answer to the channel's current free text
+// question. Returns nil if there's no game or the answer was wrong, so
+// chatter stays quiet. A player's first message on each question counts as
+// an attempt for accuracy.
+func Answer(m *discordgo.MessageCreate) *discordgo.MessageSend {
+	game := getGame(m.ChannelID)
+	if game == nil || strings.HasPrefix(m.Content, "!") {
+		return nil
+	}
+
+	game.lock.Lock()
+	message, results := game.answer(m)
+	game.lock.Unlock()
+
+	game.record(results)
+	return message
+}
+
+// Judge the answer, returning the game's results too if it was the last
+// question. Must hold the game's lock.
+func (g *Game) answer(m *discordgo.MessageCreate) (*discordgo.MessageSend, *finishedGame) {
+	if g.current >= len(g.opts.Questions) {
+		return nil, nil
+	}
+
+	q := g.opts.Questions[g.current]
+	if q.Kind != FreeText {
+		return nil, nil
+	}
+
+	g.names[m.Author.ID] = m.Author.Username
+	if !IsCorrect(m.Content, q.Answer) {
+		if _, ok := g.answered[m.Author.ID]; !ok {
+			g.answered[m.Author.ID] = false
+		}
+		return nil, nil
+	}
+
+	g.stopTimer()
+	g.answered[m.Author.ID] = true
+	g.scores[m.Author.ID] += q.Value
+	msg := fmt.Sprintf("Correct, <@%s>! The answer was **%s**. +%s (you have %s)\n",
+		m.Author.ID, q.Answer, g.format(q.Value), g.format(g.scores[m.Author.ID]))
+
+	message, results := g.next()
+	message.Content = msg + message.Content
+	return message, results
+}
+
+// Record a button press on a multiple choice question. Picks are judged when
+// time runs out. The response is only shown to the player who pressed it.
+func OnButton(i *discordgo.InteractionCreate) *discordgo.InteractionResponse {
+	respond := func(content string) *discordgo.InteractionResponse {
+		return &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}
+	}
+
+	parts := strings.Split(strings.TrimPrefix(i.MessageComponentData().CustomID, ButtonPrefix), ":")
+	if len(parts) != 3 {
+		return respond("That button doesn't do anything")
+	}
+	gameId, errGame := strconv.Atoi(parts[0])
+	question, errQuestion := strconv.Atoi(parts[1])
+	choice, errChoice := strconv.Atoi(parts[2])
+	if errGame != nil || errQuestion != nil || errChoice != nil {
+		return respond("That button doesn't do anything")
+	}
+
+	game := getGame(i.ChannelID)
+	if game == nil {
+		return respond("That game is over")
+	}
+
+	game.lock.Lock()
+	defer game.lock.Unlock()
+
+	if game.id != gameId || game.current != question {
+		return respond("That question is over")
+	}
+
+	q := game.opts.Questions[game.current]
+	if choice < 0 || choice >= len(q.Choices) {
+		return respond("That button doesn't do anything")
+	}
+
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	if _, ok := game.picks[user.ID]; ok {
+		return respond("You've already answered this one")
+	}
+
+	game.names[user.ID] = user.Username
+	game.picks[user.ID] = choice
+	return respond(fmt.Sprintf("You picked **%s**", q.Choices[choice]))
+}
+
+// Move on to the next question, or end the game if that was the last one,
+// returning its results to record. Must hold the game's lock.
+func (g *Game) next() (*discordgo.MessageSend, *finishedGame) {
+	g.finishQuestion()
+	g.current++
+	if g.current < len(g.opts.Questions) {
+		return g.ask(), nil
+	}
+
+	endGame(g.opts.Channel)
+	return &discordgo.MessageSend{
+		Content: "That's the end of the game!\n" + g.scoreboard(),
+	}, g.finish()
+}
+
+// Show the current question and start its timer. Must hold the game's lock.
+func (g *Game) ask() *discordgo.MessageSend {
+	q := g.opts.Questions[g.current]
+	idx := g.current
+	g.timer = g.opts.Clock.AfterFunc(AnswerTime, func() {
+		g.timeUp(idx)
+	})
+
+	noun := "question"
+	if g.opts.Dollars {
+		noun = "clue"
+	}
+	content := fmt.Sprintf("```Category: %s\n%s (%s %d of %d)\n\n%s```", q.Category, g.format(q.Value),
+		noun, idx+1, len(g.opts.Questions), q.Prompt)
+
+	if q.Kind == FreeText {
+		return &discordgo.MessageSend{
+			Content: content + fmt.Sprintf("You have %s to answer!", AnswerTime),
+		}
+	}
+
+	buttons := []discordgo.MessageComponent{}
+	for choice, text := range q.Choices {
+		label := text
+		if q.Kind == MultipleChoice && choice < len(choiceLetters) {
+			label = choiceLetters[choice] + ". " + text
+		}
+		if len([]rune(label)) > 80 {
+			label = string([]rune(label)[:79]) + "…"
+		}
+		buttons = append(buttons, discordgo.Button{
+			Label:    label,
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("%s%d:%d:%d", ButtonPrefix, g.id, idx, choice),
+		})
+	}
+
+	return &discordgo.MessageSend{
+		Content:    content + fmt.Sprintf("You have %s to pick an answer!", AnswerTime),
+		Components: []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}},
+	}
+}
+
+// Reveal the answer and move on. The message is sent and the results
+// recorded after the lock is released, so a slow send doesn't hold up
+// answers and button presses.
+func (g *Game) timeUp(idx int) {
+	g.lock.Lock()
+	message, results := g.reveal(idx)
+	g.lock.Unlock()
+
+	if message == nil {
+		return
+	}
+	_, err := g.session.ChannelMessageSendComplex(g.opts.Channel, message)
+	if err != nil {
+		slog.Error("failed to send trivia question", "error", err)
+	}
+	g.record(results)
+}
+
+// Judge question idx once its time is up. Returns a nil message if it's
+// already over. Must hold the game's lock.
+func (g *Game) reveal(idx int) (*discordgo.MessageSend, *finishedGame) {
+	// Someone got it right just as time ran out, or the game was stopped
+	if g.current != idx || getGame(g.opts.Channel) != g {
+		return nil, nil
+	}
+
+	q := g.opts.Questions[g.current]
+	msg := fmt.Sprintf("Time's up! The answer was **%s**.\n", q.Answer)
+	if q.Kind != FreeText {
+		msg += g.judgePicks(q)
+	}
+
+	message, results := g.next()
+	message.Content = msg + message.Content
+	return message, results
+}
+
+// Score everyone's picks on a multiple choice question. Must hold the game's
+// lock.
+func (g *Game) judgePicks(q Question) string {
+	winners := []string{}
+	for id, choice := range g.picks {
+		correct := q.isChoice(choice)
+		g.answered[id] = correct
+		if correct {
+			g.scores[id] += q.Value
+			winners = append(winners, id)
+		}
+	}
+
+	if len(winners) == 0 {
+		return "Nobody got it.\n"
+	}
+
+	sort.Strings(winners)
+	mentions := []string{}
+	for _, id := range winners {
+		mentions = append(mentions, fmt.Sprintf("<@%s>", id))
+	}
+	return fmt.Sprintf("%s got it right! +%s\n", strings.Join(mentions, ", "), g.format(q.Value))
+}
+
+// Move the current question's answers into the game's results. Must hold the
+// game's lock.
+func (g *Game) finishQuestion() {
+	if g.current >= len(g.opts.Questions) {
+		return
+	}
+
+	value := g.opts.Questions[g.current].Value
+	for id, correct := range g.answered {
+		g.results[id] = append(g.results[id], Result{Correct: correct, Value: value})
+	}
+	g.answered = map[string]bool{}
+	g.picks = map[string]int{}
+}
+
+// A finished game's players and results, copied so they can be recorded
+// without the game's lock.
+type finishedGame struct {
+	names   map[string]string
+	results map[string][]Result
+}
+
+// Must hold the game's lock.
+func (g *Game) finish() *finishedGame {
+	finished := &finishedGame{
+		names:   make(map[string]string, len(g.names)),
+		results: make(map[string][]Result, len(g.results)),
+	}
+	for id, name := range g.names {
+		finished.names[id] = name
+	}
+	for id, results := range g.results {
+		finished.results[id] = append([]Result{}, results...)
+	}
+	return finished
+}
+
+// Pass a finished game's results to OnFinish. Mustn't hold the game's lock.
+func (g *Game) record(finished *finishedGame) {
+	if finished != nil && g.opts.OnFinish != nil {
+		g.opts.OnFinish(g.opts.Guild, finished.names, finished.results)
+	}
+}
+
+// Must hold the game's lock.
+func (g *Game) stopTimer() {
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+}
+
+func (g *Game) format(amount int) string {
+	return FormatScore(amount, g.opts.Dollars, g.opts.Locale)
+}
+
+// Must hold the game's lock.
+func (g *Game) scoreboard() string {
+	if len(g.scores) == 0 {
+		return "```Nobody scored this game```"
+	}
+
+	players := make([]string, 0, len(g.scores))
+	for id := range g.scores {
+		players = append(players, id)
+	}
+	sort.Slice(players, func(i, j int) bool {
+		if g.scores[players[i]] != g.scores[players[j]] {
+			return g.scores[players[i]] > g.scores[players[j]]
+		}
+		return g.names[players[i]] < g.names[players[j]]
+	})
+
+	msg := "```Scores:\n"
+	for i, id := range players {
+		msg += fmt.Sprintf("%d. %s: %s\n", i+1, g.names[id], g.format(g.scores[id]))
+	}
+	return msg + "```"
+}
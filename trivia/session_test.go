@@ -0,0 +1,296 @@
+package trivia
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/text/language"
+
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+type MockSession struct {
+	sent []*discordgo.MessageSend
+}
+
+func (s *MockSession) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.sent = append(s.sent, data)
+	return &discordgo.Message{}, nil
+}
+
+func newMessage(content, author string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			Content:   content,
+			ChannelID: "channel",
+			Author: &discordgo.User{
+				ID:       author,
+				Username: "user" + author,
+			},
+		},
+	}
+}
+
+func newButton(customId, user string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type:      discordgo.InteractionMessageComponent,
+			ChannelID: "channel",
+			Data:      discordgo.MessageComponentInteractionData{CustomID: customId},
+			Member: &discordgo.Member{
+				User: &discordgo.User{ID: user, Username: "user" + user},
+			},
+		},
+	}
+}
+
+// Start a game on a clock that never moves, so that time only runs out when
+// the test says so. The game is stopped when the test ends.
+func startGame(t *testing.T, session SessionInterface, opts Options) *Game {
+	opts.Clock = util.NewFakeClock(time.Now())
+	opts.Command = "!trivia"
+	opts.Channel = "channel"
+	msg := Start(session, opts)
+	t.Cleanup(func() {
+		Stop("channel", "!trivia")
+	})
+
+	game := getGame("channel")
+	if game == nil {
+		t.Fatalf("expected a game to start, but got '%s'", msg.Content)
+	}
+	return game
+}
+
+func TestFreeTextGame(t *testing.T) {
+	session := &MockSession{}
+	startGame(t, session, Options{
+		Questions: []Question{
+			{Category: "capitals", Prompt: "Japan's capital", Answer: "Tokyo", Value: 200},
+			{Category: "capitals", Prompt: "France's capital", Answer: "Paris", Value: 400},
+		},
+		Dollars: true,
+	})
+
+	msg := Start(session, Options{Command: "!trivia", Channel: "channel"})
+	if !strings.Contains(msg.Content, "already a game going") {
+		t.Errorf("expected a second game to be refused, but got '%s'", msg.Content)
+	}
+
+	if Answer(newMessage("osaka", "2")) != nil || Answer(newMessage("!tokyo", "2")) != nil {
+		t.Errorf("expected wrong answers and commands to be ignored")
+	}
+
+	msg = Answer(newMessage("What is Tokyo?", "2"))
+	if msg == nil {
+		t.Fatalf("expected a correct answer to be accepted")
+	}
+	if !strings.Contains(msg.Content, "Correct, <@2>! The answer was **Tokyo**. +$200 (you have $200)") ||
+		!strings.Contains(msg.Content, "$400 (clue 2 of 2)") {
+		t.Errorf("unexpected correct answer message: '%s'", msg.Content)
+	}
+
+	msg = Answer(newMessage("paris", "3"))
+	if !strings.Contains(msg.Content, "That's the end of the game!") ||
+		!strings.Contains(msg.Content, "1. user3: $400\n2. user2: $200\n") {
+		t.Errorf("expected the final scores, but got '%s'", msg.Content)
+	}
+	if Running("channel") {
+		t.Errorf("expected the game to be over")
+	}
+}
+
+func TestTimeUp(t *testing.T) {
+	session := &MockSession{}
+	game := startGame(t, session, Options{
+		Questions: []Question{
+			{Category: "capitals", Prompt: "Japan's capital", Answer: "Tokyo", Value: 200},
+			{Category: "capitals", Prompt: "France's capital", Answer: "Paris", Value: 400},
+		},
+	})
+
+	// A stale timer from an earlier question does nothing
+	game.timeUp(1)
+	if len(session.sent) != 0 {
+		t.Fatalf("expected no messages from a stale timer, but got %d", len(session.sent))
+	}
+
+	game.timeUp(0)
+	if len(session.sent) != 1 {
+		t.Fatalf("expected 1 message when time ran out, but got %d", len(session.sent))
+	}
+	content := session.sent[0].Content
+	if !strings.Contains(content, "Time's up! The answer was **Tokyo**.") || !strings.Contains(content, "400 points (question 2 of 2)") {
+		t.Errorf("unexpected time's up message: '%s'", content)
+	}
+}
+
+func TestButtonGame(t *testing.T) {
+	var finished map[string][]Result
+	session := &MockSession{}
+	game := startGame(t, session, Options{
+		Guild: "guild",
+		Questions: []Question{
+			{Category: "science", Prompt: "Gold?", Answer: "Au", Choices: []string{"Ag", "Au"}, Kind: MultipleChoice, Value: 300},
+			{Category: "history", Prompt: "Rome was built in a day.", Answer: "False", Choices: []string{"True", "False"}, Kind: TrueFalse, Value: 100},
+		},
+		OnFinish: func(guild string, names map[string]string, results map[string][]Result) {
+			finished = results
+		},
+	})
+
+	prefix := fmt.Sprintf("%s%d", ButtonPrefix, game.id)
+
+	if Answer(newMessage("au", "1")) != nil {
+		t.Errorf("expected chat answers to be ignored on a multiple choice question")
+	}
+
+	tests := []struct {
+		customId string
+		user     string
+		expected string
+	}{
+		{customId: prefix + ":0:1", user: "1", expected: "You picked **Au**"},
+		{customId: prefix + ":0:0", user: "1", expected: "You've already answered this one"},
+		{customId: prefix + ":0:0", user: "2", expected: "You picked **Ag**"},
+		{customId: prefix + ":1:0", user: "3", expected: "That question is over"},
+		{customId: prefix + ":0:5", user: "3", expected: "That button doesn't do anything"},
+		{customId: ButtonPrefix + "bogus", user: "3", expected: "That button doesn't do anything"},
+	}
+	for _, tt := range tests {
+		resp := OnButton(newButton(tt.customId, tt.user))
+		if resp.Data.Content != tt.expected || resp.Data.Flags != discordgo.MessageFlagsEphemeral {
+			t.Errorf("expected a private '%s' for %s, but got %+v", tt.expected, tt.customId, resp.Data)
+		}
+	}
+
+	game.timeUp(0)
+	content := session.sent[0].Content
+	if !strings.Contains(content, "The answer was **Au**.\n<@1> got it right! +300 points") {
+		t.Errorf("unexpected time's up message: '%s'", content)
+	}
+	buttons := session.sent[0].Components[0].(discordgo.ActionsRow).Components
+	if len(buttons) != 2 || buttons[1].(discordgo.Button).Label != "False" {
+		t.Errorf("expected true and false buttons, but got %+v", buttons)
+	}
+
+	game.timeUp(1)
+	if !strings.Contains(session.sent[1].Content, "Nobody got it.") || Running("channel") {
+		t.Errorf("expected the game to end, but got '%s'", session.sent[1].Content)
+	}
+	if len(finished["1"]) != 1 || !finished["1"][0].Correct || finished["2"][0].Correct {
+		t.Errorf("unexpected results: %+v", finished)
+	}
+
+	resp := OnButton(newButton(prefix+":1:1", "1"))
+	if resp.Data.Content != "That game is over" {
+		t.Errorf("expected buttons to stop working after the game, but got '%s'", resp.Data.Content)
+	}
+}
+
+// Blocks sends until it's released
+type blockingSession struct {
+	MockSession
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSession) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	close(s.started)
+	<-s.release
+	return s.MockSession.ChannelMessageSendComplex(channelID, data, options...)
+}
+
+func TestTimeUpSendDoesNotBlockButtons(t *testing.T) {
+	session := &blockingSession{started: make(chan struct{}), release: make(chan struct{})}
+	game := startGame(t, session, Options{
+		Questions: []Question{
+			{Category: "science", Prompt: "Gold?", Answer: "Au", Choices: []string{"Ag", "Au"}, Kind: MultipleChoice, Value: 300},
+			{Category: "science", Prompt: "Silver?", Answer: "Ag", Choices: []string{"Ag", "Au"}, Kind: MultipleChoice, Value: 300},
+		},
+	})
+
+	done := make(chan struct{})
+	go func() {
+		game.timeUp(0)
+		close(done)
+	}()
+	<-session.started
+
+	resp := OnButton(newButton(fmt.Sprintf("%s%d:1:0", ButtonPrefix, game.id), "1"))
+	close(session.release)
+	<-done
+
+	if resp.Data.Content != "You picked **Ag**" {
+		t.Errorf("expected the pick to be taken while the answer is posted, but got '%s'", resp.Data.Content)
+	}
+}
+
+func TestResultPoints(t *testing.T) {
+	tests := map[Result]int{
+		{Correct: true, Value: 200}:               200,
+		{Correct: false, Value: 200}:              0,
+		{Correct: true, Value: 500, Wager: true}:  500,
+		{Correct: false, Value: 500, Wager: true}: -500,
+	}
+	for result, expected := range tests {
+		if actual := result.Points(); actual != expected {
+			t.Errorf("expected %d points for %+v, but got %d", expected, result, actual)
+		}
+	}
+}
+
+func TestFormatScore(t *testing.T) {
+	for _, test := range []struct {
+		amount   int
+		dollars  bool
+		locale   language.Tag
+		expected string
+	}{
+		{1200, true, language.Tag{}, "$1,200"},
+		{-1234567, false, language.English, "-1,234,567 points"},
+		{-1234567, true, language.German, "-$1.234.567"},
+		{999, false, language.German, "999 points"},
+	} {
+		if actual := FormatScore(test.amount, test.dollars, test.locale); actual != test.expected {
+			t.Errorf("expected %s, but got %s", test.expected, actual)
+		}
+	}
+}
+
+func TestHandle(t *testing.T) {
+	RegisterBank(&FileBank{questions: []Question{
+		{Category: "History", Prompt: "Rome was built in a day.", Answer: "False", Choices: []string{"True", "False"}, Kind: TrueFalse, Value: 100},
+	}})
+
+	tests := []struct {
+		name     string
+		command  string
+		contains string
+	}{
+		{name: "Test help", command: "!trivia", contains: "Trivia commands"},
+		{name: "Test play", command: "!trivia play history", contains: "Let's play trivia!\n```Category: History\n100 points (question 1 of 1)"},
+		{name: "Test no matches", command: "!trivia play sports", contains: "Couldn't find any questions matching \"sports\""},
+		{name: "Test bad count", command: "!trivia play --count 50", contains: "Must use a count between 1 and 25"},
+		{name: "Test unknown source", command: "!trivia play --source bogus", contains: "Unknown source \"bogus\", expected one of: opentdb"},
+		{name: "Test categories", command: "!trivia categories", contains: "Trivia categories:\nHistory"},
+		{name: "Test scores without a game", command: "!trivia scores", contains: "Type \"!trivia play\" to start one"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := Handle(context.Background(), &MockSession{}, newMessage(tt.command, "1"), util.NewFakeClock(time.Now()))
+			if err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+			Stop("channel", "!trivia")
+			if !strings.Contains(msg.Content, tt.contains) {
+				t.Errorf("expected '%s' in message '%s'", tt.contains, msg.Content)
+			}
+		})
+	}
+}
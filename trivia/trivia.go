@@ -0,0 +1,134 @@
+package trivia
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+// Questions per game unless "--count" says otherwise
+const defaultCount = 10
+const maxCount = 25
+
+const helpMessage string = ("```Trivia commands:\n\n" +
+	"\"!trivia play\" starts a game of multiple choice and true or false\n" +
+	"    questions in the channel. Pick your answer with the buttons\n" +
+	"\"!trivia play science\" only asks questions from categories with\n" +
+	"    \"science\" in their name\n" +
+	"\"--count 5\" changes how many questions are asked (up to 25)\n" +
+	"\"--source jeopardy\" plays with jeopardy clues instead, answered in chat\n" +
+	"\"!trivia categories\" lists the categories to pick from\n" +
+	"\"!trivia scores\" and \"!trivia stop\" for the game in the channel```")
+
+// The flags understood by "!trivia"
+var flagSpec = util.FlagSpec{"--source": true, "--count": true}
+
+// The bank used when "--source" isn't given
+var defaultBank = "opentdb"
+
+func init() {
+	RegisterBank(LoadFileBank(os.Getenv("TRIVIA_DIR")))
+}
+
+// Handle "!trivia". Questions are timed going by the clock.
+func Handle(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate, clock util.Clock) (*discordgo.MessageSend, error) {
+	flags, err := util.ParseFlags(m.Content, flagSpec)
+	if err != nil {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```%v```", err),
+		}, nil
+	}
+
+	terms := flags.Terms
+	if len(terms) == 0 {
+		return &discordgo.MessageSend{
+			Content: helpMessage,
+		}, nil
+	}
+
+	switch terms[0] {
+	case "play":
+		return play(ctx, s, m, strings.Join(terms[1:], " "), flags, clock)
+	case "stop":
+		return Stop(m.ChannelID, "!trivia"), nil
+	case "scores":
+		return Scores(m.ChannelID, "!trivia"), nil
+	case "categories":
+		return categories(), nil
+	}
+
+	return &discordgo.MessageSend{
+		Content: helpMessage,
+	}, nil
+}
+
+func play(ctx context.Context, s SessionInterface, m *discordgo.MessageCreate, search string, flags *util.Flags, clock util.Clock) (*discordgo.MessageSend, error) {
+	count, err := flags.Int("--count", defaultCount)
+	if err != nil || count < 1 || count > maxCount {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```Must use a count between 1 and %d```", maxCount),
+		}, nil
+	}
+
+	name := flags.Value("--source", defaultBank)
+	bank, ok := getBank(name)
+	if !ok {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```Unknown source \"%s\", expected one of: %s```", name, bankNames()),
+		}, nil
+	}
+
+	if Running(m.ChannelID) {
+		return &discordgo.MessageSend{
+			Content: "```There's already a game going in this channel. Type \"!trivia stop\" to end it```",
+		}, nil
+	}
+
+	questions, err := bank.Questions(ctx, search, count)
+	if err != nil {
+		return nil, err
+	}
+	if len(questions) == 0 {
+		msg := "```Couldn't find any questions"
+		if search != "" {
+			msg += fmt.Sprintf(" matching \"%s\"", search)
+		}
+		return &discordgo.MessageSend{
+			Content: msg + "```",
+		}, nil
+	}
+
+	return Start(s, Options{
+		Command:   "!trivia",
+		Intro:     "Let's play trivia!",
+		Guild:     m.GuildID,
+		Channel:   m.ChannelID,
+		Questions: questions,
+		Clock:     clock,
+	}), nil
+}
+
+func categories() *discordgo.MessageSend {
+	bank, ok := getBank(defaultBank)
+	if !ok {
+		return &discordgo.MessageSend{
+			Content: "```There aren't any trivia categories```",
+		}
+	}
+
+	lister, ok := bank.(interface{ Categories() []string })
+	if !ok {
+		return &discordgo.MessageSend{
+			Content: "```There aren't any trivia categories```",
+		}
+	}
+
+	return &discordgo.MessageSend{
+		Content: "```Trivia categories:\n" + strings.Join(lister.Categories(), "\n") + "```",
+	}
+}
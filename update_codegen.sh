@@ -0,0 +1,20 @@
+#!/usr/bin/env bash
+
+# Regenerate apis/saltbot/v1/zz_generated.deepcopy.go after changing the
+# types. deepcopy-gen writes under a GOPATH style directory, so generate into
+# a temporary one and copy the file back.
+set -euo pipefail
+
+DEEPCOPY_GEN=${DEEPCOPY_GEN:-go run k8s.io/code-generator/cmd/deepcopy-gen@v0.27.3}
+PACKAGE=github.com/highsaltlevels/saltbot/apis/saltbot/v1
+
+cd "$(dirname "$0")"
+out=$(mktemp -d)
+trap 'rm -rf "$out"' EXIT
+
+$DEEPCOPY_GEN \
+  --input-dirs "$PACKAGE" \
+  --output-file-base zz_generated.deepcopy \
+  --output-base "$out" \
+  --go-header-file /dev/null
+cp "$out/$PACKAGE/zz_generated.deepcopy.go" apis/saltbot/v1/
@@ -0,0 +1,146 @@
+package util
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Where time comes from, so that anything scheduled can be tested without
+// waiting for it.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// A timer started with Clock.AfterFunc.
+type Timer interface {
+	// Returns false if the timer already fired or was stopped
+	Stop() bool
+}
+
+type systemClock struct{}
+
+// The wall clock.
+var SystemClock Clock = systemClock{}
+
+func (c systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (c systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// A clock that only moves when it's told to.
+type FakeClock struct {
+	now     time.Time
+	waiters []*fakeWaiter
+	lock    sync.Mutex
+
+	// Signalled whenever a waiter is added
+	added *sync.Cond
+}
+
+type fakeWaiter struct {
+	clock *FakeClock
+	at    time.Time
+	ch    chan time.Time
+	f     func()
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.added = sync.NewCond(&c.lock)
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.wait(&fakeWaiter{clock: c, ch: ch}, d)
+	return ch
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	w := &fakeWaiter{clock: c, f: f}
+	c.wait(w, d)
+	return w
+}
+
+func (c *FakeClock) wait(w *fakeWaiter, d time.Duration) {
+	c.lock.Lock()
+	w.at = c.now.Add(d)
+	c.waiters = append(c.waiters, w)
+	c.added.Broadcast()
+	c.lock.Unlock()
+
+	if d <= 0 {
+		c.Advance(0)
+	}
+}
+
+func (w *fakeWaiter) Stop() bool {
+	w.clock.lock.Lock()
+	defer w.clock.lock.Unlock()
+
+	for i, waiter := range w.clock.waiters {
+		if waiter == w {
+			w.clock.waiters = append(w.clock.waiters[:i], w.clock.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Move the clock forward, firing every timer that comes due in the order
+// they're due. Functions from AfterFunc run before Advance returns.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+
+	due := []*fakeWaiter{}
+	waiting := []*fakeWaiter{}
+	for _, w := range c.waiters {
+		if !w.at.After(now) {
+			due = append(due, w)
+		} else {
+			waiting = append(waiting, w)
+		}
+	}
+	c.waiters = waiting
+	c.lock.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].at.Before(due[j].at)
+	})
+	for _, w := range due {
+		if w.f != nil {
+			w.f()
+		} else {
+			w.ch <- now
+		}
+	}
+}
+
+// Block until at least n timers are waiting, so that a test knows a
+// goroutine has gotten to its After before advancing.
+func (c *FakeClock) BlockUntil(n int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for len(c.waiters) < n {
+		c.added.Wait()
+	}
+}
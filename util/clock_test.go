@@ -0,0 +1,76 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	fired := []string{}
+	clock.AfterFunc(2*time.Hour, func() { fired = append(fired, "2h") })
+	clock.AfterFunc(time.Hour, func() { fired = append(fired, "1h") })
+	stopped := clock.AfterFunc(time.Hour, func() { fired = append(fired, "stopped") })
+	after := clock.After(90 * time.Minute)
+
+	if !stopped.Stop() || stopped.Stop() {
+		t.Errorf("expected only the first stop to succeed")
+	}
+
+	clock.Advance(time.Hour)
+	if len(fired) != 1 || fired[0] != "1h" {
+		t.Errorf("expected only the 1h timer to fire, but got %v", fired)
+	}
+	select {
+	case <-after:
+		t.Errorf("expected After to wait for 90 minutes")
+	default:
+	}
+
+	clock.Advance(2 * time.Hour)
+	if len(fired) != 2 || fired[1] != "2h" {
+		t.Errorf("expected the 2h timer to fire, but got %v", fired)
+	}
+	if at := <-after; !at.Equal(start.Add(3 * time.Hour)) {
+		t.Errorf("expected After to get the current time, but got %s", at)
+	}
+	if !clock.Now().Equal(start.Add(3 * time.Hour)) {
+		t.Errorf("expected the clock to have moved 3 hours, but got %s", clock.Now())
+	}
+
+	// Zero durations fire right away
+	select {
+	case <-clock.After(0):
+	default:
+		t.Errorf("expected After(0) to fire right away")
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tests := []struct {
+		unit          string
+		duration      string
+		expected      int64
+		expectedError bool
+	}{
+		{unit: "hours", duration: "2", expected: 1000 + 7200},
+		{unit: "week", duration: "1", expected: 1000 + 604800},
+		{unit: "minit", duration: "1", expectedError: true},
+		{unit: "minutes", duration: "few", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.duration+" "+tt.unit, func(t *testing.T) {
+			expiry, err := ParseExpiry(now, tt.unit, tt.duration)
+			if tt.expectedError != (err != nil) {
+				t.Fatalf("expected error %v, but got: %v", tt.expectedError, err)
+			}
+			if expiry != tt.expected {
+				t.Errorf("expected %d, but got %d", tt.expected, expiry)
+			}
+		})
+	}
+}
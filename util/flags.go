@@ -0,0 +1,150 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The flags a command understands, mapped to whether the flag takes a value.
+// For example {"-i": true, "-a": false} for "!gif dog -i 3" and "!gif dog -a".
+type FlagSpec map[string]bool
+
+// The result of parsing a command's arguments.
+type Flags struct {
+	// Everything that wasn't a flag or a flag's value, in order.
+	Terms  []string
+	values map[string]string
+}
+
+// The non-flag arguments joined back together, suitable for a search query.
+func (f *Flags) Query() string {
+	return strings.Join(f.Terms, " ")
+}
+
+// Whether the flag was passed at all.
+func (f *Flags) Has(name string) bool {
+	_, ok := f.values[name]
+	return ok
+}
+
+// The value passed to a flag, or def if the flag wasn't passed.
+func (f *Flags) Value(name, def string) string {
+	if value, ok := f.values[name]; ok {
+		return value
+	}
+	return def
+}
+
+// The value passed to a flag as an int, or def if the flag wasn't passed.
+func (f *Flags) Int(name string, def int) (int, error) {
+	value, ok := f.values[name]
+	if !ok {
+		return def, nil
+	}
+
+	num, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number, not \"%s\"", name, value)
+	}
+
+	return num, nil
+}
+
+// Parse a message's content into terms and flags. The first word (the command
+// itself) is skipped. Double quotes group words and stop anything inside them
+// from being treated as a flag. Errors are meant to be shown to the user.
+func ParseFlags(content string, spec FlagSpec) (*Flags, error) {
+	tokens, quoted, err := splitArgs(content)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := &Flags{
+		Terms:  []string{},
+		values: map[string]string{},
+	}
+
+	// Skip the command
+	for i := 1; i < len(tokens); i++ {
+		token := tokens[i]
+		if quoted[i] || !isFlag(token) {
+			flags.Terms = append(flags.Terms, token)
+			continue
+		}
+
+		takesValue, ok := spec[token]
+		if !ok {
+			return nil, fmt.Errorf("unknown flag %s, expected one of: %s", token, spec.names())
+		}
+
+		if !takesValue {
+			flags.values[token] = ""
+			continue
+		}
+
+		if i+1 >= len(tokens) {
+			return nil, fmt.Errorf("flag %s needs a value after it", token)
+		}
+
+		i++
+		flags.values[token] = tokens[i]
+	}
+
+	return flags, nil
+}
+
+func (s FlagSpec) names() string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Flags start with a dash followed by a letter, so that "-5" or "-" are terms.
+func isFlag(token string) bool {
+	name := strings.TrimLeft(token, "-")
+	return len(name) < len(token) && name != "" && unicode.IsLetter(rune(name[0]))
+}
+
+// Split on whitespace, keeping double quoted sections together. Also reports
+// which tokens were quoted.
+func splitArgs(content string) ([]string, []bool, error) {
+	tokens := []string{}
+	quoted := []bool{}
+
+	var current strings.Builder
+	inToken, inQuote, wasQuoted := false, false, false
+	for _, r := range content {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			inToken, wasQuoted = true, true
+		case unicode.IsSpace(r) && !inQuote:
+			if inToken {
+				tokens = append(tokens, current.String())
+				quoted = append(quoted, wasQuoted)
+				current.Reset()
+			}
+			inToken, wasQuoted = false, false
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if inQuote {
+		return nil, nil, fmt.Errorf("missing a closing quote")
+	}
+
+	if inToken {
+		tokens = append(tokens, current.String())
+		quoted = append(quoted, wasQuoted)
+	}
+
+	return tokens, quoted, nil
+}
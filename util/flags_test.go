@@ -0,0 +1,115 @@
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	spec := FlagSpec{"-i": true, "-a": false}
+	tests := []struct {
+		name           string
+		content        string
+		expectedTerms  []string
+		expectedValues map[string]string
+		expectedError  string
+	}{
+		{
+			name:           "Test only terms",
+			content:        "!gif funny dog",
+			expectedTerms:  []string{"funny", "dog"},
+			expectedValues: map[string]string{},
+		},
+		{
+			name:           "Test value and bool flags in any position",
+			content:        "!gif -a funny -i 3 dog",
+			expectedTerms:  []string{"funny", "dog"},
+			expectedValues: map[string]string{"-a": "", "-i": "3"},
+		},
+		{
+			name:           "Test negative value",
+			content:        "!gif dog -i -1",
+			expectedTerms:  []string{"dog"},
+			expectedValues: map[string]string{"-i": "-1"},
+		},
+		{
+			name:           "Test quoted terms are kept together and not treated as flags",
+			content:        `!gif "hot dog" "-a" -i 2`,
+			expectedTerms:  []string{"hot dog", "-a"},
+			expectedValues: map[string]string{"-i": "2"},
+		},
+		{
+			name:           "Test special characters are kept as terms",
+			content:        "!gif cats & dogs #1? -5 ümlaut",
+			expectedTerms:  []string{"cats", "&", "dogs", "#1?", "-5", "ümlaut"},
+			expectedValues: map[string]string{},
+		},
+		{
+			name:           "Test extra whitespace is ignored",
+			content:        "!gif   dog  ",
+			expectedTerms:  []string{"dog"},
+			expectedValues: map[string]string{},
+		},
+		{
+			name:          "Test trailing value flag",
+			content:       "!gif dog -i",
+			expectedError: "flag -i needs a value",
+		},
+		{
+			name:          "Test unknown flag",
+			content:       "!gif dog -x",
+			expectedError: "unknown flag -x, expected one of: -a, -i",
+		},
+		{
+			name:          "Test unterminated quote",
+			content:       `!gif "dog`,
+			expectedError: "missing a closing quote",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, err := ParseFlags(tt.content, spec)
+			if tt.expectedError != "" {
+				if err == nil {
+					t.Fatalf("expected error '%s' but got nil", tt.expectedError)
+				}
+				if !strings.Contains(err.Error(), tt.expectedError) {
+					t.Errorf("expected error '%s', but got '%v'", tt.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error but got error: '%v'", err)
+			}
+			if !reflect.DeepEqual(flags.Terms, tt.expectedTerms) {
+				t.Errorf("expected terms %q, but got %q", tt.expectedTerms, flags.Terms)
+			}
+			if !reflect.DeepEqual(flags.values, tt.expectedValues) {
+				t.Errorf("expected values %v, but got %v", tt.expectedValues, flags.values)
+			}
+		})
+	}
+}
+
+func TestFlagsInt(t *testing.T) {
+	flags, err := ParseFlags("!gif dog -i 3", FlagSpec{"-i": true, "-p": true})
+	if err != nil {
+		t.Fatalf("expected no error but got error: '%v'", err)
+	}
+
+	if idx, err := flags.Int("-i", 0); err != nil || idx != 3 {
+		t.Errorf("expected -i to be 3, but got %d (%v)", idx, err)
+	}
+
+	if page, err := flags.Int("-p", 1); err != nil || page != 1 {
+		t.Errorf("expected -p to default to 1, but got %d (%v)", page, err)
+	}
+
+	flags, _ = ParseFlags("!gif dog -i three", FlagSpec{"-i": true})
+	if _, err := flags.Int("-i", 0); err == nil {
+		t.Errorf("expected an error for a non-numeric value")
+	}
+}
@@ -0,0 +1,294 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Requests that failed after any retries. The reason is "unavailable" when the
+// breaker was open, "error" when there was no response, and "status" for a
+// 5xx or 429 response.
+var upstreamErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "saltbot_upstream_errors_total",
+	Help: "Requests to integrations that failed after retries, by service and reason.",
+}, []string{"service", "reason"})
+
+// Returned (wrapped in an UnavailableError) when a host's circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// The request was not sent because the upstream service has been failing.
+type UnavailableError struct {
+	Service string
+	Host    string
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s (%s) is unavailable: %v", e.Service, e.Host, ErrCircuitOpen)
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// A circuit breaker for a single host. After threshold consecutive failures the
+// breaker opens and rejects requests until the cooldown passes. It then lets a
+// single trial request through, closing again if that request succeeds.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	state     breakerState
+	openedAt  time.Time
+	probing   bool
+	clock     Clock
+	lock      sync.Mutex
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration, clock Clock) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		clock:     clock,
+	}
+}
+
+// Whether a request may be sent right now.
+func (b *CircuitBreaker) Allow() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.clock.Now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		// Only a single trial request at a time
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+
+	return true
+}
+
+func (b *CircuitBreaker) Success() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	b.state = breakerClosed
+}
+
+func (b *CircuitBreaker) Failure() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = b.clock.Now()
+	}
+}
+
+func (b *CircuitBreaker) IsOpen() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.state == breakerOpen
+}
+
+// An HttpClientInterface with a per-request deadline, retries on 5xx and 429
+// responses (honoring Retry-After), and a circuit breaker for each host.
+type ResilientClient struct {
+	service       string
+	client        *http.Client
+	maxRetries    int
+	baseBackoff   time.Duration
+	maxRetryAfter time.Duration
+	threshold     int
+	cooldown      time.Duration
+	breakers      map[string]*CircuitBreaker
+	lock          sync.Mutex
+
+	// Times the breakers' cooldowns
+	clock Clock
+
+	// Swappable for testing so retries don't actually wait
+	sleep func(context.Context, time.Duration) error
+}
+
+// Create a client for an integration. The service name is what users see when
+// the breaker is open, e.g. "giphy is down". The breakers cool down going by
+// the clock.
+func NewResilientClient(service string, timeout time.Duration, clock Clock) *ResilientClient {
+	return &ResilientClient{
+		service:       service,
+		client:        &http.Client{Timeout: timeout},
+		maxRetries:    2,
+		baseBackoff:   250 * time.Millisecond,
+		maxRetryAfter: 5 * time.Second,
+		threshold:     5,
+		cooldown:      30 * time.Second,
+		breakers:      map[string]*CircuitBreaker{},
+		clock:         clock,
+		sleep:         sleepContext,
+	}
+}
+
+func (c *ResilientClient) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Do(req)
+}
+
+func (c *ResilientClient) Do(req *http.Request) (*http.Response, error) {
+	breaker := c.breaker(req.URL.Host)
+	if !breaker.Allow() {
+		upstreamErrors.WithLabelValues(c.service, "unavailable").Inc()
+		return nil, &UnavailableError{Service: c.service, Host: req.URL.Host}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				breaker.Failure()
+				upstreamErrors.WithLabelValues(c.service, "error").Inc()
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			req.Body = body
+		}
+
+		resp, err := c.client.Do(req)
+		wait, retry := c.retryAfter(req, resp, err, attempt)
+		if !retry {
+			if err != nil || resp.StatusCode >= http.StatusInternalServerError {
+				breaker.Failure()
+			} else {
+				breaker.Success()
+			}
+
+			if err != nil {
+				upstreamErrors.WithLabelValues(c.service, "error").Inc()
+			} else if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+				upstreamErrors.WithLabelValues(c.service, "status").Inc()
+			}
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err := c.sleep(req.Context(), wait); err != nil {
+			breaker.Failure()
+			upstreamErrors.WithLabelValues(c.service, "error").Inc()
+			return nil, err
+		}
+	}
+}
+
+func (c *ResilientClient) breaker(host string) *CircuitBreaker {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	breaker, ok := c.breakers[host]
+	if !ok {
+		breaker = NewCircuitBreaker(c.threshold, c.cooldown, c.clock)
+		c.breakers[host] = breaker
+	}
+
+	return breaker
+}
+
+// Decide whether the attempt should be retried, and how long to wait first.
+func (c *ResilientClient) retryAfter(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= c.maxRetries || req.Context().Err() != nil {
+		return 0, false
+	}
+
+	// Can't resend a body we've already consumed
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return 0, false
+	}
+
+	backoff := c.baseBackoff << attempt
+	if err != nil {
+		return backoff, true
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < http.StatusInternalServerError {
+		return 0, false
+	}
+
+	if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		// Don't hold up the handler for an upstream that wants us gone for a while
+		if wait > c.maxRetryAfter {
+			return 0, false
+		}
+		return wait, true
+	}
+
+	return backoff, true
+}
+
+// Retry-After is either a number of seconds or an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,265 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// A client on a fake clock that records its retry waits rather than waiting.
+func newTestClient() (*ResilientClient, *[]time.Duration) {
+	waits := []time.Duration{}
+	client := NewResilientClient("test", time.Second, NewFakeClock(time.Unix(0, 0)))
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	return client, &waits
+}
+
+func TestResilientClientRetries(t *testing.T) {
+	tests := []struct {
+		name           string
+		statuses       []int
+		retryAfter     string
+		expectedStatus int
+		expectedCalls  int
+		expectedWaits  []time.Duration
+	}{
+		{
+			name:           "Test success is not retried",
+			statuses:       []int{http.StatusOK},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  1,
+			expectedWaits:  []time.Duration{},
+		},
+		{
+			name:           "Test client errors are not retried",
+			statuses:       []int{http.StatusForbidden},
+			expectedStatus: http.StatusForbidden,
+			expectedCalls:  1,
+			expectedWaits:  []time.Duration{},
+		},
+		{
+			name:           "Test 5xx is retried with backoff",
+			statuses:       []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  3,
+			expectedWaits:  []time.Duration{250 * time.Millisecond, 500 * time.Millisecond},
+		},
+		{
+			name:           "Test retries give up after max retries",
+			statuses:       []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  3,
+			expectedWaits:  []time.Duration{250 * time.Millisecond, 500 * time.Millisecond},
+		},
+		{
+			name:           "Test 429 honors Retry-After",
+			statuses:       []int{http.StatusTooManyRequests, http.StatusOK},
+			retryAfter:     "2",
+			expectedStatus: http.StatusOK,
+			expectedCalls:  2,
+			expectedWaits:  []time.Duration{2 * time.Second},
+		},
+		{
+			name:           "Test 429 with long Retry-After is not retried",
+			statuses:       []int{http.StatusTooManyRequests, http.StatusOK},
+			retryAfter:     "120",
+			expectedStatus: http.StatusTooManyRequests,
+			expectedCalls:  1,
+			expectedWaits:  []time.Duration{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.statuses[calls])
+				calls++
+			}))
+			defer server.Close()
+
+			client, waits := newTestClient()
+			resp, err := client.Get(context.Background(), server.URL)
+			if err != nil {
+				t.Fatalf("expected no error but got error: '%v'", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %d, but got %d", tt.expectedStatus, resp.StatusCode)
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("expected %d calls, but got %d", tt.expectedCalls, calls)
+			}
+			if len(*waits) != len(tt.expectedWaits) {
+				t.Fatalf("expected waits %v, but got %v", tt.expectedWaits, *waits)
+			}
+			for i, wait := range tt.expectedWaits {
+				if (*waits)[i] != wait {
+					t.Errorf("expected wait %d to be %s, but got %s", i, wait, (*waits)[i])
+				}
+			}
+		})
+	}
+}
+
+func TestResilientClientTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	client, _ := newTestClient()
+	client.client.Timeout = 50 * time.Millisecond
+	client.maxRetries = 0
+
+	start := time.Now()
+	_, err := client.Get(context.Background(), server.URL)
+	if err == nil {
+		t.Fatalf("expected a timeout error but got nil")
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("expected request to time out quickly, but took %s", time.Since(start))
+	}
+}
+
+func TestResilientClientCanceled(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, waits := newTestClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.Get(ctx, server.URL)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled error but got: '%v'", err)
+	}
+	if calls != 0 || len(*waits) != 0 {
+		t.Errorf("expected no requests or retries, but got %d requests and waits %v", calls, *waits)
+	}
+}
+
+func TestResilientClientCircuitBreaker(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient()
+	client.maxRetries = 0
+	statuses := testutil.ToFloat64(upstreamErrors.WithLabelValues("test", "status"))
+	unavailables := testutil.ToFloat64(upstreamErrors.WithLabelValues("test", "unavailable"))
+
+	for i := 0; i < client.threshold; i++ {
+		resp, err := client.Get(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("expected no error before breaker opens but got: '%v'", err)
+		}
+		resp.Body.Close()
+	}
+
+	_, err := client.Get(context.Background(), server.URL)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected circuit open error but got: '%v'", err)
+	}
+
+	var unavailable *UnavailableError
+	if !errors.As(err, &unavailable) || unavailable.Service != "test" {
+		t.Errorf("expected UnavailableError for service 'test', but got: '%v'", err)
+	}
+
+	if calls != client.threshold {
+		t.Errorf("expected %d calls to reach the server, but got %d", client.threshold, calls)
+	}
+
+	if testutil.ToFloat64(upstreamErrors.WithLabelValues("test", "status"))-statuses != float64(client.threshold) ||
+		testutil.ToFloat64(upstreamErrors.WithLabelValues("test", "unavailable"))-unavailables != 1 {
+		t.Errorf("expected %d failed statuses and 1 unavailable to be counted", client.threshold)
+	}
+
+	// Once the client's clock passes the cooldown, a trial request is let through
+	client.clock.(*FakeClock).Advance(client.cooldown)
+	resp, err := client.Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("expected a trial request after the cooldown but got: '%v'", err)
+	}
+	resp.Body.Close()
+	if calls != client.threshold+1 {
+		t.Errorf("expected the trial request to reach the server, but got %d calls", calls)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	breaker := NewCircuitBreaker(2, time.Minute, clock)
+
+	breaker.Failure()
+	if !breaker.Allow() {
+		t.Fatalf("expected breaker to allow requests below the threshold")
+	}
+
+	breaker.Failure()
+	if breaker.Allow() {
+		t.Fatalf("expected breaker to be open after reaching the threshold")
+	}
+
+	clock.Advance(2 * time.Minute)
+	if !breaker.Allow() {
+		t.Fatalf("expected breaker to allow a trial request after the cooldown")
+	}
+	if breaker.Allow() {
+		t.Fatalf("expected breaker to only allow a single trial request")
+	}
+
+	breaker.Failure()
+	if !breaker.IsOpen() {
+		t.Fatalf("expected failed trial request to reopen the breaker")
+	}
+
+	clock.Advance(2 * time.Minute)
+	breaker.Allow()
+	breaker.Success()
+	if breaker.IsOpen() || !breaker.Allow() {
+		t.Fatalf("expected successful trial request to close the breaker")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value         string
+		expectedWait  time.Duration
+		expectedFound bool
+	}{
+		{value: "", expectedFound: false},
+		{value: "3", expectedWait: 3 * time.Second, expectedFound: true},
+		{value: "soon", expectedFound: false},
+		{value: "Mon, 02 Jan 2006 15:04:05 GMT", expectedWait: 0, expectedFound: true},
+	}
+
+	for _, tt := range tests {
+		wait, found := parseRetryAfter(tt.value)
+		if found != tt.expectedFound || wait != tt.expectedWait {
+			t.Errorf("expected '%s' to parse to (%s, %v), but got (%s, %v)", tt.value, tt.expectedWait, tt.expectedFound, wait, found)
+		}
+	}
+}
@@ -1,18 +1,25 @@
 package util
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
 	"strconv"
-	"strings"
 	"time"
 )
 
 // Client interface for testing of 3rd party web services like youtube, giphy, jeopardy, etc...
+// Requests are made with the caller's context so a command's deadline cancels them.
 type HttpClientInterface interface {
-	Get(string) (*http.Response, error)
+	Get(context.Context, string) (*http.Response, error)
+	Do(*http.Request) (*http.Response, error)
 }
 
+// How long a single request to a 3rd party web service may take.
+const HttpTimeout = 10 * time.Second
+
 var unitDict map[string]int = map[string]int{
 	"year":    31536000,
 	"years":   31536000,
@@ -30,33 +37,9 @@ var unitDict map[string]int = map[string]int{
 	"second":  1,
 }
 
-// remove "-a" or "-i" from the args
-func ParseArgsToQuery(args []string) string {
-	parsedArgs := make([]string, 0)
-	foundIndex := false
-	for _, arg := range args {
-		if foundIndex {
-			foundIndex = false
-			continue
-		}
-
-		if arg == "-i" {
-			foundIndex = true
-			continue
-		}
-
-		if arg == "-a" {
-			continue
-		}
-
-		parsedArgs = append(parsedArgs, arg)
-	}
-
-	return strings.Join(parsedArgs, "+")
-}
-
-// Take in the unit of time and duration and return the unix epoch
-func ParseExpiry(unit, duration string) (int64, error) {
+// Take in the unit of time and duration and return the unix epoch that long
+// after now
+func ParseExpiry(now time.Time, unit, duration string) (int64, error) {
 	unitInt, ok := unitDict[unit]
 	if !ok {
 		return 0, fmt.Errorf("unparseable unit: %s", unit)
@@ -68,11 +51,30 @@ func ParseExpiry(unit, duration string) (int64, error) {
 	}
 
 	fullDuration := parsedDuration * unitInt
-	expiry := time.Now().Unix() + int64(fullDuration)
+	expiry := now.Unix() + int64(fullDuration)
 	return expiry, nil
 }
 
-func TimeFromExpiry(expiry int64) string {
-	expiryTime := time.Unix(expiry, 0)
+// Format the unix epoch in the given time zone
+func TimeFromExpiry(expiry int64, loc *time.Location) string {
+	expiryTime := time.Unix(expiry, 0).In(loc)
 	return expiryTime.Format(time.RFC1123)
 }
+
+// Read an interval such as "10m" from an env var, falling back to def if it's
+// missing, invalid or shorter than min. Anything polling on the interval would
+// otherwise spin.
+func IntervalFromEnv(envVar string, def, min time.Duration) time.Duration {
+	value, ok := os.LookupEnv(envVar)
+	if !ok {
+		return def
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < min {
+		slog.Warn("invalid interval, using the default", "env", envVar, "value", value, "min", min, "default", def, "error", err)
+		return def
+	}
+
+	return interval
+}
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIntervalFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		set      bool
+		expected time.Duration
+	}{
+		{
+			name:     "Test unset",
+			expected: 10 * time.Minute,
+		},
+		{
+			name:     "Test valid interval",
+			value:    "5m",
+			set:      true,
+			expected: 5 * time.Minute,
+		},
+		{
+			name:     "Test zero",
+			value:    "0",
+			set:      true,
+			expected: 10 * time.Minute,
+		},
+		{
+			name:     "Test negative",
+			value:    "-5m",
+			set:      true,
+			expected: 10 * time.Minute,
+		},
+		{
+			name:     "Test below the minimum",
+			value:    "30s",
+			set:      true,
+			expected: 10 * time.Minute,
+		},
+		{
+			name:     "Test invalid",
+			value:    "often",
+			set:      true,
+			expected: 10 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.set {
+				t.Setenv("TEST_INTERVAL", tt.value)
+			}
+
+			actual := IntervalFromEnv("TEST_INTERVAL", 10*time.Minute, time.Minute)
+			if actual != tt.expected {
+				t.Errorf("expected %s, but got %s", tt.expected, actual)
+			}
+		})
+	}
+}
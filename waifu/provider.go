@@ -0,0 +1,173 @@
+package waifu
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A single waifu picture. Images from a website have a Url and the Site they
+// came from, while images on disk have a Path and are uploaded with the message.
+type Image struct {
+	Url  string
+	Site string
+	Path string
+}
+
+// Identifies the image for avoiding repeats.
+func (i Image) key() string {
+	if i.Path != "" {
+		return i.Path
+	}
+	return i.Url
+}
+
+// A source of waifu pictures.
+type ImageProvider interface {
+	Name() string
+	Random(ctx context.Context) (Image, error)
+}
+
+// Pictures from thiswaifudoesnotexist.net, which has numbered examples.
+type siteProvider struct{}
+
+// The highest example number on thiswaifudoesnotexist.net
+const maxSiteExample = 99999
+
+func (p siteProvider) Name() string {
+	return "thiswaifudoesnotexist"
+}
+
+func (p siteProvider) Random(ctx context.Context) (Image, error) {
+	num := rand.Intn(maxSiteExample)
+	return Image{
+		Url:  fmt.Sprintf("https://www.thiswaifudoesnotexist.net/example-%d.jpg", num),
+		Site: "thiswaifudoesnotexist.net",
+	}, nil
+}
+
+// File extensions picked up from an image directory
+var imageExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true}
+
+// Pictures in a directory on disk.
+type dirProvider struct {
+	dir string
+}
+
+func (p dirProvider) Name() string {
+	return "directory"
+}
+
+// The directory is read every time so that pictures can be added or removed
+// without a restart.
+func (p dirProvider) Random(ctx context.Context) (Image, error) {
+	entries, err := os.ReadDir(p.dir)
+	if err != nil {
+		return Image{}, fmt.Errorf("failed to read waifu directory: %w", err)
+	}
+
+	paths := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && imageExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+			paths = append(paths, filepath.Join(p.dir, entry.Name()))
+		}
+	}
+
+	if len(paths) == 0 {
+		return Image{}, fmt.Errorf("no images in %s", p.dir)
+	}
+	return Image{Path: paths[rand.Intn(len(paths))]}, nil
+}
+
+// Pictures listed in an index file, like one kept next to the pictures in a
+// storage bucket. The index has one url per line, relative to the index.
+type indexProvider struct {
+	index *url.URL
+}
+
+func (p indexProvider) Name() string {
+	return "index"
+}
+
+func (p indexProvider) Random(ctx context.Context) (Image, error) {
+	urls, err := p.fetchIndex(ctx)
+	if err != nil {
+		return Image{}, err
+	}
+
+	if len(urls) == 0 {
+		return Image{}, fmt.Errorf("no images in %s", p.index)
+	}
+	return Image{Url: urls[rand.Intn(len(urls))], Site: p.index.Host}, nil
+}
+
+func (p indexProvider) fetchIndex(ctx context.Context) ([]string, error) {
+	key := p.index.String()
+	if cached, ok := indexCache.Get(key); ok {
+		return cached.([]string), nil
+	}
+
+	resp, err := client.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waifu index: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code %d getting waifu index", resp.StatusCode)
+	}
+
+	urls, err := parseIndex(p.index, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	indexCache.Add(key, urls)
+	return urls, nil
+}
+
+// Read an index, skipping blank lines and "#" comments.
+func parseIndex(base *url.URL, body io.Reader) ([]string, error) {
+	urls := []string{}
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		ref, err := url.Parse(line)
+		if err != nil {
+			continue
+		}
+		urls = append(urls, base.ResolveReference(ref).String())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read waifu index: %w", err)
+	}
+	return urls, nil
+}
+
+// Use the pool in WAIFU_IMAGES first if it's set, which is either a
+// directory or the url of an index. The website is always the fallback.
+func selectProviders(pool string) []ImageProvider {
+	selected := []ImageProvider{}
+	if strings.HasPrefix(pool, "http://") || strings.HasPrefix(pool, "https://") {
+		index, err := url.Parse(pool)
+		if err == nil {
+			selected = append(selected, indexProvider{index: index})
+		}
+	} else if pool != "" {
+		selected = append(selected, dirProvider{dir: pool})
+	}
+
+	return append(selected, siteProvider{})
+}
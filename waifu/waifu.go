@@ -0,0 +1,196 @@
+package waifu
+
+import (
+	"bytes"
+	"context"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/logging"
+	"github.com/highsaltlevels/saltbot/lrucache"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+// Picks to try from each provider before moving on to the next one
+const maxAttempts = 5
+
+// Number of images remembered per channel to avoid repeats
+const recentSize = 50
+
+// How long to wait when checking that an image exists
+const checkTimeout = 3 * time.Second
+
+var client util.HttpClientInterface
+
+// Checks images with a single short attempt. A missing image is just skipped,
+// so it isn't worth the retries the other requests get.
+var checkClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// Fetch and check images with c instead, like a stand in for the sites. Must
+// be called before any images are fetched.
+func SetClient(c util.HttpClientInterface) {
+	client = c
+	checkClient = c
+}
+
+// Providers in order of preference, set from WAIFU_IMAGES
+var providers []ImageProvider
+
+// Image indexes keyed on their url. TTL is set with WAIFU_INDEX_TTL.
+var indexCache *lrucache.Cache
+
+// The keys of images recently posted to each channel, oldest first
+var recent = map[string][]string{}
+var recentLock sync.Mutex
+
+func init() {
+	if client == nil {
+		client = util.NewResilientClient("waifu", util.HttpTimeout, util.SystemClock)
+	}
+	if checkClient == nil {
+		checkClient = &http.Client{Timeout: checkTimeout}
+	}
+
+	indexCache = lrucache.New("waifu", lrucache.DefaultSize, lrucache.TTLFromEnv("WAIFU_INDEX_TTL", time.Hour), util.SystemClock)
+	providers = selectProviders(os.Getenv("WAIFU_IMAGES"))
+}
+
+func wasRecent(channel, key string) bool {
+	recentLock.Lock()
+	defer recentLock.Unlock()
+
+	for _, k := range recent[channel] {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func remember(channel, key string) {
+	recentLock.Lock()
+	defer recentLock.Unlock()
+
+	keys := append(recent[channel], key)
+	if len(keys) > recentSize {
+		keys = keys[len(keys)-recentSize:]
+	}
+	recent[channel] = keys
+}
+
+// Whether the image is actually there. Urls are checked with a HEAD request
+// so that a missing picture isn't embedded.
+func exists(ctx context.Context, image Image) bool {
+	if image.Path != "" {
+		info, err := os.Stat(image.Path)
+		return err == nil && !info.IsDir()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, image.Url, nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := checkClient.Do(req)
+	if err != nil {
+		logging.From(ctx).Warn("failed to check waifu image", "url", image.Url, "error", err)
+		return false
+	}
+	resp.Body.Close()
+
+	contentType := resp.Header.Get("Content-Type")
+	return resp.StatusCode == http.StatusOK && (contentType == "" || strings.HasPrefix(contentType, "image/"))
+}
+
+// Find an image that exists and, if possible, hasn't been posted to the
+// channel recently. Returns false if no provider had one.
+func pick(ctx context.Context, channel string) (Image, bool) {
+	for _, provider := range providers {
+		var repeat *Image
+		for i := 0; i < maxAttempts; i++ {
+			image, err := provider.Random(ctx)
+			if err != nil {
+				logging.From(ctx).Warn("waifu provider failed", "provider", provider.Name(), "error", err)
+				break
+			}
+
+			if wasRecent(channel, image.key()) {
+				if repeat == nil && exists(ctx, image) {
+					repeat = &image
+				}
+				continue
+			}
+
+			if exists(ctx, image) {
+				return image, true
+			}
+		}
+
+		// A small pool might only have repeats left, which beats nothing
+		if repeat != nil {
+			return *repeat, true
+		}
+	}
+
+	return Image{}, false
+}
+
+func Get(ctx context.Context, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+	image, ok := pick(ctx, m.ChannelID)
+	if !ok {
+		return &discordgo.MessageSend{
+			Content: "```Couldn't find a waifu right now :(```",
+		}, nil
+	}
+
+	message := &discordgo.MessageSend{
+		Content: "Here's a waifu for you!",
+	}
+	if image.Path == "" {
+		message.Embeds = []*discordgo.MessageEmbed{
+			{
+				URL:         image.Url,
+				Type:        discordgo.EmbedTypeImage,
+				Title:       "Here's the sauce: " + image.Site,
+				Description: "Check out the link above to go to the site that makes this feature possible",
+				Image: &discordgo.MessageEmbedImage{
+					URL: image.Url,
+				},
+			},
+		}
+	} else {
+		data, err := os.ReadFile(image.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		name := filepath.Base(image.Path)
+		message.Files = []*discordgo.File{
+			{
+				Name:        name,
+				ContentType: mime.TypeByExtension(filepath.Ext(name)),
+				Reader:      bytes.NewReader(data),
+			},
+		}
+		message.Embeds = []*discordgo.MessageEmbed{
+			{
+				Type: discordgo.EmbedTypeImage,
+				Image: &discordgo.MessageEmbedImage{
+					URL: "attachment://" + name,
+				},
+			},
+		}
+	}
+
+	remember(m.ChannelID, image.key())
+	return message, nil
+}
@@ -0,0 +1,205 @@
+package waifu
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/lrucache"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+type MockHttpClient struct {
+	util.HttpClientInterface
+
+	// Status codes keyed on url for HEAD requests. Missing urls are a 404.
+	heads map[string]int
+
+	// The body returned from Get
+	index string
+
+	// Number of HEAD requests made
+	checks int
+}
+
+func (c *MockHttpClient) Get(ctx context.Context, url string) (*http.Response, error) {
+	if c.index == "" {
+		return nil, errors.New("expect me")
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(c.index)),
+	}, nil
+}
+
+func (c *MockHttpClient) Do(req *http.Request) (*http.Response, error) {
+	c.checks++
+	status, ok := c.heads[req.URL.String()]
+	if !ok {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"image/jpeg"}},
+		Body:       io.NopCloser(strings.NewReader("")),
+	}, nil
+}
+
+// Hands out images in order, wrapping around.
+type MockProvider struct {
+	images []Image
+	next   int
+}
+
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+func (p *MockProvider) Random(ctx context.Context) (Image, error) {
+	if len(p.images) == 0 {
+		return Image{}, errors.New("expect me")
+	}
+	image := p.images[p.next%len(p.images)]
+	p.next++
+	return image, nil
+}
+
+func newMessage(channel string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			Content:   "!waifu",
+			ChannelID: channel,
+		},
+	}
+}
+
+func reset(mock *MockHttpClient, p ...ImageProvider) {
+	client = mock
+	checkClient = mock
+	providers = p
+	recent = map[string][]string{}
+	indexCache = lrucache.New("waifu", lrucache.DefaultSize, time.Hour, util.SystemClock)
+}
+
+func TestGetSkipsMissingAndRecentImages(t *testing.T) {
+	mock := &MockHttpClient{heads: map[string]int{"https://a.jpg": http.StatusOK, "https://c.jpg": http.StatusOK}}
+	reset(mock, &MockProvider{images: []Image{{Url: "https://a.jpg"}, {Url: "https://b.jpg"}, {Url: "https://c.jpg"}}})
+
+	expected := []string{"https://a.jpg", "https://c.jpg", "https://a.jpg"}
+	for i, url := range expected {
+		msg, err := Get(context.Background(), newMessage("channel"))
+		if err != nil {
+			t.Fatalf("expected no error but got: %v", err)
+		}
+		if msg.Embeds[0].Image.URL != url {
+			t.Errorf("expected pick %d to be %s, but got %s", i, url, msg.Embeds[0].Image.URL)
+		}
+	}
+
+	// Other channels have their own history
+	msg, _ := Get(context.Background(), newMessage("other"))
+	if msg.Embeds[0].Image.URL != "https://c.jpg" {
+		t.Errorf("expected the other channel to get c, but got %s", msg.Embeds[0].Image.URL)
+	}
+}
+
+func TestGetFallsBack(t *testing.T) {
+	mock := &MockHttpClient{heads: map[string]int{"https://fallback.jpg": http.StatusOK}}
+	reset(mock, &MockProvider{}, &MockProvider{images: []Image{{Url: "https://missing.jpg"}}},
+		&MockProvider{images: []Image{{Url: "https://fallback.jpg"}}})
+
+	msg, err := Get(context.Background(), newMessage("channel"))
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if msg.Embeds[0].Image.URL != "https://fallback.jpg" {
+		t.Errorf("expected the fallback image, but got %s", msg.Embeds[0].Image.URL)
+	}
+	if mock.checks != maxAttempts+1 {
+		t.Errorf("expected %d checks, but got %d", maxAttempts+1, mock.checks)
+	}
+
+	reset(mock, &MockProvider{images: []Image{{Url: "https://missing.jpg"}}})
+	msg, _ = Get(context.Background(), newMessage("channel"))
+	if msg.Content != "```Couldn't find a waifu right now :(```" {
+		t.Errorf("unexpected message when nothing exists: '%s'", msg.Content)
+	}
+}
+
+func TestDirProvider(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "waifu.png"), []byte("png"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an image"), 0644)
+	reset(&MockHttpClient{}, selectProviders(dir)...)
+
+	msg, err := Get(context.Background(), newMessage("channel"))
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if len(msg.Files) != 1 || msg.Files[0].Name != "waifu.png" || msg.Embeds[0].Image.URL != "attachment://waifu.png" {
+		t.Errorf("expected waifu.png to be attached, but got %+v", msg)
+	}
+
+	_, err = dirProvider{dir: t.TempDir()}.Random(context.Background())
+	if err == nil {
+		t.Errorf("expected an error for an empty directory")
+	}
+}
+
+func TestIndexProvider(t *testing.T) {
+	mock := &MockHttpClient{
+		index: "# waifus\nhttps://cdn.example.com/1.jpg\n\n2.jpg\n",
+		heads: map[string]int{
+			"https://cdn.example.com/1.jpg":           http.StatusOK,
+			"https://bucket.example.com/waifus/2.jpg": http.StatusOK,
+		},
+	}
+	reset(mock, selectProviders("https://bucket.example.com/waifus/index.txt")...)
+
+	index, _ := url.Parse("https://bucket.example.com/waifus/index.txt")
+	urls, err := indexProvider{index: index}.fetchIndex(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if len(urls) != 2 || urls[0] != "https://cdn.example.com/1.jpg" || urls[1] != "https://bucket.example.com/waifus/2.jpg" {
+		t.Errorf("unexpected index: %v", urls)
+	}
+
+	msg, _ := Get(context.Background(), newMessage("channel"))
+	if len(msg.Embeds) != 1 || !strings.HasPrefix(msg.Embeds[0].Image.URL, "https://") {
+		t.Fatalf("expected an image from the index, but got %+v", msg)
+	}
+	if mock.checks != 1 || !wasRecent("channel", msg.Embeds[0].Image.URL) {
+		t.Errorf("expected the image to be checked once and remembered")
+	}
+	if msg.Embeds[0].Title != "Here's the sauce: bucket.example.com" {
+		t.Errorf("expected the embed to credit the index's site, but got '%s'", msg.Embeds[0].Title)
+	}
+}
+
+func TestSelectProviders(t *testing.T) {
+	tests := map[string][]string{
+		"":                         {"thiswaifudoesnotexist"},
+		"/images":                  {"directory", "thiswaifudoesnotexist"},
+		"https://example.com/list": {"index", "thiswaifudoesnotexist"},
+	}
+	for pool, expected := range tests {
+		selected := selectProviders(pool)
+		names := []string{}
+		for _, p := range selected {
+			names = append(names, p.Name())
+		}
+		if strings.Join(names, ",") != strings.Join(expected, ",") {
+			t.Errorf("expected providers %v for %q, but got %v", expected, pool, names)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package youtube
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Youtube red
+const embedColor = 0xFF0000
+
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)D)?T?(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?$`)
+
+// Turn an ISO 8601 duration like PT1H2M3S into 1:02:03. Returns an empty
+// string if it can't be parsed.
+func formatDuration(duration string) string {
+	parts := isoDuration.FindStringSubmatch(duration)
+	if parts == nil || duration == "P" || duration == "PT" {
+		return ""
+	}
+
+	nums := make([]int, 4)
+	for i, part := range parts[1:] {
+		nums[i], _ = strconv.Atoi(part)
+	}
+	hours := nums[0]*24 + nums[1]
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, nums[2], nums[3])
+	}
+	return fmt.Sprintf("%d:%02d", nums[2], nums[3])
+}
+
+// Add thousands separators to a view count, e.g. 1234567 -> 1,234,567
+func formatViews(views string) string {
+	if _, err := strconv.ParseUint(views, 10, 64); err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for i, digit := range views {
+		if i > 0 && (len(views)-i)%3 == 0 {
+			b.WriteRune(',')
+		}
+		b.WriteRune(digit)
+	}
+	return b.String()
+}
+
+func formatPublished(publishedAt string) string {
+	published, err := time.Parse(time.RFC3339, publishedAt)
+	if err != nil {
+		return ""
+	}
+	return published.Format("Jan 2, 2006")
+}
+
+func (t YoutubeThumbnails) best() string {
+	for _, thumbnail := range []YoutubeThumbnail{t.High, t.Medium, t.Default} {
+		if thumbnail.Url != "" {
+			return thumbnail.Url
+		}
+	}
+	return ""
+}
+
+// Youtube escapes html entities in titles, e.g. &#39; for '
+func title(video YoutubeVideo) string {
+	if video.Snippet.Title == "" {
+		return watchUrl + video.Id.VideoId
+	}
+	return html.UnescapeString(video.Snippet.Title)
+}
+
+func videoEmbed(video YoutubeVideo, details YoutubeDetails) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		URL:   watchUrl + video.Id.VideoId,
+		Type:  discordgo.EmbedTypeRich,
+		Title: title(video),
+		Color: embedColor,
+	}
+
+	if video.Snippet.ChannelTitle != "" {
+		embed.Author = &discordgo.MessageEmbedAuthor{
+			Name: html.UnescapeString(video.Snippet.ChannelTitle),
+		}
+		if video.Snippet.ChannelId != "" {
+			embed.Author.URL = "https://www.youtube.com/channel/" + video.Snippet.ChannelId
+		}
+	}
+
+	if thumbnail := video.Snippet.Thumbnails.best(); thumbnail != "" {
+		embed.Image = &discordgo.MessageEmbedImage{
+			URL: thumbnail,
+		}
+	}
+
+	fields := []struct{ name, value string }{
+		{"Duration", formatDuration(details.ContentDetails.Duration)},
+		{"Views", formatViews(details.Statistics.ViewCount)},
+		{"Published", formatPublished(video.Snippet.PublishedAt)},
+	}
+	for _, field := range fields {
+		if field.value != "" {
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+				Name:   field.name,
+				Value:  field.value,
+				Inline: true,
+			})
+		}
+	}
+
+	return embed
+}
+
+// A numbered list of results. The numbers are the -i index to pick each one.
+func listEmbed(query string, videos []YoutubeVideo, details map[string]YoutubeDetails) *discordgo.MessageEmbed {
+	var description strings.Builder
+	for idx, video := range videos {
+		line := fmt.Sprintf("`%d` [%s](%s%s)", idx, title(video), watchUrl, video.Id.VideoId)
+		if video.Snippet.ChannelTitle != "" {
+			line += " · " + html.UnescapeString(video.Snippet.ChannelTitle)
+		}
+		if duration := formatDuration(details[video.Id.VideoId].ContentDetails.Duration); duration != "" {
+			line += " · " + duration
+		}
+		description.WriteString(line + "\n")
+	}
+
+	return &discordgo.MessageEmbed{
+		Type:        discordgo.EmbedTypeRich,
+		Title:       fmt.Sprintf("Youtube results for \"%s\"", query),
+		Description: description.String(),
+		Color:       embedColor,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Pick one with \"!youtube %s -i <number>\"", query),
+		},
+	}
+}
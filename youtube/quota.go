@@ -0,0 +1,223 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+// The id of the youtube quota configmap
+const quotaId = "youtube"
+
+// Quota units charged by the youtube API for each kind of call.
+// https://developers.google.com/youtube/v3/determine_quota_cost
+var quotaCosts = map[string]int{
+	searchUrl:   100,
+	videosUrl:   1,
+	channelsUrl: 1,
+}
+
+var quotaNames = map[string]string{
+	searchUrl:   "search.list",
+	videosUrl:   "videos.list",
+	channelsUrl: "channels.list",
+}
+
+// The daily quota for the API key. Set with YOUTUBE_QUOTA_LIMIT.
+var quotaLimit int
+
+// Units searches leave alone, so upload notifications, which only need a unit
+// or two, keep working once searches are used up. Set with
+// YOUTUBE_QUOTA_RESERVE.
+var quotaReserve int
+
+// Youtube's quota resets at midnight Pacific time.
+var pacific *time.Location
+
+var quota = &quotaTracker{clock: util.SystemClock}
+
+// Returned instead of calling youtube when the day's quota is used up.
+type QuotaError struct {
+	Resets time.Time
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("youtube quota is used up until %s", e.Resets.Format(time.RFC3339))
+}
+
+// Counts the quota units spent today. Usage is kept in memory and saved to a
+// configmap in the background after every call so that restarts don't forget
+// it.
+type quotaTracker struct {
+	day       string
+	units     map[string]int
+	exhausted bool
+	loaded    bool
+	lock      sync.Mutex
+
+	// Whether a save is running, and whether usage changed since it started
+	saving bool
+	dirty  bool
+	saves  sync.WaitGroup
+
+	// Where the day comes from, for the daily reset
+	clock util.Clock
+}
+
+func (q *quotaTracker) today() string {
+	return q.clock.Now().In(pacific).Format("2006-01-02")
+}
+
+// The next midnight in Pacific time.
+func (q *quotaTracker) resets() time.Time {
+	now := q.clock.Now().In(pacific)
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, pacific)
+}
+
+func (q *quotaTracker) used() int {
+	total := 0
+	for _, units := range q.units {
+		total += units
+	}
+	return total
+}
+
+// Pick up where we left off from the configmap, and start over on a new day.
+// Must hold the lock.
+func (q *quotaTracker) refresh() {
+	today := q.today()
+	if !q.loaded {
+		q.loaded = true
+		if saved := cache.Cache.GetQuota(quotaId); saved != nil && saved.Day == today {
+			q.day = saved.Day
+			q.units = saved.Units
+			q.exhausted = saved.Exhausted
+		}
+	}
+
+	if q.day != today || q.units == nil {
+		q.day = today
+		q.units = map[string]int{}
+		q.exhausted = false
+	}
+}
+
+// Record a call to endpoint, or return a QuotaError if it would go over the
+// daily limit. Searches are refused once they'd dip into the reserve.
+func (q *quotaTracker) spend(endpoint string) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.refresh()
+	cost := quotaCosts[endpoint]
+	limit := quotaLimit
+	if endpoint == searchUrl {
+		limit -= quotaReserve
+	}
+	if q.exhausted || q.used()+cost > limit {
+		return &QuotaError{Resets: q.resets()}
+	}
+
+	q.units[quotaNames[endpoint]] += cost
+	q.save()
+	return nil
+}
+
+// Youtube said the quota is used up, even if our count disagrees.
+func (q *quotaTracker) exhaust() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.refresh()
+	q.exhausted = true
+	q.save()
+}
+
+// Save usage without making youtube calls wait on kubernetes. Changes made
+// while a save is running are written together once it's done. Must hold the
+// lock.
+func (q *quotaTracker) save() {
+	q.dirty = true
+	if q.saving {
+		return
+	}
+
+	q.saving = true
+	q.saves.Add(1)
+	go q.flush(cache.Cache)
+}
+
+// Write the latest usage to store until there's nothing new to write. The
+// store is picked when the save starts so the background writes don't read
+// cache.Cache.
+func (q *quotaTracker) flush(store *cache.ConfigMapCache) {
+	defer q.saves.Done()
+
+	for {
+		q.lock.Lock()
+		if !q.dirty {
+			q.saving = false
+			q.lock.Unlock()
+			return
+		}
+		q.dirty = false
+
+		units := make(map[string]int, len(q.units))
+		for name, spent := range q.units {
+			units[name] = spent
+		}
+		saved := &cache.Quota{
+			Day:       q.day,
+			Units:     units,
+			Exhausted: q.exhausted,
+			Id:        quotaId,
+		}
+		q.lock.Unlock()
+
+		err := store.SetQuota(context.Background(), saved)
+		if err != nil {
+			slog.Error("failed to save youtube quota", "error", err)
+		}
+	}
+}
+
+// A summary of today's usage for admins.
+func (q *quotaTracker) usage() string {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.refresh()
+	names := make([]string, 0, len(q.units))
+	for name := range q.units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msg := fmt.Sprintf("```YouTube quota for %s (Pacific):\n", q.day)
+	for _, name := range names {
+		msg += fmt.Sprintf("%-14s %d units\n", name+":", q.units[name])
+	}
+	msg += fmt.Sprintf("\nUsed %d of %d units, searches stop at %d", q.used(), quotaLimit, quotaLimit-quotaReserve)
+	if q.exhausted {
+		msg += ", youtube says the quota is used up"
+	}
+	msg += fmt.Sprintf("\nResets in %s```", untilReset(q.clock.Now(), q.resets()))
+	return msg
+}
+
+// A user facing explanation for a QuotaError.
+func quotaMessage(err *QuotaError) string {
+	return fmt.Sprintf("```SaltBot has used up today's YouTube quota, so youtube searches are off until "+
+		"midnight Pacific time (in %s). Sorry!```", untilReset(quota.clock.Now(), err.Resets))
+}
+
+func untilReset(now, resets time.Time) string {
+	minutes := int(resets.Sub(now).Round(time.Minute).Minutes())
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
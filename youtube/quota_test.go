@@ -0,0 +1,216 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/testutil"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+// Start each test with an empty cache and a fresh day of quota
+func resetQuota(clock util.Clock) {
+	// Let the last test's saves finish before swapping the cache out
+	quota.saves.Wait()
+	cache.Cache = cache.NewInMemConfigMapCache(map[string]cache.Poll{}, map[string]cache.Reminder{})
+	cache.Client = &testutil.MockK8sClient{}
+	quota = &quotaTracker{clock: clock}
+}
+
+func TestQuotaSpend(t *testing.T) {
+	clock := util.NewFakeClock(time.Date(2023, 7, 1, 12, 0, 0, 0, pacific))
+	resetQuota(clock)
+
+	for i := 0; i < (quotaLimit-quotaReserve)/100; i++ {
+		if err := quota.spend(searchUrl); err != nil {
+			t.Fatalf("expected search %d to be allowed but got: %v", i, err)
+		}
+	}
+
+	// Searches leave the reserve for everything else
+	if err := quota.spend(searchUrl); err == nil {
+		t.Fatalf("expected searches to stop short of the reserve")
+	}
+	for i := 0; i < quotaReserve; i++ {
+		if err := quota.spend(videosUrl); err != nil {
+			t.Fatalf("expected call %d into the reserve to be allowed but got: %v", i, err)
+		}
+	}
+
+	var quotaErr *QuotaError
+	err := quota.spend(videosUrl)
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("expected a quota error but got: %v", err)
+	}
+	expectedReset := time.Date(2023, 7, 2, 0, 0, 0, 0, pacific)
+	if !quotaErr.Resets.Equal(expectedReset) {
+		t.Errorf("expected quota to reset at %s, but got %s", expectedReset, quotaErr.Resets)
+	}
+
+	// A new day in Pacific time starts over, even though it's not midnight UTC
+	clock.Advance(expectedReset.Sub(clock.Now()))
+	if err := quota.spend(searchUrl); err != nil {
+		t.Errorf("expected search to be allowed the next day but got: %v", err)
+	}
+	if quota.units["search.list"] != 100 {
+		t.Errorf("expected 100 search units, but got %d", quota.units["search.list"])
+	}
+}
+
+// Configmap writes wait until release is closed, and the last one is kept.
+type blockingConfigMaps struct {
+	typedcorev1.ConfigMapInterface
+	release chan struct{}
+	writes  int32
+	last    atomic.Value
+}
+
+func (c *blockingConfigMaps) Create(ctx context.Context, configMap *corev1.ConfigMap, opts metav1.CreateOptions) (*corev1.ConfigMap, error) {
+	<-c.release
+	atomic.AddInt32(&c.writes, 1)
+	c.last.Store(configMap)
+	return configMap, nil
+}
+
+type blockingCoreV1 struct {
+	typedcorev1.CoreV1Interface
+	configMaps *blockingConfigMaps
+}
+
+func (c blockingCoreV1) ConfigMaps(namespace string) typedcorev1.ConfigMapInterface {
+	return c.configMaps
+}
+
+type blockingK8sClient struct {
+	testutil.MockK8sClient
+	configMaps *blockingConfigMaps
+}
+
+func (c blockingK8sClient) CoreV1() typedcorev1.CoreV1Interface {
+	return blockingCoreV1{configMaps: c.configMaps}
+}
+
+func TestQuotaSavesInBackground(t *testing.T) {
+	resetQuota(util.SystemClock)
+	configMaps := &blockingConfigMaps{release: make(chan struct{})}
+	cache.Client = blockingK8sClient{configMaps: configMaps}
+
+	spent := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			quota.spend(videosUrl)
+		}
+		close(spent)
+	}()
+
+	select {
+	case <-spent:
+	case <-time.After(time.Second):
+		t.Fatalf("expected spending quota not to wait for the configmap to be saved")
+	}
+
+	close(configMaps.release)
+	quota.saves.Wait()
+
+	if writes := atomic.LoadInt32(&configMaps.writes); writes < 1 || writes > 2 {
+		t.Errorf("expected the saves to be merged into one or two writes, but got %d", writes)
+	}
+	var saved cache.Quota
+	saved.FromConfigMap(configMaps.last.Load().(*corev1.ConfigMap))
+	if saved.Units["videos.list"] != 5 {
+		t.Errorf("expected the latest usage to be saved, but got %+v", saved.Units)
+	}
+}
+
+func TestQuotaLoadsSavedUsage(t *testing.T) {
+	clock := util.NewFakeClock(time.Date(2023, 7, 1, 12, 0, 0, 0, pacific))
+	resetQuota(clock)
+
+	tests := []struct {
+		name     string
+		saved    cache.Quota
+		expected int
+	}{
+		{
+			name:     "Test usage from today is kept",
+			saved:    cache.Quota{Day: "2023-07-01", Units: map[string]int{"search.list": 500}, Id: quotaId},
+			expected: 501,
+		},
+		{
+			name:     "Test usage from yesterday is dropped",
+			saved:    cache.Quota{Day: "2023-06-30", Units: map[string]int{"search.list": 500}, Id: quotaId},
+			expected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetQuota(clock)
+			configMap, _ := tt.saved.ToConfigMap()
+			cache.Cache.Store(configMap)
+
+			if err := quota.spend(videosUrl); err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+			if quota.used() != tt.expected {
+				t.Errorf("expected %d units used, but got %d", tt.expected, quota.used())
+			}
+		})
+	}
+}
+
+func TestQuotaExceededResponse(t *testing.T) {
+	searchCache.Purge()
+	resetQuota(util.SystemClock)
+	mock := &MockHttpClient{
+		responseCode:    http.StatusForbidden,
+		youtubeResponse: map[string]interface{}{"error": map[string]interface{}{"errors": []map[string]string{{"reason": "quotaExceeded"}}}},
+	}
+	client = mock
+
+	msg, err := Get(context.Background(), newMessage("!youtube salt"), false)
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if !strings.Contains(msg.Content, "used up today's YouTube quota") {
+		t.Errorf("expected a quota message, but got '%s'", msg.Content)
+	}
+
+	// Youtube isn't asked again once it says the quota is used up
+	mock.lastUrl = ""
+	Get(context.Background(), newMessage("!youtube pepper"), false)
+	if mock.lastUrl != "" {
+		t.Errorf("expected no request to youtube, but got %s", mock.lastUrl)
+	}
+}
+
+func TestQuotaCommand(t *testing.T) {
+	clock := util.NewFakeClock(time.Date(2023, 7, 1, 21, 30, 0, 0, pacific))
+	resetQuota(clock)
+	quota.spend(searchUrl)
+	quota.spend(videosUrl)
+
+	msg, _ := Get(context.Background(), newMessage("!youtube quota"), false)
+	if msg.Content != "```Only server admins can see the youtube quota```" {
+		t.Errorf("expected non-admins to be refused, but got '%s'", msg.Content)
+	}
+
+	msg, _ = Get(context.Background(), newMessage("!youtube quota"), true)
+	expected := "```YouTube quota for 2023-07-01 (Pacific):\n" +
+		"search.list:   100 units\n" +
+		"videos.list:   1 units\n" +
+		"\nUsed 101 of 10000 units, searches stop at 9500\nResets in 2h 30m```"
+	if msg.Content != expected {
+		t.Errorf("expected message:\n%s\nbut got:\n%s", expected, msg.Content)
+	}
+}
@@ -0,0 +1,353 @@
+package youtube
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+
+	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/lrucache"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+const (
+	feedUrl     = "https://www.youtube.com/feeds/videos.xml"
+	channelsUrl = "https://www.googleapis.com/youtube/v3/channels"
+)
+
+// Most uploads to post for a single subscription per check, so a channel
+// that uploads a batch of videos doesn't flood the discord channel.
+const maxUploadsPerCheck = 3
+
+const subscribeHelpMessage string = ("```Get a message in this channel whenever a youtube channel uploads.\n\n" +
+	"To subscribe, use the channel's handle, id or url:\n" +
+	"\"!youtube subscribe @handle\"\n\"!youtube subscribe https://www.youtube.com/channel/UC...\"\n\n" +
+	"To see this server's subscriptions:\n\"!youtube subscriptions\"\n\n" +
+	"To unsubscribe:\n\"!youtube unsubscribe <ID>\" where <ID> is from \"!youtube subscriptions\"```")
+
+// How often subscribed channels' feeds are checked. Set with
+// YOUTUBE_FEED_INTERVAL, which can't be less than a minute.
+var feedInterval time.Duration
+
+var channelIdPattern = regexp.MustCompile(`^UC[A-Za-z0-9_-]{22}$`)
+
+// A youtube channel's public atom feed of recent uploads
+type Feed struct {
+	ChannelId string      `xml:"http://www.youtube.com/xml/schemas/2015 channelId"`
+	Title     string      `xml:"http://www.w3.org/2005/Atom title"`
+	Entries   []FeedEntry `xml:"http://www.w3.org/2005/Atom entry"`
+}
+
+type FeedEntry struct {
+	VideoId   string        `xml:"http://www.youtube.com/xml/schemas/2015 videoId"`
+	Title     string        `xml:"http://www.w3.org/2005/Atom title"`
+	Author    string        `xml:"http://www.w3.org/2005/Atom author>name"`
+	Published string        `xml:"http://www.w3.org/2005/Atom published"`
+	Thumbnail FeedThumbnail `xml:"http://search.yahoo.com/mrss/ group>thumbnail"`
+}
+
+type FeedThumbnail struct {
+	Url string `xml:"url,attr"`
+}
+
+type YoutubeChannel struct {
+	Id      string         `json:"id"`
+	Snippet YoutubeSnippet `json:"snippet"`
+}
+
+type YoutubeChannelsResponse struct {
+	Items []YoutubeChannel `json:"items"`
+}
+
+func parseFeed(body []byte) (*Feed, error) {
+	var feed Feed
+	err := xml.Unmarshal(body, &feed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal youtube feed: %w", err)
+	}
+
+	return &feed, nil
+}
+
+func fetchFeed(ctx context.Context, channelId string) (*Feed, error) {
+	resp, err := client.Get(ctx, feedUrl+"?"+url.Values{"channel_id": {channelId}}.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get youtube feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code %d from youtube feed", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read youtube feed: %w", err)
+	}
+
+	return parseFeed(body)
+}
+
+// Entries uploaded after lastVideoId, oldest first. If lastVideoId isn't in
+// the feed anymore, only the newest entry is returned.
+func (f *Feed) newEntries(lastVideoId string) []FeedEntry {
+	entries := []FeedEntry{}
+	for _, entry := range f.Entries {
+		if entry.VideoId == lastVideoId {
+			break
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == len(f.Entries) && len(entries) > 0 {
+		entries = entries[:1]
+	}
+	if len(entries) > maxUploadsPerCheck {
+		entries = entries[:maxUploadsPerCheck]
+	}
+
+	// The feed is newest first
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	return entries
+}
+
+func (e FeedEntry) video() YoutubeVideo {
+	return YoutubeVideo{
+		Id: YoutubeId{VideoId: e.VideoId},
+		Snippet: YoutubeSnippet{
+			Title:        e.Title,
+			ChannelTitle: e.Author,
+			PublishedAt:  e.Published,
+			Thumbnails: YoutubeThumbnails{
+				High: YoutubeThumbnail{Url: e.Thumbnail.Url},
+			},
+		},
+	}
+}
+
+// Turn a channel id, channel url or @handle into a channel id. Handles cost
+// one unit of API quota to look up.
+func resolveChannel(ctx context.Context, arg string) (string, error) {
+	if parsed, err := url.Parse(arg); err == nil && parsed.Host != "" {
+		parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+		switch {
+		case len(parts) == 2 && parts[0] == "channel":
+			arg = parts[1]
+		case len(parts) >= 1 && strings.HasPrefix(parts[0], "@"):
+			arg = parts[0]
+		}
+	}
+
+	if channelIdPattern.MatchString(arg) {
+		return arg, nil
+	}
+
+	if !strings.HasPrefix(arg, "@") {
+		return "", nil
+	}
+
+	key := "handle:" + lrucache.NormalizeKey(arg)
+	if cached, ok := searchCache.Get(key); ok {
+		return cached.(string), nil
+	}
+
+	var resp YoutubeChannelsResponse
+	err := fetchYoutube(ctx, channelsUrl, url.Values{"part": {"id"}, "forHandle": {arg}}, &resp)
+	if err != nil {
+		return "", err
+	}
+
+	if len(resp.Items) == 0 {
+		return "", nil
+	}
+
+	searchCache.Add(key, resp.Items[0].Id)
+	return resp.Items[0].Id, nil
+}
+
+func subscribe(ctx context.Context, args []string, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+	if len(args) != 1 || args[0] == "help" {
+		return &discordgo.MessageSend{
+			Content: subscribeHelpMessage,
+		}, nil
+	}
+
+	if m.GuildID == "" {
+		return &discordgo.MessageSend{
+			Content: "```Subscriptions can only be made from a server channel```",
+		}, nil
+	}
+
+	channelId, err := resolveChannel(ctx, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if channelId == "" {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```Couldn't find a youtube channel for \"%s\"```", args[0]),
+		}, nil
+	}
+
+	for _, sub := range cache.Cache.ListSubscriptions() {
+		if sub.Channel == m.ChannelID && sub.YoutubeChannel == channelId {
+			return &discordgo.MessageSend{
+				Content: fmt.Sprintf("```This channel is already subscribed to %s```", sub.Title),
+			}, nil
+		}
+	}
+
+	// Grab the feed now so that only videos uploaded from here on get posted
+	feed, err := fetchFeed(ctx, channelId)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := cache.Subscription{
+		Author:         m.Author.ID,
+		Guild:          m.GuildID,
+		Channel:        m.ChannelID,
+		YoutubeChannel: channelId,
+		Title:          feed.Title,
+		Id:             strings.Split(uuid.NewString(), "-")[0],
+	}
+	if len(feed.Entries) > 0 {
+		sub.LastVideoId = feed.Entries[0].VideoId
+	}
+
+	err = cache.Cache.AddSubscription(ctx, &sub)
+	if err != nil {
+		return nil, fmt.Errorf("error adding subscription to k8s: %w", err)
+	}
+
+	return &discordgo.MessageSend{
+		Content: fmt.Sprintf("```Subscribed to %s with id: %s```", sub.Title, sub.Id),
+	}, nil
+}
+
+func listSubscriptions(m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+	msg := "```Subscriptions:\n"
+	for _, sub := range cache.Cache.ListSubscriptions() {
+		if sub.Guild == m.GuildID {
+			msg += fmt.Sprintf("%s: %s in <#%s>\n", sub.Id, sub.Title, sub.Channel)
+		}
+	}
+
+	return &discordgo.MessageSend{
+		Content: msg + "```",
+	}, nil
+}
+
+func unsubscribe(ctx context.Context, args []string, m *discordgo.MessageCreate) (*discordgo.MessageSend, error) {
+	if len(args) != 1 {
+		return &discordgo.MessageSend{
+			Content: "```To unsubscribe, you must specify the id. Use \"!youtube subscriptions\" to see them all```",
+		}, nil
+	}
+
+	sub := cache.Cache.GetSubscription(args[0])
+	if sub == nil || sub.Guild != m.GuildID {
+		return &discordgo.MessageSend{
+			Content: "```That subscription doesn't exist in this server```",
+		}, nil
+	}
+
+	err := cache.Cache.Delete(ctx, "subscription-"+sub.Id)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting subscription from k8s: %w", err)
+	}
+
+	return &discordgo.MessageSend{
+		Content: fmt.Sprintf("```Unsubscribed from %s```", sub.Title),
+	}, nil
+}
+
+type SessionInterface interface {
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+// Periodically checks every subscription's feed and posts new uploads.
+type Watcher struct {
+	session  SessionInterface
+	ctx      context.Context
+	interval time.Duration
+	clock    util.Clock
+}
+
+func NewWatcher(s SessionInterface, ctx context.Context, clock util.Clock) *Watcher {
+	return &Watcher{
+		session:  s,
+		ctx:      ctx,
+		interval: feedInterval,
+		clock:    clock,
+	}
+}
+
+func (w *Watcher) Loop() {
+	for {
+		select {
+		case <-w.ctx.Done():
+			slog.Info("youtube watcher stopped")
+			return
+
+		case <-w.clock.After(w.interval):
+			w.checkFeeds()
+		}
+	}
+}
+
+func (w *Watcher) checkFeeds() {
+	// Several discord channels can subscribe to the same youtube channel
+	feeds := map[string]*Feed{}
+	for _, sub := range cache.Cache.ListSubscriptions() {
+		feed, ok := feeds[sub.YoutubeChannel]
+		if !ok {
+			var err error
+			feed, err = fetchFeed(w.ctx, sub.YoutubeChannel)
+			if err != nil {
+				slog.Warn("failed to check feed", "subscription", sub.Id, "error", err)
+				continue
+			}
+			feeds[sub.YoutubeChannel] = feed
+		}
+
+		w.postUploads(sub, feed)
+	}
+}
+
+func (w *Watcher) postUploads(sub cache.Subscription, feed *Feed) {
+	entries := feed.newEntries(sub.LastVideoId)
+	if len(entries) == 0 {
+		return
+	}
+
+	for _, entry := range entries {
+		slog.Info("sending upload", "video", entry.VideoId, "subscription", sub.Id, "channel", sub.Channel)
+		_, err := w.session.ChannelMessageSendComplex(sub.Channel, &discordgo.MessageSend{
+			Content: fmt.Sprintf("%s just uploaded a new video!", feed.Title),
+			Embeds:  []*discordgo.MessageEmbed{videoEmbed(entry.video(), YoutubeDetails{})},
+		})
+		if err != nil {
+			slog.Error("failed to send upload, retrying next check", "subscription", sub.Id, "error", err)
+			break
+		}
+		sub.LastVideoId = entry.VideoId
+	}
+
+	err := cache.Cache.UpdateSubscription(w.ctx, &sub)
+	if err != nil {
+		slog.Error("failed to update subscription", "subscription", sub.Id, "error", err)
+	}
+}
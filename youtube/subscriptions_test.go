@@ -0,0 +1,352 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/highsaltlevels/saltbot/cache"
+	"github.com/highsaltlevels/saltbot/testutil"
+	"github.com/highsaltlevels/saltbot/util"
+)
+
+const testChannelId = "UCsaltysaltysaltysalty01"
+
+type MockSession struct {
+	sent      []*discordgo.MessageSend
+	sendError bool
+}
+
+func (s *MockSession) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	if s.sendError {
+		return nil, errors.New(expectedError)
+	}
+	s.sent = append(s.sent, data)
+	return &discordgo.Message{}, nil
+}
+
+func readFeed(t *testing.T) []byte {
+	feed, err := os.ReadFile("testdata/feed.xml")
+	if err != nil {
+		t.Fatalf("failed to read feed fixture: %v", err)
+	}
+	return feed
+}
+
+func newSubscriptionCache(subs ...cache.Subscription) {
+	resetQuota(util.SystemClock)
+	for _, sub := range subs {
+		configMap, _ := sub.ToConfigMap()
+		cache.Cache.Store(configMap)
+	}
+}
+
+func TestParseFeed(t *testing.T) {
+	feed, err := parseFeed(readFeed(t))
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+
+	if feed.ChannelId != testChannelId {
+		t.Errorf("expected channel id %s, but got %s", testChannelId, feed.ChannelId)
+	}
+	if feed.Title != "Salty Channel" {
+		t.Errorf("expected title 'Salty Channel', but got '%s'", feed.Title)
+	}
+	if len(feed.Entries) != 3 {
+		t.Fatalf("expected 3 entries, but got %d", len(feed.Entries))
+	}
+
+	entry := feed.Entries[0]
+	if entry.VideoId != "video3" || entry.Title != "Third Video & Friends" || entry.Author != "Salty Channel" {
+		t.Errorf("unexpected first entry: %+v", entry)
+	}
+	if entry.Thumbnail.Url != "https://i1.ytimg.com/vi/video3/hqdefault.jpg" {
+		t.Errorf("unexpected thumbnail: %s", entry.Thumbnail.Url)
+	}
+
+	_, err = parseFeed([]byte("not xml"))
+	if err == nil {
+		t.Errorf("expected an error for an unparseable feed")
+	}
+}
+
+func TestNewEntries(t *testing.T) {
+	feed, _ := parseFeed(readFeed(t))
+	tests := []struct {
+		name        string
+		lastVideoId string
+		expected    []string
+	}{
+		{
+			name:        "Test nothing new",
+			lastVideoId: "video3",
+			expected:    []string{},
+		},
+		{
+			name:        "Test new uploads oldest first",
+			lastVideoId: "video1",
+			expected:    []string{"video2", "video3"},
+		},
+		{
+			name:        "Test unknown last video only posts the newest",
+			lastVideoId: "gone",
+			expected:    []string{"video3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := feed.newEntries(tt.lastVideoId)
+			ids := []string{}
+			for _, entry := range entries {
+				ids = append(ids, entry.VideoId)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected entries %v, but got %v", tt.expected, ids)
+			}
+		})
+	}
+}
+
+func TestResolveChannel(t *testing.T) {
+	tests := []struct {
+		name     string
+		arg      string
+		response interface{}
+		expected string
+	}{
+		{
+			name:     "Test channel id",
+			arg:      testChannelId,
+			expected: testChannelId,
+		},
+		{
+			name:     "Test channel url",
+			arg:      "https://www.youtube.com/channel/" + testChannelId,
+			expected: testChannelId,
+		},
+		{
+			name:     "Test handle",
+			arg:      "@salty",
+			response: YoutubeChannelsResponse{Items: []YoutubeChannel{{Id: testChannelId}}},
+			expected: testChannelId,
+		},
+		{
+			name:     "Test handle url",
+			arg:      "https://www.youtube.com/@salty/videos",
+			response: YoutubeChannelsResponse{Items: []YoutubeChannel{{Id: testChannelId}}},
+			expected: testChannelId,
+		},
+		{
+			name:     "Test unknown handle",
+			arg:      "@nobody",
+			response: YoutubeChannelsResponse{},
+			expected: "",
+		},
+		{
+			name:     "Test garbage",
+			arg:      "salty",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searchCache.Purge()
+			resetQuota(util.SystemClock)
+			client = &MockHttpClient{
+				responseCode:    http.StatusOK,
+				youtubeResponse: tt.response,
+			}
+
+			id, err := resolveChannel(context.Background(), tt.arg)
+			if err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+			if id != tt.expected {
+				t.Errorf("expected channel id '%s', but got '%s'", tt.expected, id)
+			}
+		})
+	}
+}
+
+func TestSubscriptionCommands(t *testing.T) {
+	existing := cache.Subscription{
+		Guild:          "guild",
+		Channel:        "channel",
+		YoutubeChannel: testChannelId,
+		Title:          "Salty Channel",
+		Id:             "abcd",
+	}
+
+	tests := []struct {
+		name             string
+		commandStr       string
+		guild            string
+		dm               bool
+		k8sClient        cache.KubeClient
+		expectedResponse string
+		expectedError    error
+	}{
+		{
+			name:             "Test subscribe help",
+			commandStr:       "!youtube subscribe",
+			expectedResponse: subscribeHelpMessage,
+		},
+		{
+			name:             "Test subscribe from a DM",
+			commandStr:       "!youtube subscribe UCotherotherotherother01",
+			dm:               true,
+			expectedResponse: "```Subscriptions can only be made from a server channel```",
+		},
+		{
+			name:             "Test subscribe to an unknown channel",
+			commandStr:       "!youtube subscribe salty",
+			expectedResponse: "```Couldn't find a youtube channel for \"salty\"```",
+		},
+		{
+			name:             "Test subscribe twice",
+			commandStr:       "!youtube subscribe " + testChannelId,
+			expectedResponse: "```This channel is already subscribed to Salty Channel```",
+		},
+		{
+			name:             "Test subscribe success",
+			commandStr:       "!youtube subscribe UCotherotherotherother01",
+			expectedResponse: "```Subscribed to Salty Channel with id: ",
+		},
+		{
+			name:          "Test subscribe k8s error",
+			commandStr:    "!youtube subscribe UCotherotherotherother01",
+			k8sClient:     &testutil.MockErrorK8sClient{},
+			expectedError: errors.New(testutil.ExpectedError),
+		},
+		{
+			name:             "Test list subscriptions",
+			commandStr:       "!youtube subscriptions",
+			expectedResponse: "```Subscriptions:\nabcd: Salty Channel in <#channel>\n```",
+		},
+		{
+			name:             "Test list subscriptions in another server",
+			commandStr:       "!youtube subscriptions",
+			guild:            "other",
+			expectedResponse: "```Subscriptions:\n```",
+		},
+		{
+			name:             "Test unsubscribe without an id",
+			commandStr:       "!youtube unsubscribe",
+			expectedResponse: "```To unsubscribe, you must specify the id. Use \"!youtube subscriptions\" to see them all```",
+		},
+		{
+			name:             "Test unsubscribe from another server",
+			commandStr:       "!youtube unsubscribe abcd",
+			guild:            "other",
+			expectedResponse: "```That subscription doesn't exist in this server```",
+		},
+		{
+			name:             "Test unsubscribe",
+			commandStr:       "!youtube unsubscribe abcd",
+			expectedResponse: "```Unsubscribed from Salty Channel```",
+		},
+		{
+			name:          "Test unsubscribe k8s error",
+			commandStr:    "!youtube unsubscribe abcd",
+			k8sClient:     &testutil.MockErrorK8sClient{},
+			expectedError: errors.New(testutil.ExpectedError),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newSubscriptionCache(existing)
+			cache.Client = &testutil.MockK8sClient{}
+			if tt.k8sClient != nil {
+				cache.Client = tt.k8sClient
+			}
+			client = &MockHttpClient{responseCode: http.StatusOK, feedResponse: readFeed(t)}
+
+			m := newMessage(tt.commandStr)
+			if tt.guild != "" {
+				m.GuildID = tt.guild
+			}
+			if tt.dm {
+				m.GuildID = ""
+			}
+
+			msg, err := Get(context.Background(), m, false)
+			if tt.expectedError != nil {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError.Error()) {
+					t.Errorf("expected error '%v', but got '%v'", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+			if !strings.HasPrefix(msg.Content, tt.expectedResponse) {
+				t.Errorf("expected message: '%s', but got '%s'", tt.expectedResponse, msg.Content)
+			}
+		})
+	}
+}
+
+func TestCheckFeeds(t *testing.T) {
+	tests := []struct {
+		name         string
+		lastVideoId  string
+		sendError    bool
+		expectedSent []string
+	}{
+		{
+			name:         "Test no new uploads",
+			lastVideoId:  "video3",
+			expectedSent: []string{},
+		},
+		{
+			name:         "Test new uploads",
+			lastVideoId:  "video1",
+			expectedSent: []string{watchUrl + "video2", watchUrl + "video3"},
+		},
+		{
+			name:         "Test send failure",
+			lastVideoId:  "video1",
+			sendError:    true,
+			expectedSent: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newSubscriptionCache(cache.Subscription{
+				Guild:          "guild",
+				Channel:        "channel",
+				YoutubeChannel: testChannelId,
+				Title:          "Salty Channel",
+				LastVideoId:    tt.lastVideoId,
+				Id:             "abcd",
+			})
+			cache.Client = &testutil.MockK8sClient{}
+			client = &MockHttpClient{responseCode: http.StatusOK, feedResponse: readFeed(t)}
+			session := &MockSession{sendError: tt.sendError}
+
+			watcher := NewWatcher(session, context.Background(), util.SystemClock)
+			watcher.checkFeeds()
+
+			sent := []string{}
+			for _, msg := range session.sent {
+				if msg.Content != "Salty Channel just uploaded a new video!" {
+					t.Errorf("unexpected message content: %s", msg.Content)
+				}
+				sent = append(sent, msg.Embeds[0].URL)
+			}
+			if strings.Join(sent, ",") != strings.Join(tt.expectedSent, ",") {
+				t.Errorf("expected uploads %v, but got %v", tt.expectedSent, sent)
+			}
+		})
+	}
+}
@@ -1,104 +1,339 @@
 package youtube
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 
+	"github.com/highsaltlevels/saltbot/logging"
+	"github.com/highsaltlevels/saltbot/lrucache"
 	"github.com/highsaltlevels/saltbot/util"
 )
 
+const (
+	searchUrl = "https://www.googleapis.com/youtube/v3/search"
+	videosUrl = "https://www.googleapis.com/youtube/v3/videos"
+	watchUrl  = "https://www.youtube.com/watch?v="
+)
+
 var token string
 var client util.HttpClientInterface
 
+// Make youtube requests with c instead, like a stand in for the api. Must be
+// called before any searches or checks for uploads.
+func SetClient(c util.HttpClientInterface) {
+	client = c
+}
+
+// The flags understood by "!youtube"
+var flagSpec = util.FlagSpec{"-i": true, "-a": false}
+
+// Search results keyed on the normalized query. Every search costs 100 units of
+// API quota, so the default TTL (YOUTUBE_CACHE_TTL) is fairly long.
+var searchCache *lrucache.Cache
+
+// Duration and view counts keyed on video id.
+var detailsCache *lrucache.Cache
+
+// Whether to call videos.list for durations and view counts. Costs 1 unit of
+// quota per search and can be turned off with YOUTUBE_VIDEO_DETAILS=false.
+var fetchVideoDetails bool
+
 type YoutubeId struct {
 	VideoId string `json:"videoId"`
 }
 
+type YoutubeThumbnail struct {
+	Url string `json:"url"`
+}
+
+type YoutubeThumbnails struct {
+	Default YoutubeThumbnail `json:"default"`
+	Medium  YoutubeThumbnail `json:"medium"`
+	High    YoutubeThumbnail `json:"high"`
+}
+
+type YoutubeSnippet struct {
+	Title        string            `json:"title"`
+	ChannelId    string            `json:"channelId"`
+	ChannelTitle string            `json:"channelTitle"`
+	PublishedAt  string            `json:"publishedAt"`
+	Thumbnails   YoutubeThumbnails `json:"thumbnails"`
+}
+
 type YoutubeVideo struct {
-	Id YoutubeId `json:"id"`
+	Id      YoutubeId      `json:"id"`
+	Snippet YoutubeSnippet `json:"snippet"`
 }
 
 type YoutubeResponse struct {
 	Items []YoutubeVideo `json:"items"`
 }
 
+type YoutubeContentDetails struct {
+	// ISO 8601 duration like PT4M13S
+	Duration string `json:"duration"`
+}
+
+type YoutubeStatistics struct {
+	ViewCount string `json:"viewCount"`
+}
+
+type YoutubeDetails struct {
+	Id             string                `json:"id"`
+	ContentDetails YoutubeContentDetails `json:"contentDetails"`
+	Statistics     YoutubeStatistics     `json:"statistics"`
+}
+
+type YoutubeDetailsResponse struct {
+	Items []YoutubeDetails `json:"items"`
+}
+
 func init() {
 	var ok bool
+	var err error
 	if token, ok = os.LookupEnv("YOUTUBE_AUTH"); !ok {
-		log.Println("failed to get youtube auth from env var")
-		log.Println("continuing saltbot startup with partial functionality")
+		slog.Warn("YOUTUBE_AUTH isn't set, continuing without youtube")
 	}
+	fetchVideoDetails = os.Getenv("YOUTUBE_VIDEO_DETAILS") != "false"
 
 	if client == nil {
-		client = &http.Client{}
+		client = util.NewResilientClient("youtube", util.HttpTimeout, util.SystemClock)
+	}
+
+	searchCache = lrucache.New("youtube", lrucache.DefaultSize, lrucache.TTLFromEnv("YOUTUBE_CACHE_TTL", 6*time.Hour), util.SystemClock)
+	detailsCache = lrucache.New("youtube-details", lrucache.DefaultSize*4, lrucache.TTLFromEnv("YOUTUBE_CACHE_TTL", 6*time.Hour), util.SystemClock)
+	feedInterval = util.IntervalFromEnv("YOUTUBE_FEED_INTERVAL", 10*time.Minute, time.Minute)
+
+	quotaLimit = 10000
+	if limit, ok := os.LookupEnv("YOUTUBE_QUOTA_LIMIT"); ok {
+		if n, err := strconv.Atoi(limit); err != nil || n < 1 {
+			slog.Warn("invalid YOUTUBE_QUOTA_LIMIT, using the default", "value", limit, "default", quotaLimit, "error", err)
+		} else {
+			quotaLimit = n
+		}
+	}
+
+	quotaReserve = 500
+	if reserve, ok := os.LookupEnv("YOUTUBE_QUOTA_RESERVE"); ok {
+		if n, err := strconv.Atoi(reserve); err != nil || n < 0 {
+			slog.Warn("invalid YOUTUBE_QUOTA_RESERVE, using the default", "value", reserve, "default", quotaReserve, "error", err)
+		} else {
+			quotaReserve = n
+		}
+	}
+
+	if pacific, err = time.LoadLocation("America/Los_Angeles"); err != nil {
+		slog.Warn("failed to load pacific time, assuming PST", "error", err)
+		pacific = time.FixedZone("PST", -8*60*60)
 	}
 }
 
-func getYoutubeVideo(query string, idx int) (string, error) {
-	url := fmt.Sprintf("https://www.googleapis.com/youtube/v3/search?key=%s&q=%s&maxResult=15&type=video", token, query)
-	resp, err := client.Get(url)
+// Make a GET request to the youtube API and unmarshal the response into v.
+func fetchYoutube(ctx context.Context, endpoint string, params url.Values, v interface{}) error {
+	query := url.Values{}
+	for k, values := range params {
+		query[k] = values
+	}
+	query.Set("key", token)
+
+	err := quota.spend(endpoint)
 	if err != nil {
-		return "", fmt.Errorf("failed to get youtube video: %w", err)
+		return err
+	}
+
+	resp, err := client.Get(ctx, endpoint+"?"+query.Encode())
+	if err != nil {
+		return fmt.Errorf("failed to get youtube video: %w", err)
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read youtube resp: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusForbidden && strings.Contains(string(body), "quotaExceeded") {
+		quota.exhaust()
+		return &QuotaError{Resets: quota.resets()}
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("received status code %d from youtube", resp.StatusCode)
+		return fmt.Errorf("received status code %d from youtube", resp.StatusCode)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	err = json.Unmarshal(body, v)
 	if err != nil {
-		return "", fmt.Errorf("failed to read youtube resp: %w", err)
+		return fmt.Errorf("failed to unmarshal youtube response: %w", err)
+	}
+
+	return nil
+}
+
+func searchYoutube(ctx context.Context, query string) (*YoutubeResponse, error) {
+	key := lrucache.NormalizeKey(query)
+	if cached, ok := searchCache.Get(key); ok {
+		return cached.(*YoutubeResponse), nil
 	}
 
+	params := url.Values{}
+	params.Set("part", "snippet")
+	params.Set("q", query)
+	params.Set("maxResults", "15")
+	params.Set("type", "video")
+
 	var yt YoutubeResponse
-	err = json.Unmarshal(body, &yt)
+	err := fetchYoutube(ctx, searchUrl, params, &yt)
 	if err != nil {
-		return "", fmt.Errorf("failed to unmarshal youtube response: %w", err)
+		return nil, err
 	}
 
-	if len(yt.Items) < 1 {
-		return "```No videos for that query :(```", nil
+	searchCache.Add(key, &yt)
+	return &yt, nil
+}
+
+// Look up durations and view counts. Videos that are already cached aren't
+// requested again.
+func getVideoDetails(ctx context.Context, videos []YoutubeVideo) (map[string]YoutubeDetails, error) {
+	details := map[string]YoutubeDetails{}
+	missing := []string{}
+	for _, video := range videos {
+		if cached, ok := detailsCache.Get(video.Id.VideoId); ok {
+			details[video.Id.VideoId] = cached.(YoutubeDetails)
+		} else {
+			missing = append(missing, video.Id.VideoId)
+		}
+	}
+
+	if len(missing) == 0 {
+		return details, nil
+	}
+
+	params := url.Values{}
+	params.Set("part", "contentDetails,statistics")
+	params.Set("id", strings.Join(missing, ","))
+
+	var resp YoutubeDetailsResponse
+	err := fetchYoutube(ctx, videosUrl, params, &resp)
+	if err != nil {
+		return details, err
+	}
+
+	for _, item := range resp.Items {
+		detailsCache.Add(item.Id, item)
+		details[item.Id] = item
+	}
+
+	return details, nil
+}
+
+// Details are nice to have, so failing to get them only gets logged.
+func videoDetails(ctx context.Context, videos []YoutubeVideo) map[string]YoutubeDetails {
+	if !fetchVideoDetails {
+		return map[string]YoutubeDetails{}
 	}
 
-	return "https://www.youtube.com/watch?v=" + yt.Items[idx].Id.VideoId, nil
+	details, err := getVideoDetails(ctx, videos)
+	if err != nil {
+		logging.From(ctx).Warn("failed to get youtube video details", "error", err)
+	}
+
+	return details
 }
 
-func Get(content string) (*discordgo.MessageSend, error) {
-	args := strings.Split(content, " ")[1:]
-	if len(args) == 0 {
+// Admins can also see the day's quota usage with "!youtube quota".
+func Get(ctx context.Context, m *discordgo.MessageCreate, admin bool) (*discordgo.MessageSend, error) {
+	message, err := get(ctx, m, admin)
+
+	var quotaErr *QuotaError
+	if errors.As(err, &quotaErr) {
 		return &discordgo.MessageSend{
-			Content: "```Must specify a query like: \"!youtube dog\"```",
+			Content: quotaMessage(quotaErr),
 		}, nil
 	}
 
-	for i, arg := range args {
-		if arg == "-i" {
-			idx, err := strconv.Atoi(args[i+1])
-			if err != nil || idx < 0 || idx > 14 {
+	return message, err
+}
+
+func get(ctx context.Context, m *discordgo.MessageCreate, admin bool) (*discordgo.MessageSend, error) {
+	flags, err := util.ParseFlags(m.Content, flagSpec)
+	if err != nil {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```%v```", err),
+		}, nil
+	}
+
+	if len(flags.Terms) > 0 {
+		switch flags.Terms[0] {
+		case "subscribe":
+			return subscribe(ctx, flags.Terms[1:], m)
+		case "subscriptions":
+			return listSubscriptions(m)
+		case "unsubscribe":
+			return unsubscribe(ctx, flags.Terms[1:], m)
+		case "quota":
+			if !admin {
 				return &discordgo.MessageSend{
-					Content: "```Must use a valid number between 0 and 14```",
+					Content: "```Only server admins can see the youtube quota```",
 				}, nil
 			}
-
-			yt, err := getYoutubeVideo(util.ParseArgsToQuery(args), idx)
 			return &discordgo.MessageSend{
-				Content: yt,
-			}, err
+				Content: quota.usage(),
+			}, nil
 		}
 	}
 
-	// If we get here, the user didn't specify "-i", so just assume first result
-	yt, err := getYoutubeVideo(util.ParseArgsToQuery(args), 0)
+	idx, err := flags.Int("-i", 0)
+	if err != nil || idx < 0 || idx > 14 {
+		return &discordgo.MessageSend{
+			Content: "```Must use a valid number between 0 and 14```",
+		}, nil
+	}
+
+	if len(flags.Terms) == 0 {
+		return &discordgo.MessageSend{
+			Content: "```Must specify a query like: \"!youtube dog\"```",
+		}, nil
+	}
+
+	yt, err := searchYoutube(ctx, flags.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(yt.Items) < 1 {
+		return &discordgo.MessageSend{
+			Content: "```No videos for that query :(```",
+		}, nil
+	}
+
+	if flags.Has("-a") {
+		return &discordgo.MessageSend{
+			Embeds: []*discordgo.MessageEmbed{listEmbed(flags.Query(), yt.Items, videoDetails(ctx, yt.Items))},
+		}, nil
+	}
+
+	// Assume the first result if -i wasn't used
+	if idx >= len(yt.Items) {
+		return &discordgo.MessageSend{
+			Content: fmt.Sprintf("```There are only %d videos for that query```", len(yt.Items)),
+		}, nil
+	}
+
+	video := yt.Items[idx]
+	details := videoDetails(ctx, []YoutubeVideo{video})
 	return &discordgo.MessageSend{
-		Content: yt,
-	}, err
+		Embeds: []*discordgo.MessageEmbed{videoEmbed(video, details[video.Id.VideoId])},
+	}, nil
 }
@@ -1,6 +1,7 @@
 package youtube
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/bwmarrin/discordgo"
+
 	"github.com/highsaltlevels/saltbot/util"
 )
 
@@ -41,9 +44,34 @@ type MockHttpClient struct {
 
 	// The response code to be used in the http response object
 	responseCode int
+
+	// The last url requested through the client
+	lastUrl string
+
+	// Returned instead of youtubeResponse for videos.list requests if set
+	detailsResponse interface{}
+
+	// Raw xml returned for feed requests if set
+	feedResponse []byte
 }
 
-func (c *MockHttpClient) Get(url string) (*http.Response, error) {
+func (c *MockHttpClient) Get(ctx context.Context, url string) (*http.Response, error) {
+	if c.feedResponse != nil && strings.HasPrefix(url, feedUrl) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(string(c.feedResponse))),
+		}, nil
+	}
+
+	if c.detailsResponse != nil && strings.HasPrefix(url, videosUrl) {
+		data, _ := json.Marshal(c.detailsResponse)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(string(data))),
+		}, nil
+	}
+
+	c.lastUrl = url
 	if c.expectError {
 		return nil, errors.New(expectedError)
 	}
@@ -63,6 +91,19 @@ func (c *MockHttpClient) Get(url string) (*http.Response, error) {
 	}, nil
 }
 
+func newMessage(content string) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			Content:   content,
+			ChannelID: "channel",
+			GuildID:   "guild",
+			Author: &discordgo.User{
+				ID: "1234",
+			},
+		},
+	}
+}
+
 func TestGet(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -173,6 +214,14 @@ func TestGet(t *testing.T) {
 			expectedResponse: "```Must use a valid number between 0 and 14```",
 			expectedError:    nil,
 		},
+		{
+			name:             "Test trailing index flag",
+			commandStr:       "!youtube query -i",
+			youtubeResponse:  YoutubeResponse{},
+			getResponseCode:  http.StatusOK,
+			expectedResponse: "```flag -i needs a value after it```",
+			expectedError:    nil,
+		},
 		{
 			name:            "Test youtube video fetch unmarshalable response",
 			commandStr:      "!youtube query",
@@ -192,6 +241,9 @@ func TestGet(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			searchCache.Purge()
+			resetQuota(util.SystemClock)
+			detailsCache.Purge()
 			client = &MockHttpClient{
 				expectError:     tt.shouldClientError,
 				expectIOError:   tt.shouldReadCloserError,
@@ -199,7 +251,7 @@ func TestGet(t *testing.T) {
 				youtubeResponse: tt.youtubeResponse,
 			}
 
-			msg, err := Get(tt.commandStr)
+			msg, err := Get(context.Background(), newMessage(tt.commandStr), false)
 			if tt.expectedError != nil {
 				if err == nil {
 					t.Errorf("expected error '%v' to be returned but was nil", tt.expectedError)
@@ -211,11 +263,171 @@ func TestGet(t *testing.T) {
 				if err != nil {
 					t.Fatalf("expected no error but got error: '%v'", err)
 				}
-				if msg.Content != tt.expectedResponse {
-					t.Errorf("expected message: '%s', but got '%s'", tt.expectedResponse, msg.Content)
+				// Videos are sent as an embed linking to the video
+				content := msg.Content
+				if len(msg.Embeds) > 0 {
+					content = msg.Embeds[0].URL
+				}
+				if content != tt.expectedResponse {
+					t.Errorf("expected message: '%s', but got '%s'", tt.expectedResponse, content)
 				}
 			}
 		})
 
 	}
 }
+
+func TestSearchYoutubeEncodesQuery(t *testing.T) {
+	searchCache.Purge()
+	resetQuota(util.SystemClock)
+	detailsCache.Purge()
+	mock := &MockHttpClient{
+		responseCode:    http.StatusOK,
+		youtubeResponse: YoutubeResponse{},
+	}
+	client = mock
+
+	_, err := searchYoutube(context.Background(), "rock & roll")
+	if err != nil {
+		t.Fatalf("expected no error but got error: '%v'", err)
+	}
+
+	expectedUrl := "https://www.googleapis.com/youtube/v3/search?key=&maxResults=15&part=snippet&q=rock+%26+roll&type=video"
+	if mock.lastUrl != expectedUrl {
+		t.Errorf("expected url '%s', but got '%s'", expectedUrl, mock.lastUrl)
+	}
+}
+
+func TestGetEmbeds(t *testing.T) {
+	searchResponse := YoutubeResponse{
+		Items: []YoutubeVideo{
+			YoutubeVideo{
+				Id: YoutubeId{VideoId: "foo"},
+				Snippet: YoutubeSnippet{
+					Title:        "Salt &amp; Pepper&#39;s Song",
+					ChannelId:    "UC1234",
+					ChannelTitle: "Salty Channel",
+					PublishedAt:  "2023-07-04T12:00:00Z",
+					Thumbnails: YoutubeThumbnails{
+						Medium: YoutubeThumbnail{Url: "https://i.ytimg.com/foo/mqdefault.jpg"},
+					},
+				},
+			},
+			YoutubeVideo{
+				Id:      YoutubeId{VideoId: "bar"},
+				Snippet: YoutubeSnippet{Title: "Bar", ChannelTitle: "Bar Channel"},
+			},
+		},
+	}
+	detailsResponse := YoutubeDetailsResponse{
+		Items: []YoutubeDetails{
+			YoutubeDetails{
+				Id:             "foo",
+				ContentDetails: YoutubeContentDetails{Duration: "PT4M13S"},
+				Statistics:     YoutubeStatistics{ViewCount: "1234567"},
+			},
+			YoutubeDetails{
+				Id:             "bar",
+				ContentDetails: YoutubeContentDetails{Duration: "PT1H2M3S"},
+			},
+		},
+	}
+
+	searchCache.Purge()
+	resetQuota(util.SystemClock)
+	detailsCache.Purge()
+	client = &MockHttpClient{
+		responseCode:    http.StatusOK,
+		youtubeResponse: searchResponse,
+		detailsResponse: detailsResponse,
+	}
+
+	msg, err := Get(context.Background(), newMessage("!youtube salt"), false)
+	if err != nil {
+		t.Fatalf("expected no error but got error: '%v'", err)
+	}
+	if len(msg.Embeds) != 1 {
+		t.Fatalf("expected 1 embed, but got %d", len(msg.Embeds))
+	}
+
+	embed := msg.Embeds[0]
+	if embed.Title != "Salt & Pepper's Song" {
+		t.Errorf("expected unescaped title, but got '%s'", embed.Title)
+	}
+	if embed.Author == nil || embed.Author.Name != "Salty Channel" || embed.Author.URL != "https://www.youtube.com/channel/UC1234" {
+		t.Errorf("expected author Salty Channel, but got %+v", embed.Author)
+	}
+	if embed.Image == nil || embed.Image.URL != "https://i.ytimg.com/foo/mqdefault.jpg" {
+		t.Errorf("expected thumbnail image, but got %+v", embed.Image)
+	}
+
+	expectedFields := map[string]string{
+		"Duration":  "4:13",
+		"Views":     "1,234,567",
+		"Published": "Jul 4, 2023",
+	}
+	if len(embed.Fields) != len(expectedFields) {
+		t.Errorf("expected %d fields, but got %d", len(expectedFields), len(embed.Fields))
+	}
+	for _, field := range embed.Fields {
+		if expectedFields[field.Name] != field.Value {
+			t.Errorf("expected field %s to be '%s', but got '%s'", field.Name, expectedFields[field.Name], field.Value)
+		}
+	}
+
+	msg, err = Get(context.Background(), newMessage("!youtube salt -a"), false)
+	if err != nil {
+		t.Fatalf("expected no error but got error: '%v'", err)
+	}
+	if len(msg.Embeds) != 1 {
+		t.Fatalf("expected 1 embed, but got %d", len(msg.Embeds))
+	}
+
+	for _, part := range []string{
+		"`0` [Salt & Pepper's Song](https://www.youtube.com/watch?v=foo) · Salty Channel · 4:13",
+		"`1` [Bar](https://www.youtube.com/watch?v=bar) · Bar Channel · 1:02:03",
+	} {
+		if !strings.Contains(msg.Embeds[0].Description, part) {
+			t.Errorf("expected '%s' to be in '%s'", part, msg.Embeds[0].Description)
+		}
+	}
+	if !strings.Contains(msg.Embeds[0].Footer.Text, "!youtube salt -i <number>") {
+		t.Errorf("expected footer to explain how to pick a video, but got '%s'", msg.Embeds[0].Footer.Text)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := map[string]string{
+		"PT4M13S":  "4:13",
+		"PT45S":    "0:45",
+		"PT1H2M3S": "1:02:03",
+		"PT2H":     "2:00:00",
+		"P1DT1M":   "24:01:00",
+		"P0D":      "0:00",
+		"PT":       "",
+		"bogus":    "",
+	}
+
+	for duration, expected := range tests {
+		if actual := formatDuration(duration); actual != expected {
+			t.Errorf("expected '%s' to format as '%s', but got '%s'", duration, expected, actual)
+		}
+	}
+}
+
+func TestFormatViews(t *testing.T) {
+	tests := map[string]string{
+		"0":       "0",
+		"999":     "999",
+		"1000":    "1,000",
+		"1234567": "1,234,567",
+		"":        "",
+		"lots":    "",
+	}
+
+	for views, expected := range tests {
+		if actual := formatViews(views); actual != expected {
+			t.Errorf("expected '%s' to format as '%s', but got '%s'", views, expected, actual)
+		}
+	}
+}